@@ -0,0 +1,383 @@
+package wpaSuppDBusLib
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type ANQPInfoID uint16
+
+// ANQP-element Info IDs (IEEE 802.11-2020, 9.4.5.1) that can be requested with ANQPGet
+const (
+	ANQPIDCapabilityList            ANQPInfoID = 257
+	ANQPIDVenueName                 ANQPInfoID = 258
+	ANQPIDNetworkAuthType           ANQPInfoID = 260
+	ANQPIDRoamingConsortium         ANQPInfoID = 261
+	ANQPIDIPAddressTypeAvailability ANQPInfoID = 262
+	ANQPIDNAIRealm                  ANQPInfoID = 263
+	ANQPID3GPPCellularNetwork       ANQPInfoID = 264
+	ANQPIDDomainName                ANQPInfoID = 268
+)
+
+// ANQPLocalizedName is a name with its ISO-639 language code, used by Venue Name and
+// Operator Friendly Name duples
+type ANQPLocalizedName struct {
+	Language string
+	Name     string
+}
+
+type ANQPVenueInfo struct {
+	VenueGroup uint8
+	VenueType  uint8
+	Names      []ANQPLocalizedName
+}
+
+// ANQPNetworkAuthType Indicator is 0 (acceptance of terms and conditions), 1 (on-line
+// enrollment supported), 2 (http/https redirection) or 3 (DNS redirection)
+type ANQPNetworkAuthType struct {
+	Indicator   uint8
+	RedirectURL string
+}
+
+// ANQPIPAddressTypeAvailability IPv6 is 0 (not available), 1 (available) or 2 (unknown).
+// IPv4 is 0 (not available), 1 (public), 2 (port-restricted), 3 (single NATed private),
+// 4 (double NATed private), 5 (port-restricted and single NATed), 6 (port-restricted and
+// double NATed) or 7 (unknown).
+type ANQPIPAddressTypeAvailability struct {
+	IPv6 uint8
+	IPv4 uint8
+}
+
+type ANQPAuthParam struct {
+	ID    uint8
+	Value []byte
+}
+
+type ANQPEAPMethod struct {
+	Method     uint8
+	AuthParams []ANQPAuthParam
+}
+
+// ANQPNAIRealm Encoding 0 means the realms are RFC 4282 compliant, 1 that they are UTF-8
+type ANQPNAIRealm struct {
+	Encoding   uint8
+	Realms     []string
+	EAPMethods []ANQPEAPMethod
+}
+
+type ANQPPLMN struct {
+	MCC string
+	MNC string
+}
+
+// HS20WANMetrics LinkStatus is 1 (link up), 2 (link down) or 3 (link in test state).
+// Speeds are in kbps, loads scaled to 255 and the measurement duration in tenths of a second.
+type HS20WANMetrics struct {
+	LinkStatus              uint8
+	Symmetric               bool
+	AtCapacity              bool
+	DownlinkSpeed           uint32
+	UplinkSpeed             uint32
+	DownlinkLoad            uint8
+	UplinkLoad              uint8
+	LoadMeasurementDuration uint16
+}
+
+// HS20ConnectionCapability Status is 0 (closed), 1 (open) or 2 (unknown)
+type HS20ConnectionCapability struct {
+	Protocol uint8
+	Port     uint16
+	Status   uint8
+}
+
+// ANQPInfo holds the decoded ANQP elements cached by the supplicant for a BSS.
+// Elements the access point did not report are left empty.
+type ANQPInfo struct {
+	CapabilityList            []ANQPInfoID
+	VenueName                 *ANQPVenueInfo
+	NetworkAuthTypes          []ANQPNetworkAuthType
+	RoamingConsortium         []string
+	IPAddressTypeAvailability *ANQPIPAddressTypeAvailability
+	NAIRealms                 []ANQPNAIRealm
+	CellularNetworks          []ANQPPLMN
+	DomainNames               []string
+	OperatorFriendlyNames     []ANQPLocalizedName
+	WANMetrics                *HS20WANMetrics
+	ConnectionCapabilities    []HS20ConnectionCapability
+}
+
+var errANQPTruncated = errors.New("truncated anqp element")
+
+// decodeANQP decodes the raw payloads keyed by the names used in the ANQP property of BSS objects
+func decodeANQP(elements map[string][]byte) (*ANQPInfo, error) {
+	info := ANQPInfo{}
+	var err error
+	for name, payload := range elements {
+		if len(payload) == 0 {
+			continue
+		}
+		switch name {
+		case "CapabilityList":
+			info.CapabilityList, err = decodeANQPCapabilityList(payload)
+		case "VenueName":
+			info.VenueName, err = decodeANQPVenueName(payload)
+		case "NetworkAuthType":
+			info.NetworkAuthTypes, err = decodeANQPNetworkAuthType(payload)
+		case "RoamingConsortium":
+			info.RoamingConsortium, err = decodeANQPRoamingConsortium(payload)
+		case "IPAddrTypeAvailability":
+			info.IPAddressTypeAvailability, err = decodeANQPIPAddressTypeAvailability(payload)
+		case "NAIRealm":
+			info.NAIRealms, err = decodeANQPNAIRealm(payload)
+		case "ANQP3GPP":
+			info.CellularNetworks, err = decodeANQP3GPPCellularNetwork(payload)
+		case "DomainName":
+			info.DomainNames, err = decodeANQPDomainName(payload)
+		case "HS20OperatorFriendlyName":
+			info.OperatorFriendlyNames, err = decodeANQPLocalizedNames(payload)
+		case "HS20WANMetrics":
+			info.WANMetrics, err = decodeHS20WANMetrics(payload)
+		case "HS20ConnectionCapability":
+			info.ConnectionCapabilities, err = decodeHS20ConnectionCapability(payload)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode anqp %s: %w", name, err)
+		}
+	}
+	return &info, nil
+}
+
+func decodeANQPCapabilityList(payload []byte) ([]ANQPInfoID, error) {
+	if len(payload)%2 != 0 {
+		return nil, errANQPTruncated
+	}
+	ids := make([]ANQPInfoID, 0, len(payload)/2)
+	for i := 0; i < len(payload); i += 2 {
+		ids = append(ids, ANQPInfoID(binary.LittleEndian.Uint16(payload[i:])))
+	}
+	return ids, nil
+}
+
+func decodeANQPVenueName(payload []byte) (*ANQPVenueInfo, error) {
+	if len(payload) < 2 {
+		return nil, errANQPTruncated
+	}
+	names, err := decodeANQPLocalizedNames(payload[2:])
+	if err != nil {
+		return nil, err
+	}
+	return &ANQPVenueInfo{VenueGroup: payload[0], VenueType: payload[1], Names: names}, nil
+}
+
+// decodeANQPLocalizedNames decodes a list of duples: length, 3 octet language code, name
+func decodeANQPLocalizedNames(payload []byte) ([]ANQPLocalizedName, error) {
+	names := make([]ANQPLocalizedName, 0)
+	for len(payload) > 0 {
+		length := int(payload[0])
+		if length < 3 || len(payload) < 1+length {
+			return nil, errANQPTruncated
+		}
+		names = append(names, ANQPLocalizedName{
+			Language: strings.TrimRight(string(payload[1:4]), "\x00"),
+			Name:     string(payload[4 : 1+length]),
+		})
+		payload = payload[1+length:]
+	}
+	return names, nil
+}
+
+func decodeANQPNetworkAuthType(payload []byte) ([]ANQPNetworkAuthType, error) {
+	authTypes := make([]ANQPNetworkAuthType, 0)
+	for len(payload) > 0 {
+		if len(payload) < 3 {
+			return nil, errANQPTruncated
+		}
+		length := int(binary.LittleEndian.Uint16(payload[1:]))
+		if len(payload) < 3+length {
+			return nil, errANQPTruncated
+		}
+		authTypes = append(authTypes, ANQPNetworkAuthType{Indicator: payload[0], RedirectURL: string(payload[3 : 3+length])})
+		payload = payload[3+length:]
+	}
+	return authTypes, nil
+}
+
+func decodeANQPRoamingConsortium(payload []byte) ([]string, error) {
+	ois := make([]string, 0)
+	for len(payload) > 0 {
+		length := int(payload[0])
+		if len(payload) < 1+length {
+			return nil, errANQPTruncated
+		}
+		ois = append(ois, hex.EncodeToString(payload[1:1+length]))
+		payload = payload[1+length:]
+	}
+	return ois, nil
+}
+
+func decodeANQPIPAddressTypeAvailability(payload []byte) (*ANQPIPAddressTypeAvailability, error) {
+	if len(payload) < 1 {
+		return nil, errANQPTruncated
+	}
+	return &ANQPIPAddressTypeAvailability{IPv6: payload[0] & 0x03, IPv4: payload[0] >> 2}, nil
+}
+
+func decodeANQPNAIRealm(payload []byte) ([]ANQPNAIRealm, error) {
+	if len(payload) < 2 {
+		return nil, errANQPTruncated
+	}
+	count := int(binary.LittleEndian.Uint16(payload))
+	payload = payload[2:]
+	realms := make([]ANQPNAIRealm, 0, count)
+	for i := 0; i < count; i++ {
+		if len(payload) < 2 {
+			return nil, errANQPTruncated
+		}
+		length := int(binary.LittleEndian.Uint16(payload))
+		if len(payload) < 2+length {
+			return nil, errANQPTruncated
+		}
+		realm, err := decodeANQPNAIRealmData(payload[2 : 2+length])
+		if err != nil {
+			return nil, err
+		}
+		realms = append(realms, *realm)
+		payload = payload[2+length:]
+	}
+	return realms, nil
+}
+
+func decodeANQPNAIRealmData(data []byte) (*ANQPNAIRealm, error) {
+	if len(data) < 2 {
+		return nil, errANQPTruncated
+	}
+	realm := ANQPNAIRealm{Encoding: data[0] & 0x01}
+	realmLength := int(data[1])
+	if len(data) < 2+realmLength+1 {
+		return nil, errANQPTruncated
+	}
+	realm.Realms = strings.Split(string(data[2:2+realmLength]), ";")
+	data = data[2+realmLength:]
+	methodCount := int(data[0])
+	data = data[1:]
+	realm.EAPMethods = make([]ANQPEAPMethod, 0, methodCount)
+	for i := 0; i < methodCount; i++ {
+		if len(data) < 1 || len(data) < 1+int(data[0]) || data[0] < 2 {
+			return nil, errANQPTruncated
+		}
+		methodData := data[1 : 1+int(data[0])]
+		data = data[1+int(data[0]):]
+		method := ANQPEAPMethod{Method: methodData[0], AuthParams: make([]ANQPAuthParam, 0)}
+		paramCount := int(methodData[1])
+		methodData = methodData[2:]
+		for j := 0; j < paramCount; j++ {
+			if len(methodData) < 2 || len(methodData) < 2+int(methodData[1]) {
+				return nil, errANQPTruncated
+			}
+			method.AuthParams = append(method.AuthParams, ANQPAuthParam{ID: methodData[0], Value: methodData[2 : 2+int(methodData[1])]})
+			methodData = methodData[2+int(methodData[1]):]
+		}
+		realm.EAPMethods = append(realm.EAPMethods, method)
+	}
+	return &realm, nil
+}
+
+// decodeANQP3GPPCellularNetwork decodes the PLMN list of the 3GPP Cellular Network element
+// (3GPP TS 24.302, Annex H)
+func decodeANQP3GPPCellularNetwork(payload []byte) ([]ANQPPLMN, error) {
+	if len(payload) < 2 {
+		return nil, errANQPTruncated
+	}
+	if payload[0] != 0 {
+		return nil, fmt.Errorf("unsupported 3gpp gud version %d", payload[0])
+	}
+	udhl := int(payload[1])
+	if len(payload) < 2+udhl {
+		return nil, errANQPTruncated
+	}
+	data := payload[2 : 2+udhl]
+	plmns := make([]ANQPPLMN, 0)
+	for len(data) >= 2 {
+		iei, length := data[0], int(data[1]&0x7f)
+		if len(data) < 2+length {
+			return nil, errANQPTruncated
+		}
+		ie := data[2 : 2+length]
+		data = data[2+length:]
+		if iei != 0 || len(ie) < 1 {
+			continue
+		}
+		count := int(ie[0])
+		if len(ie) < 1+3*count {
+			return nil, errANQPTruncated
+		}
+		for i := 0; i < count; i++ {
+			plmns = append(plmns, decodePLMN(ie[1+3*i:4+3*i]))
+		}
+	}
+	return plmns, nil
+}
+
+func decodePLMN(plmn []byte) ANQPPLMN {
+	digit := func(nibble byte) string {
+		return string(rune('0' + nibble))
+	}
+	mcc := digit(plmn[0]&0x0f) + digit(plmn[0]>>4) + digit(plmn[1]&0x0f)
+	mnc := digit(plmn[2]&0x0f) + digit(plmn[2]>>4)
+	if plmn[1]>>4 != 0x0f {
+		mnc += digit(plmn[1] >> 4)
+	}
+	return ANQPPLMN{MCC: mcc, MNC: mnc}
+}
+
+func decodeANQPDomainName(payload []byte) ([]string, error) {
+	domains := make([]string, 0)
+	for len(payload) > 0 {
+		length := int(payload[0])
+		if len(payload) < 1+length {
+			return nil, errANQPTruncated
+		}
+		domain := payload[1 : 1+length]
+		if !utf8.Valid(domain) {
+			return nil, errors.New("invalid domain name encoding")
+		}
+		domains = append(domains, string(domain))
+		payload = payload[1+length:]
+	}
+	return domains, nil
+}
+
+func decodeHS20WANMetrics(payload []byte) (*HS20WANMetrics, error) {
+	if len(payload) < 13 {
+		return nil, errANQPTruncated
+	}
+	return &HS20WANMetrics{
+		LinkStatus:              payload[0] & 0x03,
+		Symmetric:               payload[0]&0x04 != 0,
+		AtCapacity:              payload[0]&0x08 != 0,
+		DownlinkSpeed:           binary.LittleEndian.Uint32(payload[1:]),
+		UplinkSpeed:             binary.LittleEndian.Uint32(payload[5:]),
+		DownlinkLoad:            payload[9],
+		UplinkLoad:              payload[10],
+		LoadMeasurementDuration: binary.LittleEndian.Uint16(payload[11:]),
+	}, nil
+}
+
+func decodeHS20ConnectionCapability(payload []byte) ([]HS20ConnectionCapability, error) {
+	if len(payload)%4 != 0 {
+		return nil, errANQPTruncated
+	}
+	capabilities := make([]HS20ConnectionCapability, 0, len(payload)/4)
+	for i := 0; i < len(payload); i += 4 {
+		capabilities = append(capabilities, HS20ConnectionCapability{
+			Protocol: payload[i],
+			Port:     binary.LittleEndian.Uint16(payload[i+1:]),
+			Status:   payload[i+3],
+		})
+	}
+	return capabilities, nil
+}
@@ -0,0 +1,62 @@
+package wpaSuppDBusLib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeANQP(t *testing.T) {
+	elements := map[string][]byte{
+		// venue group 2 (business), type 8, one english name
+		"VenueName": {0x02, 0x08, 0x0c, 'e', 'n', 'g', 'W', 'a', 'r', 'e', 'h', 'o', 'u', 's', 'e'},
+		// acceptance of terms and conditions without redirect url
+		"NetworkAuthType":        {0x00, 0x00, 0x00},
+		"RoamingConsortium":      {0x03, 0x50, 0x6f, 0x9a, 0x05, 0x00, 0x1b, 0xc5, 0x04, 0xbd},
+		"IPAddrTypeAvailability": {0x0d},
+		// one realm with EAP-TTLS (21) and non-EAP inner auth MSCHAPv2 (id 2, value 4)
+		"NAIRealm": {0x01, 0x00, 0x14, 0x00, 0x00, 0x0b, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm', 0x01, 0x05, 0x15, 0x01, 0x02, 0x01, 0x04},
+		// plmn list with mcc 310 mnc 410 and mcc 262 mnc 01
+		"ANQP3GPP":                 {0x00, 0x09, 0x00, 0x07, 0x02, 0x13, 0x00, 0x14, 0x62, 0xf2, 0x10},
+		"DomainName":               {0x0b, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'},
+		"HS20OperatorFriendlyName": {0x07, 'e', 'n', 'g', 'A', 'c', 'm', 'e'},
+		"HS20WANMetrics":           {0x01, 0x10, 0x27, 0x00, 0x00, 0xe8, 0x03, 0x00, 0x00, 0x20, 0x10, 0x64, 0x00},
+		"HS20ConnectionCapability": {0x06, 0xbb, 0x01, 0x01, 0x11, 0xf4, 0x01, 0x00},
+	}
+	info, err := decodeANQP(elements)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ANQPInfo{
+		VenueName:                 &ANQPVenueInfo{VenueGroup: 2, VenueType: 8, Names: []ANQPLocalizedName{{Language: "eng", Name: "Warehouse"}}},
+		NetworkAuthTypes:          []ANQPNetworkAuthType{{Indicator: 0, RedirectURL: ""}},
+		RoamingConsortium:         []string{"506f9a", "001bc504bd"},
+		IPAddressTypeAvailability: &ANQPIPAddressTypeAvailability{IPv6: 1, IPv4: 3},
+		NAIRealms: []ANQPNAIRealm{{
+			Encoding:   0,
+			Realms:     []string{"example.com"},
+			EAPMethods: []ANQPEAPMethod{{Method: 21, AuthParams: []ANQPAuthParam{{ID: 2, Value: []byte{4}}}}},
+		}},
+		CellularNetworks:       []ANQPPLMN{{MCC: "310", MNC: "410"}, {MCC: "262", MNC: "01"}},
+		DomainNames:            []string{"example.com"},
+		OperatorFriendlyNames:  []ANQPLocalizedName{{Language: "eng", Name: "Acme"}},
+		WANMetrics:             &HS20WANMetrics{LinkStatus: 1, DownlinkSpeed: 10000, UplinkSpeed: 1000, DownlinkLoad: 0x20, UplinkLoad: 0x10, LoadMeasurementDuration: 100},
+		ConnectionCapabilities: []HS20ConnectionCapability{{Protocol: 6, Port: 443, Status: 1}, {Protocol: 17, Port: 500, Status: 0}},
+	}
+	if !reflect.DeepEqual(*info, expected) {
+		t.Errorf("decoded anqp does not match\n got: %+v\nwant: %+v", *info, expected)
+	}
+}
+
+func TestDecodeANQPRejectsTruncatedElements(t *testing.T) {
+	truncated := map[string][]byte{
+		"VenueName":      {0x02, 0x08, 0x0b, 'e', 'n'},
+		"NAIRealm":       {0x01, 0x00, 0x10, 0x00, 0x00},
+		"HS20WANMetrics": {0x01, 0x10},
+	}
+	for name, payload := range truncated {
+		if _, err := decodeANQP(map[string][]byte{name: payload}); err == nil {
+			t.Errorf("%s: expected error for truncated payload", name)
+		}
+	}
+}
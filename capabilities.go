@@ -0,0 +1,172 @@
+package wpaSuppDBusLib
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"strings"
+)
+
+// SupplicantCapabilities are the features and EAP methods the running wpa_supplicant was
+// compiled with. Features holds the root Capabilities, e.g. ap, p2p, interworking or mesh.
+type SupplicantCapabilities struct {
+	Features   []string
+	EAPMethods []string
+}
+
+// InterfaceCapabilities are the Capabilities of an interface as reported by its driver. The
+// values are lower case, e.g. ccmp, wpa-psk, rsn or infrastructure.
+type InterfaceCapabilities struct {
+	Pairwise    []string
+	Group       []string
+	GroupMgmt   []string
+	KeyMgmt     []string
+	Protocol    []string
+	AuthAlg     []string
+	Scan        []string
+	Modes       []string
+	MaxScanSSID int32
+}
+
+// Capabilities combines the capabilities a WPAInterface is validated against. A nil part is
+// not checked.
+type Capabilities struct {
+	Supplicant *SupplicantCapabilities
+	Interface  *InterfaceCapabilities
+}
+
+// capabilityKeyMgmt maps key management values to the names used in the KeyMgmt capability
+var capabilityKeyMgmt = map[KeyManagement]string{
+	WpaEAP:    "wpa-eap",
+	WpaPSK:    "wpa-psk",
+	IEEE8021X: "ieee8021x",
+	NONE:      "none",
+	SAE:       "sae",
+	FtEAP:     "wpa-ft-eap",
+	FtPSK:     "wpa-ft-psk",
+}
+
+// capabilityModes maps network modes to the names used in the Modes capability
+var capabilityModes = map[Mode]string{
+	ModeInfrastructure: "infrastructure",
+	ModeIBSS:           "ad-hoc",
+	ModeAP:             "ap",
+	ModeMesh:           "mesh",
+}
+
+// GetSupplicantCapabilities reads the root Capabilities and EapMethods properties
+func (wpaDbus *WpaSupplicantDbus) GetSupplicantCapabilities() (*SupplicantCapabilities, error) {
+	err := readCapabilities(wpaDbus)
+	if err != nil {
+		return nil, err
+	}
+	err = readEapMethods(wpaDbus)
+	if err != nil {
+		return nil, err
+	}
+	return &SupplicantCapabilities{Features: wpaDbus.Capabilities, EAPMethods: wpaDbus.EapMethods}, nil
+}
+
+// GetInterfaceCapabilities reads the Capabilities dictionary of an interface
+func (wpaDbus *WpaSupplicantDbus) GetInterfaceCapabilities(wpaInterfaceName dbus.ObjectPath) (*InterfaceCapabilities, error) {
+	var properties map[string]dbus.Variant
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "Capabilities", &properties)
+	if err != nil {
+		return nil, err
+	}
+	return newInterfaceCapabilities(properties), nil
+}
+
+func newInterfaceCapabilities(properties map[string]dbus.Variant) *InterfaceCapabilities {
+	return &InterfaceCapabilities{
+		Pairwise:    variantStrings(properties, "Pairwise"),
+		Group:       variantStrings(properties, "Group"),
+		GroupMgmt:   variantStrings(properties, "GroupMgmt"),
+		KeyMgmt:     variantStrings(properties, "KeyMgmt"),
+		Protocol:    variantStrings(properties, "Protocol"),
+		AuthAlg:     variantStrings(properties, "AuthAlg"),
+		Scan:        variantStrings(properties, "Scan"),
+		Modes:       variantStrings(properties, "Modes"),
+		MaxScanSSID: int32(variantInt(properties, "MaxScanSSID")),
+	}
+}
+
+// ValidateAgainst checks that the interface only uses EAP methods, features, ciphers, modes and
+// ssid specific scans the running supplicant and driver support. The returned MultiError lists every violation.
+func (wpa *WPAInterface) ValidateAgainst(caps Capabilities) error {
+	errs := make(MultiError, 0)
+	if caps.Supplicant != nil {
+		errs = append(errs, wpa.validateSupplicantCapabilities(caps.Supplicant)...)
+	}
+	if caps.Interface != nil {
+		for _, network := range wpa.network {
+			errs = append(errs, network.validateInterfaceCapabilities(caps.Interface)...)
+		}
+	}
+	return errs.errorOrNil()
+}
+
+func (wpa *WPAInterface) validateSupplicantCapabilities(caps *SupplicantCapabilities) MultiError {
+	errs := make(MultiError, 0)
+	if (wpa.interworking != defaultInterworking || wpa.hs20 != defaultHS20) && !containsFold(caps.Features, "interworking") {
+		errs = append(errs, fmt.Errorf("interworking is not supported by the supplicant"))
+	}
+	for _, cred := range wpa.credential {
+		if cred.eap != "" && !containsFold(caps.EAPMethods, string(cred.eap)) {
+			errs = append(errs, fmt.Errorf("credential of realm %q uses eap method %s which the supplicant does not support", cred.realm, cred.eap))
+		}
+	}
+	for _, network := range wpa.network {
+		for _, method := range network.eap {
+			if !containsFold(caps.EAPMethods, method.GetEAPName()) {
+				errs = append(errs, fmt.Errorf("network %q uses eap method %s which the supplicant does not support", network.ssid, method.GetEAPName()))
+			}
+		}
+		if network.mode == ModeAP && !containsFold(caps.Features, "ap") {
+			errs = append(errs, fmt.Errorf("network %q uses mode ap which the supplicant does not support", network.ssid))
+		}
+		if network.mode == ModeMesh && !containsFold(caps.Features, "mesh") {
+			errs = append(errs, fmt.Errorf("network %q uses mode mesh which the supplicant does not support", network.ssid))
+		}
+	}
+	return errs
+}
+
+func (net *Network) validateInterfaceCapabilities(caps *InterfaceCapabilities) MultiError {
+	errs := make(MultiError, 0)
+	unsupported := func(kind, value string, supported []string) {
+		if !containsFold(supported, value) {
+			errs = append(errs, fmt.Errorf("network %q uses %s %s which the interface does not support", net.ssid, kind, value))
+		}
+	}
+	for _, keyMgmt := range net.keyMngnt {
+		unsupported("key management", capabilityKeyMgmt[keyMgmt], caps.KeyMgmt)
+	}
+	for _, proto := range net.proto {
+		unsupported("protocol", string(proto), caps.Protocol)
+	}
+	for _, pairwise := range net.pairWise {
+		unsupported("pairwise cipher", string(pairwise), caps.Pairwise)
+	}
+	for _, group := range net.group {
+		unsupported("group cipher", string(group), caps.Group)
+	}
+	for _, authAlg := range net.authAlg {
+		unsupported("auth algorithm", string(authAlg), caps.AuthAlg)
+	}
+	if net.mode != -1 {
+		unsupported("mode", capabilityModes[net.mode], caps.Modes)
+	}
+	if net.scanSsid == ScanOff && caps.MaxScanSSID < 1 {
+		errs = append(errs, fmt.Errorf("network %q uses scan_ssid 1 but the interface can not scan for specific ssids", net.ssid))
+	}
+	return errs
+}
+
+func containsFold(list []string, value string) bool {
+	for _, entry := range list {
+		if strings.EqualFold(entry, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+	"strings"
+	"testing"
+)
+
+func TestValidateAgainstCapabilities(t *testing.T) {
+	peapBuilder := NewPEAPBuilder()
+	eapPEAP, _ := peapBuilder.WithIdentity("user_name").WithPassword("user_password").WithInnerAuthType(InnerAuthMsChapV2).Build()
+	corp, _ := NewNetworkBuilder().WithSSID("corp").WithScanSSID(ScanOff).WithKeyManagement(WpaEAP).WithPairWise(PairWiseCCMP).WithEAPMethods(eapPEAP).Build()
+	mesh, _ := NewNetworkBuilder().WithSSID("mesh").WithMode(ModeMesh).WithFrequency(2412).WithKeyManagement(SAE).WithPSK("mesh passphrase").Build()
+	wpaInterface, err := NewWpaInterfaceBuilder().WithNetwork(*corp, *mesh).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supported := Capabilities{
+		Supplicant: &SupplicantCapabilities{Features: []string{"ap", "mesh"}, EAPMethods: []string{"MD5", "TLS", "PEAP", "MSCHAPV2"}},
+		Interface: newInterfaceCapabilities(map[string]dbus.Variant{
+			"KeyMgmt":     dbus.MakeVariant([]string{"none", "wpa-psk", "wpa-eap", "sae"}),
+			"Pairwise":    dbus.MakeVariant([]string{"ccmp", "tkip"}),
+			"Modes":       dbus.MakeVariant([]string{"infrastructure", "ap", "mesh"}),
+			"MaxScanSSID": dbus.MakeVariant(int32(4)),
+		}),
+	}
+	if err := wpaInterface.ValidateAgainst(supported); err != nil {
+		t.Errorf("supported config rejected: %v", err)
+	}
+
+	limited := Capabilities{
+		Supplicant: &SupplicantCapabilities{Features: []string{"ap"}, EAPMethods: []string{"TLS"}},
+		Interface:  &InterfaceCapabilities{KeyMgmt: []string{"wpa-eap"}, Pairwise: []string{"ccmp"}, Modes: []string{"infrastructure"}},
+	}
+	err = wpaInterface.ValidateAgainst(limited)
+	multiErr, ok := err.(MultiError)
+	if !ok || len(multiErr) != 5 {
+		t.Fatalf("expected eap method, mesh feature, sae, mesh mode and scan ssid errors, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "network \"corp\" uses eap method PEAP") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
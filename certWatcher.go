@@ -0,0 +1,261 @@
+package wpaSuppDBusLib
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"os"
+	"sync"
+	"time"
+)
+
+var defaultCertPollInterval = time.Minute
+var defaultCertExpiryWarningWindow = 7 * 24 * time.Hour
+
+// CertWatcher polls the certificate and key files referenced by the TLS methods of every
+// interface in CreatedWPAInterfaces. It warns through the Logger when a certificate is about
+// to expire and, when a file is replaced, regenerates the interface config and forces a new
+// authentication so the rotated certificate is picked up.
+type CertWatcher struct {
+	wpaDbus             *WpaSupplicantDbus
+	pollInterval        time.Duration
+	expiryWarningWindow time.Duration
+	// mutex serializes passes, Check may be called while Run is active
+	mutex      sync.Mutex
+	fileStates map[certFileKey]certFileState
+}
+
+// certFileKey identifies a watched file per interface, so every interface sharing a certificate
+// or key sees the rotation
+type certFileKey struct {
+	ifPath string
+	file   string
+}
+
+type certFileState struct {
+	digest       [sha256.Size]byte
+	expiryWarned bool
+}
+
+// NewCertWatcher creates a watcher bound to the interfaces tracked by wpaDbus.
+// A zero pollInterval or expiryWarningWindow falls back to one minute and seven days respectively.
+func NewCertWatcher(wpaDbus *WpaSupplicantDbus, pollInterval, expiryWarningWindow time.Duration) *CertWatcher {
+	if pollInterval <= 0 {
+		pollInterval = defaultCertPollInterval
+	}
+	if expiryWarningWindow <= 0 {
+		expiryWarningWindow = defaultCertExpiryWarningWindow
+	}
+	return &CertWatcher{
+		wpaDbus:             wpaDbus,
+		pollInterval:        pollInterval,
+		expiryWarningWindow: expiryWarningWindow,
+		fileStates:          make(map[certFileKey]certFileState),
+	}
+}
+
+// Run checks the watched files every poll interval until ctx is cancelled.
+func (c *CertWatcher) Run(ctx context.Context) {
+	c.Check()
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Check()
+		}
+	}
+}
+
+// Check runs a single pass over the watched files. The first time a file is seen its
+// content is only recorded; subsequent changes trigger re-authentication of the interface.
+// A change is only recorded once the re-authentication succeeded, so a failed attempt is
+// retried on the next pass. Files of interfaces that are no longer tracked are forgotten.
+func (c *CertWatcher) Check() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	interfaces := c.trackedInterfaces()
+	for key := range c.fileStates {
+		if _, tracked := interfaces[key.ifPath]; !tracked {
+			delete(c.fileStates, key)
+		}
+	}
+	for ifPath, wpaInterface := range interfaces {
+		rotated := make(map[certFileKey][sha256.Size]byte)
+		for _, tls := range wpaInterface.tlsMethods() {
+			for _, file := range tls.watchedFiles() {
+				key := certFileKey{ifPath: ifPath, file: file}
+				digest, changed, err := c.refreshFileState(key)
+				if err != nil {
+					c.wpaDbus.logger.Warn(fmt.Sprintf("unable to read %s watched for %s: %v", file, ifPath, err))
+					continue
+				}
+				if changed {
+					rotated[key] = digest
+				}
+			}
+			c.checkExpiry(ifPath, tls.clientCert)
+			c.checkExpiry(ifPath, tls.caCertPath)
+		}
+		if len(rotated) == 0 {
+			continue
+		}
+		err := c.reauthenticate(dbus.ObjectPath(ifPath), wpaInterface)
+		if err != nil {
+			c.wpaDbus.logger.Error(fmt.Sprintf("unable to re-authenticate %s after certificate rotation: %v", ifPath, err))
+			continue
+		}
+		for key, digest := range rotated {
+			c.fileStates[key] = certFileState{digest: digest}
+		}
+	}
+}
+
+func (c *CertWatcher) trackedInterfaces() map[string]WPAInterface {
+	c.wpaDbus.mutex.Lock()
+	defer c.wpaDbus.mutex.Unlock()
+	interfaces := make(map[string]WPAInterface, len(c.wpaDbus.CreatedWPAInterfaces))
+	for ifPath, wpaInterface := range c.wpaDbus.CreatedWPAInterfaces {
+		interfaces[ifPath] = wpaInterface
+	}
+	return interfaces
+}
+
+// refreshFileState returns the current digest of the file and reports whether it differs from
+// the recorded one. Only the first digest of a file is recorded here, changed digests are recorded
+// by Check after the interface re-authenticated.
+func (c *CertWatcher) refreshFileState(key certFileKey) ([sha256.Size]byte, bool, error) {
+	content, err := os.ReadFile(key.file)
+	if err != nil {
+		return [sha256.Size]byte{}, false, err
+	}
+	digest := sha256.Sum256(content)
+	previous, known := c.fileStates[key]
+	if !known {
+		c.fileStates[key] = certFileState{digest: digest}
+		return digest, false, nil
+	}
+	return digest, previous.digest != digest, nil
+}
+
+func (c *CertWatcher) checkExpiry(ifPath, file string) {
+	if file == "" {
+		return
+	}
+	key := certFileKey{ifPath: ifPath, file: file}
+	state, known := c.fileStates[key]
+	if !known || state.expiryWarned {
+		return
+	}
+	notAfter, err := readCertificateExpiry(file)
+	if err != nil {
+		c.wpaDbus.logger.Warn(fmt.Sprintf("unable to read certificate expiry of %s: %v", file, err))
+		state.expiryWarned = true
+		c.fileStates[key] = state
+		return
+	}
+	remaining := time.Until(notAfter)
+	if remaining > c.expiryWarningWindow {
+		return
+	}
+	if remaining <= 0 {
+		c.wpaDbus.logger.Warn(fmt.Sprintf("certificate %s expired on %s", file, notAfter.Format(time.RFC3339)))
+	} else {
+		c.wpaDbus.logger.Warn(fmt.Sprintf("certificate %s expires on %s", file, notAfter.Format(time.RFC3339)))
+	}
+	state.expiryWarned = true
+	c.fileStates[key] = state
+}
+
+func (c *CertWatcher) reauthenticate(ifPath dbus.ObjectPath, wpaInterface WPAInterface) error {
+	c.wpaDbus.mutex.Lock()
+	ifConfig, known := c.wpaDbus.interfaceConfigs[string(ifPath)]
+	c.wpaDbus.mutex.Unlock()
+	if !known {
+		return reassociate(c.wpaDbus, ifPath)
+	}
+	err := writeInterfaceConfig(wpaInterface, ifConfig.configFile)
+	if err != nil {
+		return err
+	}
+	c.wpaDbus.logger.Info(fmt.Sprintf("certificate rotated, re-authenticating %s", ifConfig.ifname))
+	if ifConfig.driver != DriverWired && ifConfig.driver != DriverMacSecLinux {
+		return reassociate(c.wpaDbus, ifPath)
+	}
+	err = eapLogoff(c.wpaDbus, ifPath)
+	if err != nil {
+		return err
+	}
+	return eapLogon(c.wpaDbus, ifPath)
+}
+
+// readCertificateExpiry returns the earliest NotAfter of the certificates in a PEM or DER file.
+func readCertificateExpiry(file string) (time.Time, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	certs := make([]*x509.Certificate, 0)
+	rest := content
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		cert, err := x509.ParseCertificate(content)
+		if err != nil {
+			return time.Time{}, errors.New("no certificate found")
+		}
+		certs = append(certs, cert)
+	}
+	notAfter := certs[0].NotAfter
+	for i := 1; i < len(certs); i++ {
+		if certs[i].NotAfter.Before(notAfter) {
+			notAfter = certs[i].NotAfter
+		}
+	}
+	return notAfter, nil
+}
+
+func (t *tlsMethod) watchedFiles() []string {
+	files := make([]string, 0, 3)
+	if t.caCertPath != "" {
+		files = append(files, t.caCertPath)
+	}
+	if t.clientCert != "" {
+		files = append(files, t.clientCert)
+	}
+	if t.privateKey != "" && t.privateKey != t.clientCert {
+		files = append(files, t.privateKey)
+	}
+	return files
+}
+
+func (wpa *WPAInterface) tlsMethods() []*tlsMethod {
+	methods := make([]*tlsMethod, 0)
+	for i := 0; i < len(wpa.network); i++ {
+		for j := 0; j < len(wpa.network[i].eap); j++ {
+			if tls, ok := wpa.network[i].eap[j].(*tlsMethod); ok {
+				methods = append(methods, tls)
+			}
+		}
+	}
+	return methods
+}
@@ -0,0 +1,110 @@
+package wpaSuppDBusLib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, file string, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadCertificateExpiry(t *testing.T) {
+	file := path.Join(t.TempDir(), "client.pem")
+	notAfter := time.Now().Add(48 * time.Hour).Truncate(time.Second).UTC()
+	writeTestCertificate(t, file, notAfter)
+
+	expiry, err := readCertificateExpiry(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expiry.Equal(notAfter) {
+		t.Errorf("expected expiry %s, got %s", notAfter, expiry)
+	}
+}
+
+func TestCertWatcherDetectsRotation(t *testing.T) {
+	file := path.Join(t.TempDir(), "client.pem")
+	writeTestCertificate(t, file, time.Now().Add(time.Hour))
+	watcher := NewCertWatcher(&WpaSupplicantDbus{logger: newDefaultLogger()}, 0, 0)
+	first := certFileKey{ifPath: "/fi/w1/wpa_supplicant1/Interfaces/0", file: file}
+	second := certFileKey{ifPath: "/fi/w1/wpa_supplicant1/Interfaces/1", file: file}
+
+	for _, key := range []certFileKey{first, second} {
+		_, changed, err := watcher.refreshFileState(key)
+		if err != nil || changed {
+			t.Fatalf("first observation must not be reported as rotation (changed=%v, err=%v)", changed, err)
+		}
+	}
+	_, changed, _ := watcher.refreshFileState(first)
+	if changed {
+		t.Errorf("unchanged file reported as rotated")
+	}
+	writeTestCertificate(t, file, time.Now().Add(2*time.Hour))
+	digest, changed, _ := watcher.refreshFileState(first)
+	if !changed {
+		t.Errorf("replaced file not reported as rotated")
+	}
+	_, changed, _ = watcher.refreshFileState(first)
+	if !changed {
+		t.Errorf("rotation must be reported again until it is recorded after re-authentication")
+	}
+	watcher.fileStates[first] = certFileState{digest: digest}
+	if _, changed, _ = watcher.refreshFileState(first); changed {
+		t.Errorf("recorded rotation reported again")
+	}
+	if _, changed, _ = watcher.refreshFileState(second); !changed {
+		t.Errorf("rotation of a shared file must be reported for every interface")
+	}
+}
+
+func TestCertWatcherForgetsUntrackedInterfaces(t *testing.T) {
+	file := path.Join(t.TempDir(), "client.pem")
+	writeTestCertificate(t, file, time.Now().Add(time.Hour))
+	tlsBuilder := NewTLSBuilder()
+	tls, _ := tlsBuilder.WithIdentity("device").WithClientCertPath(file).WithPrivateKeyPath(file).Build()
+	wired, _ := NewNetworkBuilder().WithKeyManagement(IEEE8021X).WithEAPMethods(tls).Build()
+	wpaInterface, err := NewWpaInterfaceBuilder().WithNetwork(*wired).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wpaDbus := &WpaSupplicantDbus{logger: newDefaultLogger(), CreatedWPAInterfaces: map[string]WPAInterface{
+		"/fi/w1/wpa_supplicant1/Interfaces/0": *wpaInterface,
+	}}
+	watcher := NewCertWatcher(wpaDbus, 0, 0)
+	watcher.fileStates[certFileKey{ifPath: "/fi/w1/wpa_supplicant1/Interfaces/1", file: file}] = certFileState{}
+
+	watcher.Check()
+	if len(watcher.fileStates) != 1 {
+		t.Fatalf("expected only the file of the tracked interface, got %v", watcher.fileStates)
+	}
+	if _, known := watcher.fileStates[certFileKey{ifPath: "/fi/w1/wpa_supplicant1/Interfaces/0", file: file}]; !known {
+		t.Errorf("file of the tracked interface not recorded")
+	}
+}
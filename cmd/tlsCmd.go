@@ -39,9 +39,8 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	//print config
-	confStr := wpaInterface.ToConfigString()
-	log.Println(confStr)
+	//print config without the private key password
+	log.Println(wpaInterface.ToRedactedConfigString())
 
 	//use supplicant API to set it all up
 	supplicantAPI, _ := wpaSuppDBusLib.NewWpaSupplicantAPI()
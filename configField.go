@@ -0,0 +1,244 @@
+package wpaSuppDBusLib
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// configKind tells how an unquoted field value is sent over D-Bus
+type configKind uint8
+
+const (
+	configKindString configKind = iota
+	configKindInt
+	configKindHex
+)
+
+// configField is a single key=value line of a network or cred block. Keeping the fields in a
+// list lets the same definition be rendered to a config file and to a D-Bus argument map.
+type configField struct {
+	key    string
+	value  string
+	quoted bool
+	// literal fields are written in double quotes as they are, the key accepts no other encoding
+	literal bool
+	kind    configKind
+}
+
+// quotedField is a string value, it is written in the encoding wpa_supplicant parses back to
+// exactly the same bytes, see encodeConfigString
+func quotedField(key, value string) configField {
+	return configField{key: key, value: value, quoted: true}
+}
+
+// passphraseField is a psk passphrase, wpa_supplicant only accepts it in plain double quotes.
+// The builders make sure it is printable ASCII.
+func passphraseField(key, value string) configField {
+	return configField{key: key, value: value, quoted: true, literal: true}
+}
+
+// rawField is an unquoted value that is sent over D-Bus as a string, e.g. a bssid or an ext: reference
+func rawField(key string, value interface{}) configField {
+	return configField{key: key, value: fmt.Sprint(value)}
+}
+
+// intField is a number, it is sent over D-Bus as int32 or, when it does not fit, as uint32
+func intField(key string, value interface{}) configField {
+	return configField{key: key, value: fmt.Sprint(value), kind: configKindInt}
+}
+
+// hexField is a hex encoded key like a raw psk or mka_cak, it is sent over D-Bus as a byte array
+// so leading zeros are kept
+func hexField(key, value string) configField {
+	return configField{key: key, value: value, kind: configKindHex}
+}
+
+// listField joins a slice of values into a space separated field, e.g. key_mgmt=WPA-PSK SAE
+func listField(key string, values interface{}) configField {
+	return rawField(key, strings.Trim(fmt.Sprint(values), "[]"))
+}
+
+func (f configField) toConfigString() string {
+	if f.literal {
+		return fmt.Sprintf("%s=\"%s\"", f.key, f.value)
+	}
+	if f.quoted {
+		return fmt.Sprintf("%s=%s", f.key, encodeConfigString(f.value))
+	}
+	return fmt.Sprintf("%s=%s", f.key, f.value)
+}
+
+// encodeConfigString encodes a string value the way wpa_config_parse_string reads it. Printable
+// UTF-8 is written in double quotes, wpa_supplicant takes everything up to the last quote of the
+// line so embedded quotes need no escaping. UTF-8 with control characters is written as a printf
+// escaped P"..." string and anything else, e.g. a binary SSID, as hex.
+func encodeConfigString(value string) string {
+	if !utf8.ValidString(value) {
+		return hex.EncodeToString([]byte(value))
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return "P\"" + printfEscape(value) + "\""
+		}
+	}
+	return "\"" + value + "\""
+}
+
+// printfEscape escapes value for printf_decode. Quotes are written as \x22 so the line never holds
+// a quote the comment stripping of the config parser could pair up.
+func printfEscape(value string) string {
+	builder := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\':
+			builder.WriteString("\\\\")
+		case c == '\n':
+			builder.WriteString("\\n")
+		case c == '\r':
+			builder.WriteString("\\r")
+		case c == '\t':
+			builder.WriteString("\\t")
+		case c == '"' || c == '#' || c < 0x20 || c == 0x7f:
+			builder.WriteString(fmt.Sprintf("\\x%02x", c))
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// decodeConfigString is the inverse of encodeConfigString and accepts every form
+// wpa_config_parse_string does: "quoted", P"printf escaped" and hex
+func decodeConfigString(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "\""):
+		end := strings.LastIndex(value, "\"")
+		if end != len(value)-1 || end == 0 {
+			return "", errors.New("invalid quoted string")
+		}
+		return value[1:end], nil
+	case strings.HasPrefix(value, "P\""):
+		end := strings.LastIndex(value, "\"")
+		if end != len(value)-1 || end == 1 {
+			return "", errors.New("invalid printf string")
+		}
+		return printfDecode(value[2:end])
+	}
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		return "", errors.New("invalid hex string")
+	}
+	return string(decoded), nil
+}
+
+func printfDecode(value string) (string, error) {
+	builder := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			builder.WriteByte(value[i])
+			continue
+		}
+		i++
+		if i == len(value) {
+			return "", errors.New("invalid escape at end of string")
+		}
+		switch value[i] {
+		case '\\', '"':
+			builder.WriteByte(value[i])
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 't':
+			builder.WriteByte('\t')
+		case 'e':
+			builder.WriteByte(0x1b)
+		case 'x':
+			if i+2 >= len(value) {
+				return "", errors.New("invalid hex escape")
+			}
+			decoded, err := hex.DecodeString(value[i+1 : i+3])
+			if err != nil {
+				return "", errors.New("invalid hex escape")
+			}
+			builder.WriteByte(decoded[0])
+			i += 2
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", value[i])
+		}
+	}
+	return builder.String(), nil
+}
+
+// checkDBusSupported rejects ext: and hash: values. wpa_supplicant only resolves them when
+// reading a config file, values set over D-Bus are taken literally.
+func checkDBusSupported(fields []configField) error {
+	for _, field := range fields {
+		if !field.quoted && (strings.HasPrefix(field.value, "ext:") || strings.HasPrefix(field.value, "hash:")) {
+			return fmt.Errorf("%s uses a %s value which wpa_supplicant only accepts from config files",
+				field.key, field.value[:strings.Index(field.value, ":")+1])
+		}
+	}
+	return nil
+}
+
+func renderConfigFields(indent string, fields []configField) string {
+	builder := strings.Builder{}
+	for _, field := range fields {
+		builder.WriteString(indent)
+		builder.WriteString(field.toConfigString())
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// dbusUnquotedKeys mirrors the keys wpa_supplicant does not wrap in quotes when they are
+// received as D-Bus strings (dont_quote in dbus_new_handlers.c)
+var dbusUnquotedKeys = []string{"key_mgmt", "proto", "pairwise", "auth_alg", "group", "eap",
+	"bssid", "scan_freq", "freq_list", "scan_ssid", "bssid_hint", "bssid_ignore", "bssid_accept",
+	"group_mgmt", "ignore_broadcast_ssid", "mesh_basic_rates", "go_p2p_dev_addr", "p2p_client_list",
+	"psk_list", "roaming_consortium", "required_roaming_consortium"}
+
+// configFieldsToDBusArgs converts fields into the dictionary expected by AddNetwork, AddCred and
+// friends. wpa_supplicant quotes every string that is not listed in dbusUnquotedKeys, so unquoted
+// values are sent in the type of their kind: integers for int fields and byte arrays for hex fields.
+// D-Bus strings must be UTF-8, other string values are sent as byte arrays which wpa_supplicant
+// stores as they are.
+func configFieldsToDBusArgs(fields []configField) map[string]interface{} {
+	args := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if field.quoted && !utf8.ValidString(field.value) {
+			args[field.key] = []byte(field.value)
+			continue
+		}
+		if field.quoted || contains(dbusUnquotedKeys, field.key) {
+			args[field.key] = field.value
+			continue
+		}
+		switch field.kind {
+		case configKindInt:
+			if number, err := strconv.ParseInt(field.value, 10, 64); err == nil && number >= math.MinInt32 && number <= math.MaxInt32 {
+				args[field.key] = int32(number)
+				continue
+			}
+			if number, err := strconv.ParseUint(field.value, 10, 32); err == nil {
+				args[field.key] = uint32(number)
+				continue
+			}
+		case configKindHex:
+			if decoded, err := hex.DecodeString(field.value); err == nil {
+				args[field.key] = decoded
+				continue
+			}
+		}
+		args[field.key] = field.value
+	}
+	return args
+}
@@ -0,0 +1,140 @@
+package wpaSuppDBusLib
+
+import (
+	"strings"
+	"testing"
+)
+
+// readConfigLine splits a config line the way wpa_config_get_line and wpa_config_parse_string
+// do: leading white space is skipped, a # after the last quoted part starts a comment and
+// trailing white space is dropped
+func readConfigLine(line string) (string, string, bool) {
+	line = strings.TrimLeft(line, " \t")
+	start := 0
+	if first := strings.Index(line, "\""); first >= 0 {
+		if last := strings.LastIndex(line, "\""); last > first {
+			start = last
+		}
+	}
+	if comment := strings.Index(line[start:], "#"); comment >= 0 {
+		line = line[:start+comment]
+	}
+	line = strings.TrimRight(line, " \t\r")
+	separator := strings.Index(line, "=")
+	if separator <= 0 {
+		return "", "", false
+	}
+	return line[:separator], line[separator+1:], true
+}
+
+func TestEncodeConfigString(t *testing.T) {
+	encoded := map[string]string{
+		"home":        `"home"`,
+		`pass"word`:   `"pass"word"`,
+		"pass#word":   `"pass#word"`,
+		"line\nbreak": `P"line\nbreak"`,
+		"tab\t\"#":    `P"tab\t\x22\x23"`,
+		"\xff\xfe":    "fffe",
+		"café ☃":      "\"café ☃\"",
+	}
+	for value, expected := range encoded {
+		if actual := encodeConfigString(value); actual != expected {
+			t.Errorf("%q encoded as %s, expected %s", value, actual, expected)
+		}
+	}
+}
+
+func TestNetworkConfigLimits(t *testing.T) {
+	if _, err := NewNetworkBuilder().WithSSID(strings.Repeat("x", 33)).WithKeyManagement(NONE).Build(); err == nil {
+		t.Errorf("ssid longer than 32 bytes must be rejected")
+	}
+	if _, err := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithPSK("pass\nword!").Build(); err == nil {
+		t.Errorf("psk with line break must be rejected")
+	}
+	network, err := NewNetworkBuilder().WithSSID("home").WithBSSID("00:11:22:33:44:55").WithKeyManagement(NONE).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(network.ToConfigString(), "  bssid=00:11:22:33:44:55\n") {
+		t.Errorf("bssid must not be quoted: %s", network.ToConfigString())
+	}
+
+	peapBuilder := NewPEAPBuilder()
+	peap, err := peapBuilder.WithIdentity("user").WithPasswordHash("8846f7eaee8fb117ad06bdd830b7586c").WithInnerAuthType(InnerAuthMsChapV2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(peap.ToConfigString(), "  password=hash:8846f7eaee8fb117ad06bdd830b7586c\n") {
+		t.Errorf("unexpected password hash: %s", peap.ToConfigString())
+	}
+	peapBuilder = NewPEAPBuilder()
+	if _, err := peapBuilder.WithIdentity("user").WithPasswordHash("8846f7ea").WithInnerAuthType(InnerAuthMsChapV2).Build(); err == nil {
+		t.Errorf("short password hash must be rejected")
+	}
+	peapBuilder = NewPEAPBuilder()
+	if _, err := peapBuilder.WithIdentity("user").WithPasswordHash("8846f7eaee8fb117ad06bdd830b7586c").WithInnerAuthType(InnerAuthGTC).Build(); err == nil {
+		t.Errorf("password hash with GTC must be rejected")
+	}
+}
+
+func FuzzEncodeConfigString(f *testing.F) {
+	for _, seed := range []string{"", "home", `"`, `a"b#c"`, "#", "\\x41", "line\nbreak", "\x00\xff", " padded ", "P\"x\""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		encoded := encodeConfigString(value)
+		if strings.ContainsAny(encoded, "\n\r") {
+			t.Fatalf("%q encoded to multiple lines: %q", value, encoded)
+		}
+		key, raw, ok := readConfigLine("  identity=" + encoded)
+		if !ok || key != "identity" {
+			t.Fatalf("%q encoded to unreadable line %q", value, encoded)
+		}
+		decoded, err := decodeConfigString(raw)
+		if err != nil {
+			t.Fatalf("%q encoded to %q which does not decode: %v", value, encoded, err)
+		}
+		if decoded != value {
+			t.Fatalf("%q encoded to %q decodes to %q", value, encoded, decoded)
+		}
+	})
+}
+
+func FuzzNetworkToConfigString(f *testing.F) {
+	f.Add("home", "user", "password")
+	f.Add("caf\xe9", "user\"\n}\nnetwork={", "pass#word\"")
+	f.Fuzz(func(t *testing.T, ssid string, identity string, password string) {
+		ttlsBuilder := NewTTLSBuilder()
+		ttls, err := ttlsBuilder.WithIdentity(identity).WithPassword(password).WithInnerAuthType(InnerAuthMsChapV2).Build()
+		if err != nil {
+			return
+		}
+		network, err := NewNetworkBuilder().WithSSID(ssid).WithKeyManagement(WpaEAP).WithEAPMethods(ttls).Build()
+		if err != nil {
+			return
+		}
+		expected := map[string]string{"ssid": ssid, "identity": identity, "password": password, "phase2": "auth=MSCHAPV2"}
+		lines := strings.Split(strings.TrimSuffix(network.ToConfigString(), "\n"), "\n")
+		if lines[0] != "network={" || lines[len(lines)-1] != "}" {
+			t.Fatalf("broken network block %q", lines)
+		}
+		for _, line := range lines[1 : len(lines)-1] {
+			key, raw, ok := readConfigLine(line)
+			if !ok {
+				t.Fatalf("unreadable line %q", line)
+			}
+			value, ok := expected[key]
+			if !ok {
+				continue
+			}
+			decoded, err := decodeConfigString(raw)
+			if err != nil || decoded != value {
+				t.Fatalf("%s=%q re-parses as %q (%v)", key, value, decoded, err)
+			}
+			delete(expected, key)
+		}
+		if len(expected) > 0 {
+			t.Fatalf("fields missing from the config: %v", expected)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"net"
+	"strings"
+)
+
+var dbusWPABSSname = "fi.w1.wpa_supplicant1.BSS"
+
+// ANQPQueryResult is reported when an ANQPGet query finishes. Result is SUCCESS, FAILURE,
+// INVALID_FRAME or NO_RESPONSE as reported by the supplicant.
+type ANQPQueryResult struct {
+	Addr   string
+	Result string
+}
+
+// ANQPGet sends an ANQP query for the given Info IDs to the access point with the given BSSID.
+// The response is cached in the BSS object and can be read with GetBSSANQP once
+// SubscribeANQPQueryDone reports completion. Hotspot 2.0 elements (operator friendly name,
+// WAN metrics, connection capability) cannot be requested through D-Bus, they are fetched
+// by InterworkingSelect.
+func (wpaDbus *WpaSupplicantDbus) ANQPGet(wpaInterfaceName dbus.ObjectPath, bssid string, infoIDs ...ANQPInfoID) error {
+	if _, err := net.ParseMAC(bssid); err != nil {
+		return errors.New("invalid value for bssid")
+	}
+	if len(infoIDs) == 0 {
+		return errors.New("at least one anqp info id must be specified")
+	}
+	ids := make([]uint16, 0, len(infoIDs))
+	for _, id := range infoIDs {
+		ids = append(ids, uint16(id))
+	}
+	args := map[string]interface{}{
+		"addr": bssid,
+		"ids":  ids,
+	}
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "ANQPGet", args)
+}
+
+// SubscribeANQPQueryDone forwards the ANQPQueryDone signals of the interface to resultChan.
+// The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeANQPQueryDone(wpaInterfaceName dbus.ObjectPath, resultChan chan ANQPQueryResult) (func(), error) {
+	return subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, "ANQPQueryDone", func(signal *dbus.Signal, done <-chan struct{}) {
+		if len(signal.Body) < 2 {
+			return
+		}
+		addr, _ := signal.Body[0].(string)
+		result, _ := signal.Body[1].(string)
+		select {
+		case resultChan <- ANQPQueryResult{Addr: addr, Result: result}:
+		case <-done:
+		}
+	})
+}
+
+// GetBSSs returns the object paths of the BSSs currently known to the interface
+func (wpaDbus *WpaSupplicantDbus) GetBSSs(wpaInterfaceName dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	var bsss []dbus.ObjectPath
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "BSSs", &bsss)
+	if err != nil {
+		return nil, err
+	}
+	return bsss, nil
+}
+
+// GetBSSID returns the BSSID of a BSS object formatted as aa:bb:cc:dd:ee:ff
+func (wpaDbus *WpaSupplicantDbus) GetBSSID(bssPath dbus.ObjectPath) (string, error) {
+	var bssid []byte
+	err := readObjectProperty(wpaDbus, bssPath, dbusWPABSSname, "BSSID", &bssid)
+	if err != nil {
+		return "", err
+	}
+	return net.HardwareAddr(bssid).String(), nil
+}
+
+// FindBSS returns the object path of the BSS with the given BSSID
+func (wpaDbus *WpaSupplicantDbus) FindBSS(wpaInterfaceName dbus.ObjectPath, bssid string) (dbus.ObjectPath, error) {
+	bsss, err := wpaDbus.GetBSSs(wpaInterfaceName)
+	if err != nil {
+		return "", err
+	}
+	for _, bssPath := range bsss {
+		current, err := wpaDbus.GetBSSID(bssPath)
+		if err != nil {
+			return "", err
+		}
+		if strings.EqualFold(current, bssid) {
+			return bssPath, nil
+		}
+	}
+	return "", errors.New("bss " + bssid + " not found")
+}
+
+// GetBSSANQP reads and decodes the ANQP elements the supplicant cached for a BSS
+func (wpaDbus *WpaSupplicantDbus) GetBSSANQP(bssPath dbus.ObjectPath) (*ANQPInfo, error) {
+	var properties map[string]dbus.Variant
+	err := readObjectProperty(wpaDbus, bssPath, dbusWPABSSname, "ANQP", &properties)
+	if err != nil {
+		return nil, err
+	}
+	elements := make(map[string][]byte, len(properties))
+	for name, value := range properties {
+		if payload, ok := value.Value().([]byte); ok {
+			elements[name] = payload
+		}
+	}
+	return decodeANQP(elements)
+}
@@ -0,0 +1,137 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+	"net"
+)
+
+var dbusWPAStationname = "fi.w1.wpa_supplicant1.Station"
+
+type StationEventType string
+
+const (
+	StationAdded    StationEventType = "StationAdded"
+	StationRemoved  StationEventType = "StationRemoved"
+	StaAuthorized   StationEventType = "StaAuthorized"
+	StaDeauthorized StationEventType = "StaDeauthorized"
+)
+
+var stationEventSlice = []StationEventType{StationAdded, StationRemoved, StaAuthorized, StaDeauthorized}
+
+// Station holds the properties of a station associated with an AP mode interface
+type Station struct {
+	Path         dbus.ObjectPath
+	Address      string
+	AID          uint16
+	Capabilities uint16
+	RxPackets    uint64
+	TxPackets    uint64
+	RxBytes      uint64
+	TxBytes      uint64
+}
+
+// StationEvent is a typed station signal. Station is set for StationAdded and StationRemoved,
+// Address for StaAuthorized and StaDeauthorized. StationAdded also carries the station properties.
+type StationEvent struct {
+	Type       StationEventType
+	Station    dbus.ObjectPath
+	Address    string
+	Properties *Station
+}
+
+// GetStations returns the object paths of the stations associated with the AP
+func (wpaDbus *WpaSupplicantDbus) GetStations(wpaInterfaceName dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	var stations []dbus.ObjectPath
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "Stations", &stations)
+	if err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// GetStation reads the properties of a Station object
+func (wpaDbus *WpaSupplicantDbus) GetStation(stationPath dbus.ObjectPath) (*Station, error) {
+	properties, err := readAllObjectProperties(wpaDbus, stationPath, dbusWPAStationname)
+	if err != nil {
+		return nil, err
+	}
+	return newStation(stationPath, properties), nil
+}
+
+// ListStations reads the properties of every station associated with the AP
+func (wpaDbus *WpaSupplicantDbus) ListStations(wpaInterfaceName dbus.ObjectPath) ([]Station, error) {
+	paths, err := wpaDbus.GetStations(wpaInterfaceName)
+	if err != nil {
+		return nil, err
+	}
+	stations := make([]Station, 0, len(paths))
+	for _, path := range paths {
+		station, err := wpaDbus.GetStation(path)
+		if err != nil {
+			return nil, err
+		}
+		stations = append(stations, *station)
+	}
+	return stations, nil
+}
+
+// SubscribeStationEvents forwards the station signals of the interface to eventChan.
+// The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeStationEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan StationEvent) (func(), error) {
+	unsubscribers := make([]func(), 0, len(stationEventSlice))
+	unsubscribeAll := func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+	for _, eventType := range stationEventSlice {
+		eventType := eventType
+		unsubscribe, err := subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, string(eventType), func(signal *dbus.Signal, done <-chan struct{}) {
+			select {
+			case eventChan <- decodeStationEvent(eventType, signal):
+			case <-done:
+			}
+		})
+		if err != nil {
+			unsubscribeAll()
+			return nil, err
+		}
+		unsubscribers = append(unsubscribers, unsubscribe)
+	}
+	return unsubscribeAll, nil
+}
+
+func decodeStationEvent(eventType StationEventType, signal *dbus.Signal) StationEvent {
+	event := StationEvent{Type: eventType}
+	switch eventType {
+	case StationAdded:
+		event.Station = signalObjectPath(signal, 0)
+		if properties := signalVariants(signal, 1); properties != nil {
+			event.Properties = newStation(event.Station, properties)
+			event.Address = event.Properties.Address
+		}
+	case StationRemoved:
+		event.Station = signalObjectPath(signal, 0)
+	case StaAuthorized, StaDeauthorized:
+		if len(signal.Body) > 0 {
+			event.Address, _ = signal.Body[0].(string)
+		}
+	}
+	return event
+}
+
+func newStation(stationPath dbus.ObjectPath, properties map[string]dbus.Variant) *Station {
+	station := &Station{
+		Path:         stationPath,
+		AID:          uint16(variantInt(properties, "AID")),
+		Capabilities: uint16(variantInt(properties, "Capabilities")),
+		RxPackets:    uint64(variantInt(properties, "RxPackets")),
+		TxPackets:    uint64(variantInt(properties, "TxPackets")),
+		RxBytes:      uint64(variantInt(properties, "RxBytes")),
+		TxBytes:      uint64(variantInt(properties, "TxBytes")),
+	}
+	if addr := variantBytes(properties, "Address"); len(addr) > 0 {
+		station.Address = net.HardwareAddr(addr).String()
+	}
+	return station
+}
@@ -0,0 +1,112 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+)
+
+type DebugLevel string
+
+// debug levels from most to least verbose
+const (
+	DebugExcessive DebugLevel = "excessive"
+	DebugMsgDump   DebugLevel = "msgdump"
+	DebugDebug     DebugLevel = "debug"
+	DebugInfo      DebugLevel = "info"
+	DebugWarning   DebugLevel = "warning"
+	DebugError     DebugLevel = "error"
+)
+
+var debugLevelSlice = []DebugLevel{DebugExcessive, DebugMsgDump, DebugDebug, DebugInfo, DebugWarning, DebugError}
+
+// moreVerbose reports whether level logs more than other
+func (level DebugLevel) moreVerbose(other DebugLevel) bool {
+	return debugLevelIndex(level) < debugLevelIndex(other)
+}
+
+func debugLevelIndex(level DebugLevel) int {
+	for i, l := range debugLevelSlice {
+		if l == level {
+			return i
+		}
+	}
+	return len(debugLevelSlice)
+}
+
+// SetDebugLevel changes the log level of the running supplicant
+func (wpaDbus *WpaSupplicantDbus) SetDebugLevel(level DebugLevel) error {
+	if !contains(debugLevelSlice, level) {
+		return errors.New("invalid value for debug level")
+	}
+	err := writeObjectProperty(wpaDbus, dbusWPAObjectPath, dbusWPAname, "DebugLevel", string(level))
+	if err != nil {
+		return err
+	}
+	wpaDbus.DebugLevel = string(level)
+	return nil
+}
+
+// SetDebugTimestamp enables or disables timestamps in the supplicant log
+func (wpaDbus *WpaSupplicantDbus) SetDebugTimestamp(timestamp bool) error {
+	err := writeObjectProperty(wpaDbus, dbusWPAObjectPath, dbusWPAname, "DebugTimestamp", timestamp)
+	if err != nil {
+		return err
+	}
+	wpaDbus.DebugTimeStamp = timestamp
+	return nil
+}
+
+// SetDebugShowKeys enables or disables logging of keys and passwords. Only enable it while debugging.
+func (wpaDbus *WpaSupplicantDbus) SetDebugShowKeys(showKeys bool) error {
+	err := writeObjectProperty(wpaDbus, dbusWPAObjectPath, dbusWPAname, "DebugShowKeys", showKeys)
+	if err != nil {
+		return err
+	}
+	wpaDbus.DebugShowKeys = showKeys
+	return nil
+}
+
+// DebugSession raises the log level of the supplicant to at least level and enables timestamps
+// while fn runs. Afterwards, also when fn panics, the previous settings are restored. The
+// returned error holds the error of fn and any failure to change or restore the settings.
+func (wpaDbus *WpaSupplicantDbus) DebugSession(level DebugLevel, fn func() error) (err error) {
+	if !contains(debugLevelSlice, level) {
+		return errors.New("invalid value for debug level")
+	}
+	errs := make(MultiError, 0)
+	if e := readDebugLevel(wpaDbus); e != nil {
+		return e
+	}
+	if e := readDebugTimeStamp(wpaDbus); e != nil {
+		return e
+	}
+	previousLevel := DebugLevel(wpaDbus.DebugLevel)
+	previousTimestamp := wpaDbus.DebugTimeStamp
+	defer func() {
+		if DebugLevel(wpaDbus.DebugLevel) != previousLevel {
+			if e := wpaDbus.SetDebugLevel(previousLevel); e != nil {
+				errs = append(errs, e)
+			}
+		}
+		if wpaDbus.DebugTimeStamp != previousTimestamp {
+			if e := wpaDbus.SetDebugTimestamp(previousTimestamp); e != nil {
+				errs = append(errs, e)
+			}
+		}
+		err = errs.errorOrNil()
+	}()
+	if level.moreVerbose(previousLevel) {
+		if e := wpaDbus.SetDebugLevel(level); e != nil {
+			errs = append(errs, e)
+			return
+		}
+	}
+	if !previousTimestamp {
+		if e := wpaDbus.SetDebugTimestamp(true); e != nil {
+			errs = append(errs, e)
+		}
+	}
+	if e := fn(); e != nil {
+		errs = append(errs, e)
+	}
+	return
+}
@@ -0,0 +1,36 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"testing"
+)
+
+func TestDebugLevelVerbosity(t *testing.T) {
+	if !DebugExcessive.moreVerbose(DebugInfo) || !DebugDebug.moreVerbose(DebugError) {
+		t.Errorf("more verbose levels must sort first")
+	}
+	if DebugInfo.moreVerbose(DebugInfo) || DebugWarning.moreVerbose(DebugMsgDump) {
+		t.Errorf("less or equally verbose levels must not be reported as more verbose")
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	if (MultiError{}).errorOrNil() != nil {
+		t.Errorf("empty MultiError must be nil")
+	}
+	err := MultiError{errors.New("DebugLevel failed"), errors.New("EapMethods failed")}.errorOrNil()
+	if err == nil || err.Error() != "DebugLevel failed; EapMethods failed" {
+		t.Errorf("unexpected error %v", err)
+	}
+	dbusErr := dbus.MakeFailedError(errors.New("access denied"))
+	err = MultiError{errors.New("DebugLevel failed"), dbusErr}.errorOrNil()
+	var target *dbus.Error
+	if !errors.As(err, &target) || !errors.Is(err, dbusErr) {
+		t.Errorf("aggregated dbus error not found in %v", err)
+	}
+	multiErr := err.(MultiError)
+	if !multiErr.As(&target) || !multiErr.Is(dbusErr) || multiErr.Is(errors.New("other")) {
+		t.Errorf("Is and As must look into the collected errors of %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+// EAPEvent is an EAP signal of an interface. Status is e.g. started, method, completion or
+// remote certificate verification, Parameter holds the method name for method and success
+// or failure for completion.
+type EAPEvent struct {
+	Status    string
+	Parameter string
+}
+
+// GetState returns the wpa_supplicant state of the interface, e.g. completed or disconnected
+func (wpaDbus *WpaSupplicantDbus) GetState(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var state string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "State", &state)
+	return state, err
+}
+
+// SubscribeStateChanges forwards every new State of the interface to stateChan.
+// The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeStateChanges(wpaInterfaceName dbus.ObjectPath, stateChan chan string) (func(), error) {
+	return subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, "PropertiesChanged", func(signal *dbus.Signal, done <-chan struct{}) {
+		properties := signalVariants(signal, 0)
+		if _, ok := properties["State"]; ok {
+			select {
+			case stateChan <- variantString(properties, "State"):
+			case <-done:
+			}
+		}
+	})
+}
+
+// SubscribeEAPEvents forwards the EAP signals of the interface to eventChan.
+// The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeEAPEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan EAPEvent) (func(), error) {
+	return subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, "EAP", func(signal *dbus.Signal, done <-chan struct{}) {
+		if len(signal.Body) < 2 {
+			return
+		}
+		status, _ := signal.Body[0].(string)
+		parameter, _ := signal.Body[1].(string)
+		select {
+		case eventChan <- EAPEvent{Status: status, Parameter: parameter}:
+		case <-done:
+		}
+	})
+}
+
+// SubscribeScanDone forwards the result of every finished scan of the interface to resultChan.
+// The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeScanDone(wpaInterfaceName dbus.ObjectPath, resultChan chan bool) (func(), error) {
+	return subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, "ScanDone", func(signal *dbus.Signal, done <-chan struct{}) {
+		if len(signal.Body) < 1 {
+			return
+		}
+		success, _ := signal.Body[0].(bool)
+		select {
+		case resultChan <- success:
+		case <-done:
+		}
+	})
+}
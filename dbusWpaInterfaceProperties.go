@@ -0,0 +1,253 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"strconv"
+)
+
+// MAC address randomization mask keys of the MACAddressRandomizationMask property
+const (
+	MACRandomizationScan      = "scan"
+	MACRandomizationSchedScan = "sched_scan"
+	MACRandomizationPNO       = "pno"
+)
+
+var macRandomizationSlice = []string{MACRandomizationScan, MACRandomizationSchedScan, MACRandomizationPNO}
+
+// GetApScan returns the ap_scan mode the interface is currently running with
+func (wpaDbus *WpaSupplicantDbus) GetApScan(wpaInterfaceName dbus.ObjectPath) (ApScan, error) {
+	var apScan uint32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "ApScan", &apScan)
+	return ApScan(apScan), err
+}
+
+// SetApScan changes the ap_scan mode of the interface
+func (wpaDbus *WpaSupplicantDbus) SetApScan(wpaInterfaceName dbus.ObjectPath, apScan ApScan) error {
+	if !contains(apScanSlice, apScan) {
+		return errors.New("invalid value for ap scan")
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "ApScan", uint32(apScan))
+}
+
+// GetBSSExpireAge returns how many seconds an unseen BSS is kept in the BSS table
+func (wpaDbus *WpaSupplicantDbus) GetBSSExpireAge(wpaInterfaceName dbus.ObjectPath) (uint32, error) {
+	var age uint32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "BSSExpireAge", &age)
+	return age, err
+}
+
+// SetBSSExpireAge sets how many seconds an unseen BSS is kept in the BSS table, at least 10
+func (wpaDbus *WpaSupplicantDbus) SetBSSExpireAge(wpaInterfaceName dbus.ObjectPath, age uint32) error {
+	if age < 10 {
+		return errors.New("invalid value for bss expiration age. must be at least 10 seconds")
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "BSSExpireAge", age)
+}
+
+// GetBSSExpireCount returns after how many scans without it an unseen BSS is removed
+func (wpaDbus *WpaSupplicantDbus) GetBSSExpireCount(wpaInterfaceName dbus.ObjectPath) (uint32, error) {
+	var count uint32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "BSSExpireCount", &count)
+	return count, err
+}
+
+// SetBSSExpireCount sets after how many scans without it an unseen BSS is removed, at least 1
+func (wpaDbus *WpaSupplicantDbus) SetBSSExpireCount(wpaInterfaceName dbus.ObjectPath, count uint32) error {
+	if count == 0 {
+		return errors.New("invalid value for bss expiration scan count. must be at least 1")
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "BSSExpireCount", count)
+}
+
+// GetCountry returns the ISO 3166-1 alpha-2 country code of the interface
+func (wpaDbus *WpaSupplicantDbus) GetCountry(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var country string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "Country", &country)
+	return country, err
+}
+
+// SetCountry changes the regulatory domain of the interface
+func (wpaDbus *WpaSupplicantDbus) SetCountry(wpaInterfaceName dbus.ObjectPath, country string) error {
+	if !countryRegex.MatchString(country) {
+		return errors.New("invalid value for country. must be an ISO 3166-1 alpha-2 code")
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "Country", country)
+}
+
+// GetFastReauth reports whether fast re-authentication (EAP session resumption) is enabled
+func (wpaDbus *WpaSupplicantDbus) GetFastReauth(wpaInterfaceName dbus.ObjectPath) (FastReauth, error) {
+	var fastReauth bool
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "FastReauth", &fastReauth)
+	if fastReauth {
+		return FastReauthOn, err
+	}
+	return FastReauthOff, err
+}
+
+// SetFastReauth enables or disables fast re-authentication
+func (wpaDbus *WpaSupplicantDbus) SetFastReauth(wpaInterfaceName dbus.ObjectPath, fastReauth FastReauth) error {
+	if !contains(fastReauthSlice, fastReauth) {
+		return errors.New("invalid value for fast reauth")
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "FastReauth", fastReauth == FastReauthOn)
+}
+
+// GetScanInterval returns the interval in seconds between scans while looking for a network
+func (wpaDbus *WpaSupplicantDbus) GetScanInterval(wpaInterfaceName dbus.ObjectPath) (int32, error) {
+	var interval int32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "ScanInterval", &interval)
+	return interval, err
+}
+
+// SetScanInterval sets the interval in seconds between scans while looking for a network
+func (wpaDbus *WpaSupplicantDbus) SetScanInterval(wpaInterfaceName dbus.ObjectPath, interval int32) error {
+	if interval < 0 {
+		return errors.New("invalid value for scan interval")
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "ScanInterval", interval)
+}
+
+// GetEapolVersion returns the EAPOL version the interface uses
+func (wpaDbus *WpaSupplicantDbus) GetEapolVersion(wpaInterfaceName dbus.ObjectPath) (EapolVersion, error) {
+	var eapolVersion string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "EapolVersion", &eapolVersion)
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.ParseUint(eapolVersion, 10, 8)
+	if err != nil {
+		return 0, err
+	}
+	return EapolVersion(version), nil
+}
+
+// SetEapolVersion changes the EAPOL version the interface uses
+func (wpaDbus *WpaSupplicantDbus) SetEapolVersion(wpaInterfaceName dbus.ObjectPath, eapolVersion EapolVersion) error {
+	if !contains(eapolVersionSlice, eapolVersion) {
+		return errors.New("invalid value for eapol version")
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "EapolVersion", strconv.Itoa(int(eapolVersion)))
+}
+
+// GetMACAddressRandomizationMask returns the masks applied to random MAC addresses, keyed by
+// scan, sched_scan or pno
+func (wpaDbus *WpaSupplicantDbus) GetMACAddressRandomizationMask(wpaInterfaceName dbus.ObjectPath) (map[string][]byte, error) {
+	var masks map[string][]byte
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "MACAddressRandomizationMask", &masks)
+	if err != nil {
+		return nil, err
+	}
+	return masks, nil
+}
+
+// SetMACAddressRandomizationMask enables MAC address randomization for the given scan types. Each
+// mask is 6 bytes; bits set in the mask are randomized. Scan types missing from masks are disabled.
+func (wpaDbus *WpaSupplicantDbus) SetMACAddressRandomizationMask(wpaInterfaceName dbus.ObjectPath, masks map[string][]byte) error {
+	for scanType, mask := range masks {
+		if !contains(macRandomizationSlice, scanType) || len(mask) != 6 {
+			return errors.New("invalid value for mac address randomization mask")
+		}
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "MACAddressRandomizationMask", masks)
+}
+
+// GetApIsolate reports whether stations connected to the AP of the interface are isolated from each other
+func (wpaDbus *WpaSupplicantDbus) GetApIsolate(wpaInterfaceName dbus.ObjectPath) (bool, error) {
+	var apIsolate string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "ApIsolate", &apIsolate)
+	return apIsolate == "1", err
+}
+
+// SetApIsolate enables or disables isolation of the stations connected to the AP of the interface
+func (wpaDbus *WpaSupplicantDbus) SetApIsolate(wpaInterfaceName dbus.ObjectPath, apIsolate bool) error {
+	value := "0"
+	if apIsolate {
+		value = "1"
+	}
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "ApIsolate", value)
+}
+
+// GetSchedScanPlans returns the scheduled scan plans, e.g. "10:100 20:200 30"
+func (wpaDbus *WpaSupplicantDbus) GetSchedScanPlans(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var plans string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "SchedScanPlans", &plans)
+	return plans, err
+}
+
+// SetSchedScanPlans sets the scheduled scan plans as space separated <interval>:<iterations>
+// pairs, the last plan being only an interval that repeats forever
+func (wpaDbus *WpaSupplicantDbus) SetSchedScanPlans(wpaInterfaceName dbus.ObjectPath, plans string) error {
+	return writeInterfaceProperty(wpaDbus, wpaInterfaceName, "SchedScanPlans", plans)
+}
+
+// GetIfname returns the name of the network interface the supplicant interface controls
+func (wpaDbus *WpaSupplicantDbus) GetIfname(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var ifname string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "Ifname", &ifname)
+	return ifname, err
+}
+
+// GetDriver returns the driver backend of the interface, e.g. nl80211 or wired
+func (wpaDbus *WpaSupplicantDbus) GetDriver(wpaInterfaceName dbus.ObjectPath) (Driver, error) {
+	var driver string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "Driver", &driver)
+	return Driver(driver), err
+}
+
+// GetBridgeIfname returns the bridge the interface is attached to, empty if none
+func (wpaDbus *WpaSupplicantDbus) GetBridgeIfname(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var bridge string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "BridgeIfname", &bridge)
+	return bridge, err
+}
+
+// GetConfigFile returns the config file the interface was created with, empty if none
+func (wpaDbus *WpaSupplicantDbus) GetConfigFile(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var configFile string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "ConfigFile", &configFile)
+	return configFile, err
+}
+
+// GetCurrentNetwork returns the object path of the network in use, "/" when not connected
+func (wpaDbus *WpaSupplicantDbus) GetCurrentNetwork(wpaInterfaceName dbus.ObjectPath) (dbus.ObjectPath, error) {
+	var network dbus.ObjectPath
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "CurrentNetwork", &network)
+	return network, err
+}
+
+// GetCurrentAuthMode returns the authentication mode of the current connection, e.g. WPA2-PSK or EAP-TLS
+func (wpaDbus *WpaSupplicantDbus) GetCurrentAuthMode(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var authMode string
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "CurrentAuthMode", &authMode)
+	return authMode, err
+}
+
+// GetDisconnectReason returns the IEEE 802.11 reason code of the last disconnect. It is
+// negative when the disconnect was triggered locally.
+func (wpaDbus *WpaSupplicantDbus) GetDisconnectReason(wpaInterfaceName dbus.ObjectPath) (int32, error) {
+	var reason int32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "DisconnectReason", &reason)
+	return reason, err
+}
+
+// GetAuthStatusCode returns the IEEE 802.11 status code of the last authentication
+func (wpaDbus *WpaSupplicantDbus) GetAuthStatusCode(wpaInterfaceName dbus.ObjectPath) (int32, error) {
+	var status int32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "AuthStatusCode", &status)
+	return status, err
+}
+
+// GetAssocStatusCode returns the IEEE 802.11 status code of the last association
+func (wpaDbus *WpaSupplicantDbus) GetAssocStatusCode(wpaInterfaceName dbus.ObjectPath) (int32, error) {
+	var status int32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "AssocStatusCode", &status)
+	return status, err
+}
+
+// GetScanning reports whether the interface is currently scanning
+func (wpaDbus *WpaSupplicantDbus) GetScanning(wpaInterfaceName dbus.ObjectPath) (bool, error) {
+	var scanning bool
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "Scanning", &scanning)
+	return scanning, err
+}
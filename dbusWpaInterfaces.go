@@ -0,0 +1,137 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+type InterfaceEventType string
+
+const (
+	InterfaceAdded   InterfaceEventType = "InterfaceAdded"
+	InterfaceRemoved InterfaceEventType = "InterfaceRemoved"
+)
+
+var interfaceEventSlice = []InterfaceEventType{InterfaceAdded, InterfaceRemoved}
+
+// InterfaceInfo describes an interface known to the supplicant. Created is true for interfaces
+// created through this WpaSupplicantDbus, false for ones added by other processes.
+type InterfaceInfo struct {
+	Path         dbus.ObjectPath
+	Ifname       string
+	Driver       Driver
+	BridgeIfname string
+	ConfigFile   string
+	Created      bool
+}
+
+// InterfaceEvent is a root InterfaceAdded or InterfaceRemoved signal. Info is only set for
+// InterfaceAdded, a removed interface can no longer be queried.
+type InterfaceEvent struct {
+	Type InterfaceEventType
+	Path dbus.ObjectPath
+	Info *InterfaceInfo
+}
+
+// GetInterfaces returns the object paths of all interfaces the supplicant controls
+func (wpaDbus *WpaSupplicantDbus) GetInterfaces() ([]dbus.ObjectPath, error) {
+	var interfaces []dbus.ObjectPath
+	err := readObjectProperty(wpaDbus, dbusWPAObjectPath, dbusWPAname, "Interfaces", &interfaces)
+	if err != nil {
+		return nil, err
+	}
+	return interfaces, nil
+}
+
+// ListInterfaces returns every interface the supplicant controls, including the ones created by
+// other processes like NetworkManager. Interfaces removed while the list is read are left out.
+func (wpaDbus *WpaSupplicantDbus) ListInterfaces() ([]InterfaceInfo, error) {
+	paths, err := wpaDbus.GetInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	interfaces := make([]InterfaceInfo, 0, len(paths))
+	for _, ifPath := range paths {
+		info, err := wpaDbus.readInterfaceInfo(ifPath)
+		if isObjectUnknown(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		interfaces = append(interfaces, *info)
+	}
+	return interfaces, nil
+}
+
+// SubscribeInterfaceEvents forwards the root InterfaceAdded and InterfaceRemoved signals to
+// eventChan. The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeInterfaceEvents(eventChan chan InterfaceEvent) (func(), error) {
+	unsubscribers := make([]func(), 0, len(interfaceEventSlice))
+	unsubscribeAll := func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+	for _, eventType := range interfaceEventSlice {
+		eventType := eventType
+		unsubscribe, err := subscribeSignal(wpaDbus, dbusWPAObjectPath, dbusWPAname, string(eventType), func(signal *dbus.Signal, done <-chan struct{}) {
+			event := wpaDbus.newInterfaceEvent(eventType, signal)
+			if eventType == InterfaceRemoved {
+				forgetInterface(wpaDbus, string(event.Path))
+			}
+			select {
+			case eventChan <- event:
+			case <-done:
+			}
+		})
+		if err != nil {
+			unsubscribeAll()
+			return nil, err
+		}
+		unsubscribers = append(unsubscribers, unsubscribe)
+	}
+	return unsubscribeAll, nil
+}
+
+// newInterfaceEvent decodes a root InterfaceAdded or InterfaceRemoved signal
+func (wpaDbus *WpaSupplicantDbus) newInterfaceEvent(eventType InterfaceEventType, signal *dbus.Signal) InterfaceEvent {
+	event := InterfaceEvent{Type: eventType, Path: signalObjectPath(signal, 0)}
+	if eventType == InterfaceAdded {
+		event.Info = wpaDbus.newInterfaceInfo(event.Path, signalVariants(signal, 1))
+	}
+	return event
+}
+
+// watchInterfaceRemovals drops interfaces from CreatedWPAInterfaces once the supplicant removes
+// them, e.g. because another process called RemoveInterface or the device disappeared. It is
+// installed by the constructor so no removal is missed.
+func watchInterfaceRemovals(wpaDbus *WpaSupplicantDbus) {
+	_, err := subscribeSignal(wpaDbus, dbusWPAObjectPath, dbusWPAname, string(InterfaceRemoved), func(signal *dbus.Signal, _ <-chan struct{}) {
+		forgetInterface(wpaDbus, string(signalObjectPath(signal, 0)))
+	})
+	if err != nil {
+		wpaDbus.logger.Warn(err)
+	}
+}
+
+func (wpaDbus *WpaSupplicantDbus) readInterfaceInfo(ifPath dbus.ObjectPath) (*InterfaceInfo, error) {
+	properties, err := readAllObjectProperties(wpaDbus, ifPath, dbusWPAInterfacename)
+	if err != nil {
+		return nil, err
+	}
+	return wpaDbus.newInterfaceInfo(ifPath, properties), nil
+}
+
+func (wpaDbus *WpaSupplicantDbus) newInterfaceInfo(ifPath dbus.ObjectPath, properties map[string]dbus.Variant) *InterfaceInfo {
+	wpaDbus.mutex.Lock()
+	_, created := wpaDbus.CreatedWPAInterfaces[string(ifPath)]
+	wpaDbus.mutex.Unlock()
+	return &InterfaceInfo{
+		Path:         ifPath,
+		Ifname:       variantString(properties, "Ifname"),
+		Driver:       Driver(variantString(properties, "Driver")),
+		BridgeIfname: variantString(properties, "BridgeIfname"),
+		ConfigFile:   variantString(properties, "ConfigFile"),
+		Created:      created,
+	}
+}
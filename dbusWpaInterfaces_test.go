@@ -0,0 +1,44 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"testing"
+)
+
+func TestNewInterfaceEvent(t *testing.T) {
+	created := dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/0")
+	foreign := dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/1")
+	wpaDbus := &WpaSupplicantDbus{CreatedWPAInterfaces: map[string]WPAInterface{string(created): {}}}
+
+	added := &dbus.Signal{Name: dbusWPAname + ".InterfaceAdded", Body: []interface{}{foreign, map[string]dbus.Variant{
+		"Ifname":       dbus.MakeVariant("wlan0"),
+		"Driver":       dbus.MakeVariant("nl80211"),
+		"BridgeIfname": dbus.MakeVariant("br0"),
+		"ConfigFile":   dbus.MakeVariant("/etc/wpa_supplicant/wlan0.conf"),
+	}}}
+	event := wpaDbus.newInterfaceEvent(InterfaceAdded, added)
+	expected := InterfaceInfo{Path: foreign, Ifname: "wlan0", Driver: DriverNL80211, BridgeIfname: "br0", ConfigFile: "/etc/wpa_supplicant/wlan0.conf"}
+	if event.Type != InterfaceAdded || event.Path != foreign || event.Info == nil || *event.Info != expected {
+		t.Errorf("unexpected added event %+v", event)
+	}
+
+	if info := wpaDbus.newInterfaceInfo(created, map[string]dbus.Variant{"Ifname": dbus.MakeVariant("eth0")}); !info.Created || info.Ifname != "eth0" {
+		t.Errorf("interface created through the library not marked: %+v", info)
+	}
+
+	removed := &dbus.Signal{Name: dbusWPAname + ".InterfaceRemoved", Body: []interface{}{created}}
+	event = wpaDbus.newInterfaceEvent(InterfaceRemoved, removed)
+	if event.Type != InterfaceRemoved || event.Path != created || event.Info != nil {
+		t.Errorf("unexpected removed event %+v", event)
+	}
+}
+
+func TestIsObjectUnknown(t *testing.T) {
+	if !isObjectUnknown(dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownObject"}) {
+		t.Errorf("UnknownObject not detected")
+	}
+	if isObjectUnknown(dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}) || isObjectUnknown(errors.New("UnknownObject")) {
+		t.Errorf("other errors must not be treated as unknown object")
+	}
+}
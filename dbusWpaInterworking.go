@@ -0,0 +1,88 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+type InterworkingEventType string
+
+const (
+	InterworkingAPAdded    InterworkingEventType = "InterworkingAPAdded"
+	InterworkingSelectDone InterworkingEventType = "InterworkingSelectDone"
+)
+
+// InterworkingEvent is emitted while an interworking selection runs. For InterworkingAPAdded
+// BSS and Cred identify the matching access point and credential, MatchType is one of
+// home, roaming or unknown. InterworkingSelectDone carries no data.
+type InterworkingEvent struct {
+	Type       InterworkingEventType
+	BSS        dbus.ObjectPath
+	Cred       dbus.ObjectPath
+	MatchType  string
+	Properties map[string]dbus.Variant
+}
+
+// AddCred adds a Hotspot 2.0 credential to a running interface and returns its object path
+func (wpaDbus *WpaSupplicantDbus) AddCred(wpaInterfaceName dbus.ObjectPath, cred Credential) (dbus.ObjectPath, error) {
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, wpaInterfaceName)
+	var credPath dbus.ObjectPath
+	err := obj.Call(dbusWPAInterfacename+".AddCred", 0, cred.toDBusArgs()).Store(&credPath)
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return "", err
+	}
+	return credPath, nil
+}
+
+// RemoveCred removes a credential previously added with AddCred
+func (wpaDbus *WpaSupplicantDbus) RemoveCred(wpaInterfaceName dbus.ObjectPath, credPath dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "RemoveCred", credPath)
+}
+
+// RemoveAllCreds removes every credential of the interface
+func (wpaDbus *WpaSupplicantDbus) RemoveAllCreds(wpaInterfaceName dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "RemoveAllCreds")
+}
+
+// InterworkingSelect starts an ANQP fetch and interworking network selection. Progress is
+// reported through the events of SubscribeInterworkingEvents.
+func (wpaDbus *WpaSupplicantDbus) InterworkingSelect(wpaInterfaceName dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "InterworkingSelect")
+}
+
+// SubscribeInterworkingEvents forwards the InterworkingAPAdded and InterworkingSelectDone signals
+// of the interface to eventChan. The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeInterworkingEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan InterworkingEvent) (func(), error) {
+	unsubscribeAPAdded, err := subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, string(InterworkingAPAdded), func(signal *dbus.Signal, done <-chan struct{}) {
+		event := InterworkingEvent{
+			Type:       InterworkingAPAdded,
+			BSS:        signalObjectPath(signal, 0),
+			Cred:       signalObjectPath(signal, 1),
+			Properties: signalVariants(signal, 2),
+		}
+		if matchType, ok := event.Properties["type"]; ok {
+			event.MatchType, _ = matchType.Value().(string)
+		}
+		select {
+		case eventChan <- event:
+		case <-done:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	unsubscribeSelectDone, err := subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, string(InterworkingSelectDone), func(signal *dbus.Signal, done <-chan struct{}) {
+		select {
+		case eventChan <- InterworkingEvent{Type: InterworkingSelectDone}:
+		case <-done:
+		}
+	})
+	if err != nil {
+		unsubscribeAPAdded()
+		return nil, err
+	}
+	return func() {
+		unsubscribeAPAdded()
+		unsubscribeSelectDone()
+	}, nil
+}
@@ -0,0 +1,104 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"net"
+)
+
+var dbusWPAMeshname = "fi.w1.wpa_supplicant1.Interface.Mesh"
+
+type MeshEventType string
+
+const (
+	MeshGroupStarted     MeshEventType = "MeshGroupStarted"
+	MeshGroupRemoved     MeshEventType = "MeshGroupRemoved"
+	MeshPeerConnected    MeshEventType = "MeshPeerConnected"
+	MeshPeerDisconnected MeshEventType = "MeshPeerDisconnected"
+)
+
+var meshEventSlice = []MeshEventType{MeshGroupStarted, MeshGroupRemoved, MeshPeerConnected, MeshPeerDisconnected}
+
+// MeshEvent is a typed Mesh signal. SSID is set for the group events, PeerAddress for the
+// peer events and DisconnectReason for MeshGroupRemoved and MeshPeerDisconnected.
+type MeshEvent struct {
+	Type             MeshEventType
+	SSID             []byte
+	PeerAddress      string
+	DisconnectReason int64
+}
+
+// MeshGroupAdd joins or starts the mesh described by network, which must be built with ModeMesh
+func (wpaDbus *WpaSupplicantDbus) MeshGroupAdd(wpaInterfaceName dbus.ObjectPath, network Network) error {
+	if network.mode != ModeMesh {
+		return errors.New("mesh group requires a network in mode mesh")
+	}
+	if err := checkDBusSupported(network.allConfigFields()); err != nil {
+		return err
+	}
+	return callObjectMethod(wpaDbus, wpaInterfaceName, dbusWPAMeshname, "MeshGroupAdd", network.toDBusArgs())
+}
+
+// MeshGroupRemove leaves the current mesh group
+func (wpaDbus *WpaSupplicantDbus) MeshGroupRemove(wpaInterfaceName dbus.ObjectPath) error {
+	return callObjectMethod(wpaDbus, wpaInterfaceName, dbusWPAMeshname, "MeshGroupRemove")
+}
+
+// GetMeshPeers returns the addresses of the connected mesh peers formatted as aa:bb:cc:dd:ee:ff
+func (wpaDbus *WpaSupplicantDbus) GetMeshPeers(wpaInterfaceName dbus.ObjectPath) ([]string, error) {
+	var peers [][]byte
+	err := readObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAMeshname, "MeshPeers", &peers)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		addresses = append(addresses, net.HardwareAddr(peer).String())
+	}
+	return addresses, nil
+}
+
+// GetMeshGroup returns the mesh ID (SSID) of the current mesh group
+func (wpaDbus *WpaSupplicantDbus) GetMeshGroup(wpaInterfaceName dbus.ObjectPath) ([]byte, error) {
+	var group []byte
+	err := readObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAMeshname, "MeshGroup", &group)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// SubscribeMeshEvents forwards the Mesh signals of the interface to eventChan.
+// The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeMeshEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan MeshEvent) (func(), error) {
+	unsubscribers := make([]func(), 0, len(meshEventSlice))
+	unsubscribeAll := func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+	for _, eventType := range meshEventSlice {
+		eventType := eventType
+		unsubscribe, err := subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAMeshname, string(eventType), func(signal *dbus.Signal, done <-chan struct{}) {
+			args := signalVariants(signal, 0)
+			event := MeshEvent{
+				Type:             eventType,
+				SSID:             variantBytes(args, "SSID"),
+				DisconnectReason: variantInt(args, "DisconnectReason"),
+			}
+			if addr := variantBytes(args, "PeerAddress"); len(addr) > 0 {
+				event.PeerAddress = net.HardwareAddr(addr).String()
+			}
+			select {
+			case eventChan <- event:
+			case <-done:
+			}
+		})
+		if err != nil {
+			unsubscribeAll()
+			return nil, err
+		}
+		unsubscribers = append(unsubscribers, unsubscribe)
+	}
+	return unsubscribeAll, nil
+}
@@ -0,0 +1,193 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+	"sort"
+	"strings"
+)
+
+var dbusWPANetworkname = "fi.w1.wpa_supplicant1.Network"
+
+// secretConfigKeys are the network keys wpa_supplicant never returns in the Properties of a
+// Network object, so they can not be compared against the running configuration
+var secretConfigKeys = []string{"psk", "password", "private_key_passwd", "private_key2_passwd",
+	"sae_password", "mka_cak", "wep_key0", "wep_key1", "wep_key2", "wep_key3", "pin"}
+
+// listConfigKeys are the network keys holding space separated lists
+var listConfigKeys = []string{"key_mgmt", "proto", "pairwise", "group", "eap"}
+
+// AddNetwork adds network to a running interface and returns its object path. The network is
+// added disabled, enable it with SetNetworkEnabled or SelectNetwork.
+func (wpaDbus *WpaSupplicantDbus) AddNetwork(wpaInterfaceName dbus.ObjectPath, network Network) (dbus.ObjectPath, error) {
+	err := checkDBusSupported(network.allConfigFields())
+	if err != nil {
+		return "", err
+	}
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, wpaInterfaceName)
+	var networkPath dbus.ObjectPath
+	err = obj.Call(dbusWPAInterfacename+".AddNetwork", 0, network.toDBusArgs()).Store(&networkPath)
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return "", err
+	}
+	return networkPath, nil
+}
+
+// RemoveNetwork removes a network, disconnecting first if it is in use
+func (wpaDbus *WpaSupplicantDbus) RemoveNetwork(wpaInterfaceName dbus.ObjectPath, networkPath dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "RemoveNetwork", networkPath)
+}
+
+// RemoveAllNetworks removes every network of the interface
+func (wpaDbus *WpaSupplicantDbus) RemoveAllNetworks(wpaInterfaceName dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "RemoveAllNetworks")
+}
+
+// SelectNetwork connects to the network and disables all others
+func (wpaDbus *WpaSupplicantDbus) SelectNetwork(wpaInterfaceName dbus.ObjectPath, networkPath dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "SelectNetwork", networkPath)
+}
+
+// GetNetworks returns the object paths of the networks configured on the interface
+func (wpaDbus *WpaSupplicantDbus) GetNetworks(wpaInterfaceName dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	var networks []dbus.ObjectPath
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "Networks", &networks)
+	if err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
+
+// GetNetworkProperties returns the configuration of a network as wpa_supplicant renders it in a
+// config file, i.e. strings keep their quotes. Secrets like psk or password are not included.
+func (wpaDbus *WpaSupplicantDbus) GetNetworkProperties(networkPath dbus.ObjectPath) (map[string]string, error) {
+	var properties map[string]dbus.Variant
+	err := readObjectProperty(wpaDbus, networkPath, dbusWPANetworkname, "Properties", &properties)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(properties))
+	for key := range properties {
+		values[key] = variantString(properties, key)
+	}
+	return values, nil
+}
+
+// SetNetworkProperties changes single fields of a configured network. The values follow the
+// same rules as AddNetwork.
+func (wpaDbus *WpaSupplicantDbus) SetNetworkProperties(networkPath dbus.ObjectPath, properties map[string]interface{}) error {
+	return writeObjectProperty(wpaDbus, networkPath, dbusWPANetworkname, "Properties", properties)
+}
+
+// GetNetworkEnabled reports whether the network may be selected for a connection
+func (wpaDbus *WpaSupplicantDbus) GetNetworkEnabled(networkPath dbus.ObjectPath) (bool, error) {
+	var enabled bool
+	err := readObjectProperty(wpaDbus, networkPath, dbusWPANetworkname, "Enabled", &enabled)
+	return enabled, err
+}
+
+// SetNetworkEnabled enables or disables the network
+func (wpaDbus *WpaSupplicantDbus) SetNetworkEnabled(networkPath dbus.ObjectPath, enabled bool) error {
+	return writeObjectProperty(wpaDbus, networkPath, dbusWPANetworkname, "Enabled", enabled)
+}
+
+// networkMatches reports whether the live properties of a Network object contain every non
+// secret field of network with the same value
+func networkMatches(network Network, live map[string]string) bool {
+	for _, field := range network.allConfigFields() {
+		if contains(secretConfigKeys, field.key) {
+			continue
+		}
+		value, ok := live[field.key]
+		if !ok || !configValueMatches(field, value) {
+			return false
+		}
+	}
+	return len(staleNetworkFields(network, live)) == 0
+}
+
+// configValueMatches compares a live property with the value of a desired field. Values of
+// listConfigKeys are compared as sets since wpa_supplicant renders them in its own order.
+func configValueMatches(field configField, liveValue string) bool {
+	liveValue = liveConfigValue(field, liveValue)
+	if !contains(listConfigKeys, field.key) {
+		return liveValue == field.value
+	}
+	liveItems := strings.Fields(liveValue)
+	desiredItems := strings.Fields(field.value)
+	for _, item := range liveItems {
+		if !contains(desiredItems, item) {
+			return false
+		}
+	}
+	for _, item := range desiredItems {
+		if !contains(liveItems, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// staleNetworkFields returns the sorted keys of the string properties the live network has but
+// network does not set. wpa_supplicant only reports string properties that were set, and they can
+// not be cleared through D-Bus, so such a network has to be replaced.
+func staleNetworkFields(network Network, live map[string]string) []string {
+	desired := make([]string, 0)
+	for _, field := range network.allConfigFields() {
+		desired = append(desired, field.key)
+	}
+	stale := make([]string, 0)
+	for key, value := range live {
+		if !strings.HasPrefix(value, "\"") && !strings.HasPrefix(value, "P\"") {
+			continue
+		}
+		if !contains(desired, key) && !contains(secretConfigKeys, key) {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// networksMatch reports whether every desired network has exactly one matching live network
+func networksMatch(networks []Network, live []map[string]string) bool {
+	if len(networks) != len(live) {
+		return false
+	}
+	used := make([]bool, len(live))
+	for _, network := range networks {
+		found := false
+		for i := range live {
+			if !used[i] && networkMatches(network, live[i]) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// liveConfigValue decodes a live property in the encoding of field. wpa_supplicant returns string
+// properties holding control or non ASCII bytes as bare hex, e.g. the SSID "Café" as 436166c3a9.
+func liveConfigValue(field configField, liveValue string) string {
+	if field.quoted && !strings.HasPrefix(liveValue, "\"") && !strings.HasPrefix(liveValue, "P\"") {
+		if decoded, err := decodeConfigString(liveValue); err == nil {
+			return decoded
+		}
+	}
+	return unquoteConfigValue(liveValue)
+}
+
+// unquoteConfigValue decodes a quoted or P"..." string property, other values are returned as they are
+func unquoteConfigValue(value string) string {
+	if strings.HasPrefix(value, "\"") || strings.HasPrefix(value, "P\"") {
+		if decoded, err := decodeConfigString(value); err == nil {
+			return decoded
+		}
+	}
+	return value
+}
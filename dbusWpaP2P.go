@@ -0,0 +1,696 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"net"
+)
+
+var dbusWPAP2PDevicename = "fi.w1.wpa_supplicant1.Interface.P2PDevice"
+var dbusWPAPeername = "fi.w1.wpa_supplicant1.Peer"
+var dbusWPAGroupname = "fi.w1.wpa_supplicant1.Group"
+var dbusWPAPersistentGroupname = "fi.w1.wpa_supplicant1.PersistentGroup"
+
+type P2PDiscoveryType string
+type P2PWPSMethod string
+type P2PConfigMethod string
+type P2PEventType string
+
+const (
+	P2PDiscoveryFull        P2PDiscoveryType = "start_with_full"
+	P2PDiscoverySocial      P2PDiscoveryType = "social"
+	P2PDiscoveryProgressive P2PDiscoveryType = "progressive"
+)
+
+const (
+	P2PWPSPBC     P2PWPSMethod = "pbc"
+	P2PWPSDisplay P2PWPSMethod = "display"
+	P2PWPSKeypad  P2PWPSMethod = "keypad"
+	P2PWPSPin     P2PWPSMethod = "pin"
+)
+
+const (
+	P2PConfigDisplay    P2PConfigMethod = "display"
+	P2PConfigKeypad     P2PConfigMethod = "keypad"
+	P2PConfigPBC        P2PConfigMethod = "pbc"
+	P2PConfigPushbutton P2PConfigMethod = "pushbutton"
+)
+
+const (
+	P2PDeviceFound                          P2PEventType = "DeviceFound"
+	P2PDeviceLost                           P2PEventType = "DeviceLost"
+	P2PFindStopped                          P2PEventType = "FindStopped"
+	P2PProvisionDiscoveryRequestDisplayPin  P2PEventType = "ProvisionDiscoveryRequestDisplayPin"
+	P2PProvisionDiscoveryResponseDisplayPin P2PEventType = "ProvisionDiscoveryResponseDisplayPin"
+	P2PProvisionDiscoveryRequestEnterPin    P2PEventType = "ProvisionDiscoveryRequestEnterPin"
+	P2PProvisionDiscoveryResponseEnterPin   P2PEventType = "ProvisionDiscoveryResponseEnterPin"
+	P2PProvisionDiscoveryPBCRequest         P2PEventType = "ProvisionDiscoveryPBCRequest"
+	P2PProvisionDiscoveryPBCResponse        P2PEventType = "ProvisionDiscoveryPBCResponse"
+	P2PProvisionDiscoveryFailure            P2PEventType = "ProvisionDiscoveryFailure"
+	P2PGONegotiationRequest                 P2PEventType = "GONegotiationRequest"
+	P2PGONegotiationSuccess                 P2PEventType = "GONegotiationSuccess"
+	P2PGONegotiationFailure                 P2PEventType = "GONegotiationFailure"
+	P2PGroupStarted                         P2PEventType = "GroupStarted"
+	P2PGroupFinished                        P2PEventType = "GroupFinished"
+	P2PGroupFormationFailure                P2PEventType = "GroupFormationFailure"
+	P2PInvitationResult                     P2PEventType = "InvitationResult"
+	P2PInvitationReceived                   P2PEventType = "InvitationReceived"
+	P2PServiceDiscoveryRequest              P2PEventType = "ServiceDiscoveryRequest"
+	P2PServiceDiscoveryResponse             P2PEventType = "ServiceDiscoveryResponse"
+	P2PPersistentGroupAdded                 P2PEventType = "PersistentGroupAdded"
+	P2PPersistentGroupRemoved               P2PEventType = "PersistentGroupRemoved"
+	P2PWpsFailed                            P2PEventType = "WpsFailed"
+	P2PPeerJoined                           P2PEventType = "PeerJoined"
+	P2PPeerDisconnected                     P2PEventType = "PeerDisconnected"
+)
+
+var p2pDiscoveryTypeSlice = []P2PDiscoveryType{P2PDiscoveryFull, P2PDiscoverySocial, P2PDiscoveryProgressive}
+var p2pWPSMethodSlice = []P2PWPSMethod{P2PWPSPBC, P2PWPSDisplay, P2PWPSKeypad, P2PWPSPin}
+var p2pConfigMethodSlice = []P2PConfigMethod{P2PConfigDisplay, P2PConfigKeypad, P2PConfigPBC, P2PConfigPushbutton}
+
+var p2pDeviceEventSlice = []P2PEventType{P2PDeviceFound, P2PDeviceLost, P2PFindStopped,
+	P2PProvisionDiscoveryRequestDisplayPin, P2PProvisionDiscoveryResponseDisplayPin,
+	P2PProvisionDiscoveryRequestEnterPin, P2PProvisionDiscoveryResponseEnterPin,
+	P2PProvisionDiscoveryPBCRequest, P2PProvisionDiscoveryPBCResponse, P2PProvisionDiscoveryFailure,
+	P2PGONegotiationRequest, P2PGONegotiationSuccess, P2PGONegotiationFailure,
+	P2PGroupStarted, P2PGroupFinished, P2PGroupFormationFailure,
+	P2PInvitationResult, P2PInvitationReceived,
+	P2PServiceDiscoveryRequest, P2PServiceDiscoveryResponse,
+	P2PPersistentGroupAdded, P2PPersistentGroupRemoved, P2PWpsFailed}
+
+// P2PDevice wraps the P2PDevice interface of a supplicant interface. On drivers with a dedicated
+// P2P device the path is the one of the p2p-dev-<ifname> interface.
+type P2PDevice struct {
+	wpaDbus *WpaSupplicantDbus
+	path    dbus.ObjectPath
+}
+
+// P2PFindParams are the arguments of Find. A zero Timeout searches until StopFind is called,
+// a zero Frequency uses the channels selected by DiscoveryType.
+type P2PFindParams struct {
+	Timeout              int32
+	DiscoveryType        P2PDiscoveryType
+	RequestedDeviceTypes [][]byte
+	Frequency            int32
+}
+
+// P2PConnectParams are the arguments of Connect. A zero GOIntent uses the GOIntent of the
+// device configuration, 1 to 15 override it. For the pin and display methods an empty Pin
+// makes the supplicant generate one, which Connect returns.
+type P2PConnectParams struct {
+	Peer          dbus.ObjectPath
+	WPSMethod     P2PWPSMethod
+	Pin           string
+	Persistent    bool
+	Join          bool
+	AuthorizeOnly bool
+	Frequency     int32
+	GOIntent      int32
+}
+
+// P2PGroupAddParams are the arguments of GroupAdd. PersistentGroup restarts a stored persistent
+// group, otherwise a new (optionally persistent) autonomous group is created.
+type P2PGroupAddParams struct {
+	Persistent      bool
+	Frequency       int32
+	PersistentGroup dbus.ObjectPath
+}
+
+// P2PServiceRequest is a service discovery query. Either a UPnP query (ServiceType "upnp",
+// Version and Service) or raw TLVs are sent. An empty Peer queries every peer.
+type P2PServiceRequest struct {
+	Peer        dbus.ObjectPath
+	ServiceType string
+	Version     int32
+	Service     string
+	TLV         []byte
+}
+
+// P2PServiceResponse answers a ServiceDiscoveryRequest event when service discovery is handled externally
+type P2PServiceResponse struct {
+	Peer        dbus.ObjectPath
+	Frequency   int32
+	DialogToken int32
+	TLVs        []byte
+}
+
+// P2PDeviceConfig is the P2PDeviceConfig property. SetConfig writes every field back, read the
+// current configuration with GetConfig before changing it.
+type P2PDeviceConfig struct {
+	DeviceName           string
+	PrimaryDeviceType    []byte
+	SecondaryDeviceTypes [][]byte
+	VendorExtension      [][]byte
+	GOIntent             uint32
+	PersistentReconnect  bool
+	ListenRegClass       uint32
+	ListenChannel        uint32
+	OperRegClass         uint32
+	OperChannel          uint32
+	SsidPostfix          string
+	IntraBss             bool
+	GroupIdle            uint32
+	DisassocLowAck       uint32
+	NoGroupIface         bool
+	P2PSearchDelay       uint32
+}
+
+// P2PPeer holds the properties of a Peer object
+type P2PPeer struct {
+	Path                 dbus.ObjectPath
+	DeviceName           string
+	Manufacturer         string
+	ModelName            string
+	ModelNumber          string
+	SerialNumber         string
+	DeviceAddress        string
+	PrimaryDeviceType    []byte
+	SecondaryDeviceTypes [][]byte
+	ConfigMethod         uint16
+	Level                int32
+	DeviceCapability     byte
+	GroupCapability      byte
+	Groups               []dbus.ObjectPath
+}
+
+// P2PGroup holds the properties of a Group object
+type P2PGroup struct {
+	Path       dbus.ObjectPath
+	Role       string
+	SSID       []byte
+	BSSID      string
+	Frequency  uint16
+	Passphrase string
+	PSK        []byte
+	Members    []dbus.ObjectPath
+}
+
+// P2PEvent is a typed P2PDevice or Group signal. Peer, Group, Interface, Role, Pin, Status and
+// Reason are filled in when the signal carries them, Properties holds the raw dictionary of
+// dictionary based signals.
+type P2PEvent struct {
+	Type          P2PEventType
+	Peer          dbus.ObjectPath
+	Group         dbus.ObjectPath
+	Interface     dbus.ObjectPath
+	Role          string
+	Pin           string
+	Status        int64
+	Reason        string
+	DevPasswordID int64
+	GOIntent      int64
+	Properties    map[string]dbus.Variant
+}
+
+// P2PDevice returns the P2P device API of the given supplicant interface
+func (wpaDbus *WpaSupplicantDbus) P2PDevice(wpaInterfaceName dbus.ObjectPath) *P2PDevice {
+	return &P2PDevice{wpaDbus: wpaDbus, path: wpaInterfaceName}
+}
+
+// Find starts P2P device discovery, found peers are reported with DeviceFound events
+func (p *P2PDevice) Find(params P2PFindParams) error {
+	args := map[string]interface{}{}
+	if params.Timeout > 0 {
+		args["Timeout"] = params.Timeout
+	}
+	if params.DiscoveryType != "" {
+		if !contains(p2pDiscoveryTypeSlice, params.DiscoveryType) {
+			return errors.New("invalid value for p2p discovery type")
+		}
+		args["DiscoveryType"] = string(params.DiscoveryType)
+	}
+	if len(params.RequestedDeviceTypes) > 0 {
+		for _, deviceType := range params.RequestedDeviceTypes {
+			if len(deviceType) != 8 {
+				return errors.New("invalid value for requested device type")
+			}
+		}
+		args["RequestedDeviceTypes"] = params.RequestedDeviceTypes
+	}
+	if params.Frequency > 0 {
+		args["freq"] = params.Frequency
+	}
+	return p.call("Find", args)
+}
+
+func (p *P2PDevice) StopFind() error {
+	return p.call("StopFind")
+}
+
+// Listen makes the device discoverable for timeout seconds
+func (p *P2PDevice) Listen(timeout int32) error {
+	return p.call("Listen", timeout)
+}
+
+// ExtendedListen makes the device discoverable for period milliseconds every interval milliseconds.
+// Zero values disable extended listen.
+func (p *P2PDevice) ExtendedListen(period, interval int32) error {
+	if period > interval {
+		return errors.New("extended listen period must not exceed the interval")
+	}
+	args := map[string]interface{}{}
+	if interval > 0 {
+		args["period"] = period
+		args["interval"] = interval
+	}
+	return p.call("ExtendedListen", args)
+}
+
+// ProvisionDiscoveryRequest asks the peer which WPS config method to use
+func (p *P2PDevice) ProvisionDiscoveryRequest(peer dbus.ObjectPath, method P2PConfigMethod) error {
+	if !contains(p2pConfigMethodSlice, method) {
+		return errors.New("invalid value for p2p config method")
+	}
+	return p.call("ProvisionDiscoveryRequest", peer, string(method))
+}
+
+// Connect starts group owner negotiation with a peer, or joins its group when Join is set.
+// It returns the generated PIN for pin and display connections without a PIN.
+func (p *P2PDevice) Connect(params P2PConnectParams) (string, error) {
+	args, err := params.toDBusArgs()
+	if err != nil {
+		return "", err
+	}
+	obj := p.wpaDbus.dbusCon.Object(dbusWPAname, p.path)
+	var pin string
+	err = obj.Call(dbusWPAP2PDevicename+".Connect", 0, args).Store(&pin)
+	if err != nil {
+		p.wpaDbus.logger.Error(err)
+		return "", err
+	}
+	return pin, nil
+}
+
+// GroupAdd creates an autonomous group with this device as group owner
+func (p *P2PDevice) GroupAdd(params P2PGroupAddParams) error {
+	args := map[string]interface{}{
+		"persistent": params.Persistent,
+	}
+	if params.Frequency > 0 {
+		args["frequency"] = params.Frequency
+	}
+	if params.PersistentGroup != "" {
+		args["persistent_group_object"] = params.PersistentGroup
+	}
+	return p.call("GroupAdd", args)
+}
+
+// Invite invites a peer into the current group or, when persistentGroup is set, into that persistent group
+func (p *P2PDevice) Invite(peer, persistentGroup dbus.ObjectPath) error {
+	if peer == "" {
+		return errors.New("invite requires a peer")
+	}
+	args := map[string]interface{}{
+		"peer": peer,
+	}
+	if persistentGroup != "" {
+		args["persistent_group_object"] = persistentGroup
+	}
+	return p.call("Invite", args)
+}
+
+// Disconnect terminates the group this device is part of
+func (p *P2PDevice) Disconnect() error {
+	return p.call("Disconnect")
+}
+
+// Cancel stops an ongoing group formation
+func (p *P2PDevice) Cancel() error {
+	return p.call("Cancel")
+}
+
+func (p *P2PDevice) RejectPeer(peer dbus.ObjectPath) error {
+	return p.call("RejectPeer", peer)
+}
+
+// Flush clears the peer table and the P2P state of the device
+func (p *P2PDevice) Flush() error {
+	return p.call("Flush")
+}
+
+// ServiceDiscoveryRequest queries the services of a peer and returns the request reference
+// used by ServiceDiscoveryCancelRequest
+func (p *P2PDevice) ServiceDiscoveryRequest(request P2PServiceRequest) (uint64, error) {
+	args := map[string]interface{}{}
+	if request.Peer != "" {
+		args["peer_object"] = request.Peer
+	}
+	switch {
+	case request.ServiceType == "upnp" && request.Service != "" && len(request.TLV) == 0:
+		args["service_type"] = request.ServiceType
+		args["version"] = request.Version
+		args["service"] = request.Service
+	case request.ServiceType == "" && len(request.TLV) > 0:
+		args["tlv"] = request.TLV
+	default:
+		return 0, errors.New("service discovery request requires either a upnp service or tlvs")
+	}
+	obj := p.wpaDbus.dbusCon.Object(dbusWPAname, p.path)
+	var ref uint64
+	err := obj.Call(dbusWPAP2PDevicename+".ServiceDiscoveryRequest", 0, args).Store(&ref)
+	if err != nil {
+		p.wpaDbus.logger.Error(err)
+		return 0, err
+	}
+	return ref, nil
+}
+
+func (p *P2PDevice) ServiceDiscoveryResponse(response P2PServiceResponse) error {
+	if response.Peer == "" || len(response.TLVs) == 0 {
+		return errors.New("service discovery response requires a peer and tlvs")
+	}
+	args := map[string]interface{}{
+		"peer_object":  response.Peer,
+		"frequency":    response.Frequency,
+		"dialog_token": response.DialogToken,
+		"tlvs":         response.TLVs,
+	}
+	return p.call("ServiceDiscoveryResponse", args)
+}
+
+func (p *P2PDevice) ServiceDiscoveryCancelRequest(ref uint64) error {
+	return p.call("ServiceDiscoveryCancelRequest", ref)
+}
+
+// ServiceDiscoveryExternal hands service discovery responses to the application when enabled
+func (p *P2PDevice) ServiceDiscoveryExternal(enabled bool) error {
+	arg := int32(0)
+	if enabled {
+		arg = 1
+	}
+	return p.call("ServiceDiscoveryExternal", arg)
+}
+
+func (p *P2PDevice) FlushService() error {
+	return p.call("FlushService")
+}
+
+func (p *P2PDevice) RemovePersistentGroup(persistentGroup dbus.ObjectPath) error {
+	return p.call("RemovePersistentGroup", persistentGroup)
+}
+
+func (p *P2PDevice) RemoveAllPersistentGroups() error {
+	return p.call("RemoveAllPersistentGroups")
+}
+
+// GetConfig reads the P2PDeviceConfig property
+func (p *P2PDevice) GetConfig() (*P2PDeviceConfig, error) {
+	var properties map[string]dbus.Variant
+	err := readObjectProperty(p.wpaDbus, p.path, dbusWPAP2PDevicename, "P2PDeviceConfig", &properties)
+	if err != nil {
+		return nil, err
+	}
+	return newP2PDeviceConfig(properties), nil
+}
+
+// SetConfig writes the P2PDeviceConfig property
+func (p *P2PDevice) SetConfig(config P2PDeviceConfig) error {
+	args, err := config.toDBusArgs()
+	if err != nil {
+		return err
+	}
+	return writeObjectProperty(p.wpaDbus, p.path, dbusWPAP2PDevicename, "P2PDeviceConfig", args)
+}
+
+// GetPeers returns the object paths of the discovered peers
+func (p *P2PDevice) GetPeers() ([]dbus.ObjectPath, error) {
+	var peers []dbus.ObjectPath
+	err := readObjectProperty(p.wpaDbus, p.path, dbusWPAP2PDevicename, "Peers", &peers)
+	return peers, err
+}
+
+// GetRole returns GO, client or device
+func (p *P2PDevice) GetRole() (string, error) {
+	var role string
+	err := readObjectProperty(p.wpaDbus, p.path, dbusWPAP2PDevicename, "Role", &role)
+	return role, err
+}
+
+// GetGroup returns the object path of the current group
+func (p *P2PDevice) GetGroup() (dbus.ObjectPath, error) {
+	var group dbus.ObjectPath
+	err := readObjectProperty(p.wpaDbus, p.path, dbusWPAP2PDevicename, "Group", &group)
+	return group, err
+}
+
+func (p *P2PDevice) GetPersistentGroups() ([]dbus.ObjectPath, error) {
+	var groups []dbus.ObjectPath
+	err := readObjectProperty(p.wpaDbus, p.path, dbusWPAP2PDevicename, "PersistentGroups", &groups)
+	return groups, err
+}
+
+// GetPeer reads the properties of a Peer object
+func (p *P2PDevice) GetPeer(peerPath dbus.ObjectPath) (*P2PPeer, error) {
+	properties, err := readAllObjectProperties(p.wpaDbus, peerPath, dbusWPAPeername)
+	if err != nil {
+		return nil, err
+	}
+	peer := &P2PPeer{
+		Path:              peerPath,
+		DeviceName:        variantString(properties, "DeviceName"),
+		Manufacturer:      variantString(properties, "Manufacturer"),
+		ModelName:         variantString(properties, "ModelName"),
+		ModelNumber:       variantString(properties, "ModelNumber"),
+		SerialNumber:      variantString(properties, "SerialNumber"),
+		PrimaryDeviceType: variantBytes(properties, "PrimaryDeviceType"),
+		ConfigMethod:      uint16(variantInt(properties, "config_method")),
+		Level:             int32(variantInt(properties, "level")),
+		DeviceCapability:  byte(variantInt(properties, "devicecapability")),
+		GroupCapability:   byte(variantInt(properties, "groupcapability")),
+	}
+	if addr := variantBytes(properties, "DeviceAddress"); len(addr) > 0 {
+		peer.DeviceAddress = net.HardwareAddr(addr).String()
+	}
+	if value, ok := properties["SecondaryDeviceTypes"]; ok {
+		peer.SecondaryDeviceTypes, _ = value.Value().([][]byte)
+	}
+	if value, ok := properties["Groups"]; ok {
+		peer.Groups, _ = value.Value().([]dbus.ObjectPath)
+	}
+	return peer, nil
+}
+
+// GetGroupInfo reads the properties of a Group object
+func (p *P2PDevice) GetGroupInfo(groupPath dbus.ObjectPath) (*P2PGroup, error) {
+	properties, err := readAllObjectProperties(p.wpaDbus, groupPath, dbusWPAGroupname)
+	if err != nil {
+		return nil, err
+	}
+	group := &P2PGroup{
+		Path:       groupPath,
+		Role:       variantString(properties, "Role"),
+		SSID:       variantBytes(properties, "SSID"),
+		Frequency:  uint16(variantInt(properties, "Frequency")),
+		Passphrase: variantString(properties, "Passphrase"),
+		PSK:        variantBytes(properties, "PSK"),
+	}
+	if bssid := variantBytes(properties, "BSSID"); len(bssid) > 0 {
+		group.BSSID = net.HardwareAddr(bssid).String()
+	}
+	if value, ok := properties["Members"]; ok {
+		group.Members, _ = value.Value().([]dbus.ObjectPath)
+	}
+	return group, nil
+}
+
+// GetPersistentGroupProperties returns the network properties stored in a PersistentGroup object
+func (p *P2PDevice) GetPersistentGroupProperties(persistentGroup dbus.ObjectPath) (map[string]dbus.Variant, error) {
+	var properties map[string]dbus.Variant
+	err := readObjectProperty(p.wpaDbus, persistentGroup, dbusWPAPersistentGroupname, "Properties", &properties)
+	return properties, err
+}
+
+// SubscribeEvents forwards the P2PDevice signals of the device to eventChan.
+// The returned function cancels the subscription.
+func (p *P2PDevice) SubscribeEvents(eventChan chan P2PEvent) (func(), error) {
+	return p.subscribe(p.path, dbusWPAP2PDevicename, p2pDeviceEventSlice, eventChan)
+}
+
+// SubscribeGroupEvents forwards the PeerJoined and PeerDisconnected signals of a group to eventChan.
+// The returned function cancels the subscription.
+func (p *P2PDevice) SubscribeGroupEvents(groupPath dbus.ObjectPath, eventChan chan P2PEvent) (func(), error) {
+	return p.subscribe(groupPath, dbusWPAGroupname, []P2PEventType{P2PPeerJoined, P2PPeerDisconnected}, eventChan)
+}
+
+func (p *P2PDevice) subscribe(path dbus.ObjectPath, iface string, eventTypes []P2PEventType, eventChan chan P2PEvent) (func(), error) {
+	unsubscribers := make([]func(), 0, len(eventTypes))
+	unsubscribeAll := func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+	for _, eventType := range eventTypes {
+		eventType := eventType
+		unsubscribe, err := subscribeSignal(p.wpaDbus, path, iface, string(eventType), func(signal *dbus.Signal, done <-chan struct{}) {
+			select {
+			case eventChan <- decodeP2PEvent(eventType, signal):
+			case <-done:
+			}
+		})
+		if err != nil {
+			unsubscribeAll()
+			return nil, err
+		}
+		unsubscribers = append(unsubscribers, unsubscribe)
+	}
+	return unsubscribeAll, nil
+}
+
+func (p *P2PDevice) call(method string, args ...interface{}) error {
+	return callObjectMethod(p.wpaDbus, p.path, dbusWPAP2PDevicename, method, args...)
+}
+
+func decodeP2PEvent(eventType P2PEventType, signal *dbus.Signal) P2PEvent {
+	event := P2PEvent{Type: eventType}
+	switch eventType {
+	case P2PDeviceFound, P2PDeviceLost, P2PProvisionDiscoveryRequestEnterPin, P2PProvisionDiscoveryResponseEnterPin,
+		P2PProvisionDiscoveryPBCRequest, P2PProvisionDiscoveryPBCResponse, P2PPeerJoined, P2PPeerDisconnected:
+		event.Peer = signalObjectPath(signal, 0)
+	case P2PProvisionDiscoveryRequestDisplayPin, P2PProvisionDiscoveryResponseDisplayPin:
+		event.Peer = signalObjectPath(signal, 0)
+		if len(signal.Body) > 1 {
+			event.Pin, _ = signal.Body[1].(string)
+		}
+	case P2PProvisionDiscoveryFailure:
+		event.Peer = signalObjectPath(signal, 0)
+		if len(signal.Body) > 1 {
+			status, _ := signal.Body[1].(int32)
+			event.Status = int64(status)
+		}
+	case P2PGONegotiationRequest:
+		event.Peer = signalObjectPath(signal, 0)
+		if len(signal.Body) > 2 {
+			devPasswordID, _ := signal.Body[1].(uint16)
+			goIntent, _ := signal.Body[2].(byte)
+			event.DevPasswordID = int64(devPasswordID)
+			event.GOIntent = int64(goIntent)
+		}
+	case P2PGroupFormationFailure:
+		if len(signal.Body) > 0 {
+			event.Reason, _ = signal.Body[0].(string)
+		}
+	case P2PPersistentGroupAdded:
+		event.Group = signalObjectPath(signal, 0)
+		event.Properties = signalVariants(signal, 1)
+	case P2PPersistentGroupRemoved:
+		event.Group = signalObjectPath(signal, 0)
+	case P2PWpsFailed:
+		if len(signal.Body) > 0 {
+			event.Reason, _ = signal.Body[0].(string)
+		}
+		event.Properties = signalVariants(signal, 1)
+		event.Status = variantInt(event.Properties, "config_error")
+	case P2PGONegotiationSuccess, P2PGONegotiationFailure, P2PGroupStarted, P2PGroupFinished,
+		P2PInvitationResult, P2PInvitationReceived, P2PServiceDiscoveryRequest, P2PServiceDiscoveryResponse:
+		event.Properties = signalVariants(signal, 0)
+		event.Peer = variantObjectPath(event.Properties, "peer_object")
+		event.Group = variantObjectPath(event.Properties, "group_object")
+		event.Interface = variantObjectPath(event.Properties, "interface_object")
+		event.Role = variantString(event.Properties, "role")
+		if event.Role == "" {
+			event.Role = variantString(event.Properties, "role_go")
+		}
+		event.Status = variantInt(event.Properties, "status")
+	}
+	return event
+}
+
+func (c *P2PConnectParams) toDBusArgs() (map[string]interface{}, error) {
+	if c.Peer == "" {
+		return nil, errors.New("connect requires a peer")
+	}
+	if !contains(p2pWPSMethodSlice, c.WPSMethod) {
+		return nil, errors.New("invalid value for p2p wps method")
+	}
+	if c.WPSMethod == P2PWPSPBC && c.Pin != "" {
+		return nil, errors.New("p2p pbc does not take a pin")
+	}
+	if c.WPSMethod == P2PWPSKeypad && c.Pin == "" {
+		return nil, errors.New("p2p keypad requires the pin displayed by the peer")
+	}
+	if c.GOIntent < 0 || c.GOIntent > 15 {
+		return nil, errors.New("invalid value for go intent")
+	}
+	args := map[string]interface{}{
+		"peer":           c.Peer,
+		"wps_method":     string(c.WPSMethod),
+		"persistent":     c.Persistent,
+		"join":           c.Join,
+		"authorize_only": c.AuthorizeOnly,
+	}
+	if c.Pin != "" {
+		args["pin"] = c.Pin
+	}
+	if c.Frequency > 0 {
+		args["frequency"] = c.Frequency
+	}
+	if c.GOIntent > 0 {
+		args["go_intent"] = c.GOIntent
+	}
+	return args, nil
+}
+
+func newP2PDeviceConfig(properties map[string]dbus.Variant) *P2PDeviceConfig {
+	config := &P2PDeviceConfig{
+		DeviceName:          variantString(properties, "DeviceName"),
+		PrimaryDeviceType:   variantBytes(properties, "PrimaryDeviceType"),
+		GOIntent:            uint32(variantInt(properties, "GOIntent")),
+		PersistentReconnect: variantBool(properties, "PersistentReconnect"),
+		ListenRegClass:      uint32(variantInt(properties, "ListenRegClass")),
+		ListenChannel:       uint32(variantInt(properties, "ListenChannel")),
+		OperRegClass:        uint32(variantInt(properties, "OperRegClass")),
+		OperChannel:         uint32(variantInt(properties, "OperChannel")),
+		SsidPostfix:         variantString(properties, "SsidPostfix"),
+		IntraBss:            variantBool(properties, "IntraBss"),
+		GroupIdle:           uint32(variantInt(properties, "GroupIdle")),
+		DisassocLowAck:      uint32(variantInt(properties, "disassoc_low_ack")),
+		NoGroupIface:        variantBool(properties, "NoGroupIface"),
+		P2PSearchDelay:      uint32(variantInt(properties, "p2p_search_delay")),
+	}
+	if value, ok := properties["SecondaryDeviceTypes"]; ok {
+		config.SecondaryDeviceTypes, _ = value.Value().([][]byte)
+	}
+	if value, ok := properties["VendorExtension"]; ok {
+		config.VendorExtension, _ = value.Value().([][]byte)
+	}
+	return config
+}
+
+func (c *P2PDeviceConfig) toDBusArgs() (map[string]interface{}, error) {
+	if c.GOIntent > 15 {
+		return nil, errors.New("invalid value for go intent")
+	}
+	if len(c.PrimaryDeviceType) != 0 && len(c.PrimaryDeviceType) != 8 {
+		return nil, errors.New("invalid value for primary device type")
+	}
+	for _, deviceType := range c.SecondaryDeviceTypes {
+		if len(deviceType) != 8 {
+			return nil, errors.New("invalid value for secondary device type")
+		}
+	}
+	args := map[string]interface{}{
+		"DeviceName":          c.DeviceName,
+		"GOIntent":            c.GOIntent,
+		"PersistentReconnect": c.PersistentReconnect,
+		"ListenRegClass":      c.ListenRegClass,
+		"ListenChannel":       c.ListenChannel,
+		"OperRegClass":        c.OperRegClass,
+		"OperChannel":         c.OperChannel,
+		"SsidPostfix":         c.SsidPostfix,
+		"IntraBss":            c.IntraBss,
+		"GroupIdle":           c.GroupIdle,
+		"disassoc_low_ack":    c.DisassocLowAck,
+		"NoGroupIface":        c.NoGroupIface,
+		"p2p_search_delay":    c.P2PSearchDelay,
+	}
+	if len(c.PrimaryDeviceType) > 0 {
+		args["PrimaryDeviceType"] = c.PrimaryDeviceType
+	}
+	if len(c.SecondaryDeviceTypes) > 0 {
+		args["SecondaryDeviceTypes"] = c.SecondaryDeviceTypes
+	}
+	if len(c.VendorExtension) > 0 {
+		args["VendorExtension"] = c.VendorExtension
+	}
+	return args, nil
+}
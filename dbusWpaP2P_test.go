@@ -0,0 +1,73 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+	"testing"
+)
+
+func TestP2PConnectParamsValidation(t *testing.T) {
+	peer := dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/0/Peers/020000000001")
+	invalid := map[string]P2PConnectParams{
+		"no peer":            {WPSMethod: P2PWPSPBC},
+		"unknown method":     {Peer: peer, WPSMethod: "nfc"},
+		"pbc with pin":       {Peer: peer, WPSMethod: P2PWPSPBC, Pin: "12345670"},
+		"keypad without pin": {Peer: peer, WPSMethod: P2PWPSKeypad},
+		"go intent too high": {Peer: peer, WPSMethod: P2PWPSPBC, GOIntent: 16},
+	}
+	for name, params := range invalid {
+		if _, err := params.toDBusArgs(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	args, err := (&P2PConnectParams{Peer: peer, WPSMethod: P2PWPSDisplay, Persistent: true, GOIntent: 15}).toDBusArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := args["pin"]; ok {
+		t.Errorf("pin should be left out so the supplicant generates one")
+	}
+	if args["go_intent"] != int32(15) || args["persistent"] != true {
+		t.Errorf("unexpected connect args %v", args)
+	}
+}
+
+func TestDecodeP2PEvent(t *testing.T) {
+	peer := dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/0/Peers/020000000001")
+	group := dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/1/Groups/0")
+	iface := dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/1")
+
+	event := decodeP2PEvent(P2PProvisionDiscoveryRequestDisplayPin, &dbus.Signal{Body: []interface{}{peer, "12345670"}})
+	if event.Peer != peer || event.Pin != "12345670" {
+		t.Errorf("unexpected provision discovery event %+v", event)
+	}
+
+	event = decodeP2PEvent(P2PGroupStarted, &dbus.Signal{Body: []interface{}{map[string]dbus.Variant{
+		"interface_object": dbus.MakeVariant(iface),
+		"group_object":     dbus.MakeVariant(group),
+		"role":             dbus.MakeVariant("GO"),
+	}}})
+	if event.Interface != iface || event.Group != group || event.Role != "GO" {
+		t.Errorf("unexpected group started event %+v", event)
+	}
+
+	event = decodeP2PEvent(P2PGONegotiationRequest, &dbus.Signal{Body: []interface{}{peer, uint16(4), byte(7)}})
+	if event.Peer != peer || event.DevPasswordID != 4 || event.GOIntent != 7 {
+		t.Errorf("unexpected go negotiation request event %+v", event)
+	}
+}
+
+func TestP2PDeviceConfigValidation(t *testing.T) {
+	config := P2PDeviceConfig{DeviceName: "display", PrimaryDeviceType: []byte{0, 7, 0, 0x50, 0xf2, 4, 0, 1}, GOIntent: 7}
+	args, err := config.toDBusArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args["DeviceName"] != "display" || args["GOIntent"] != uint32(7) {
+		t.Errorf("unexpected config args %v", args)
+	}
+	config.PrimaryDeviceType = []byte{0, 7}
+	if _, err = config.toDBusArgs(); err == nil {
+		t.Errorf("expected short primary device type to be rejected")
+	}
+}
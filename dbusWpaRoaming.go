@@ -0,0 +1,108 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"net"
+	"time"
+)
+
+// noBSS is the CurrentBSS value of a disconnected interface
+var noBSS = dbus.ObjectPath("/")
+
+// RoamEvent is emitted when the interface moves from one access point to another without
+// disconnecting. Duration, Complete, SessionLength and BSSTMStatus are the RoamTime,
+// RoamComplete, SessionLength and BSSTMStatus properties read right after the roam.
+type RoamEvent struct {
+	OldBSSID      string
+	NewBSSID      string
+	Duration      time.Duration
+	Complete      bool
+	SessionLength time.Duration
+	BSSTMStatus   uint32
+}
+
+// Roam makes the interface roam to the access point with the given BSSID within the current ESS
+func (wpaDbus *WpaSupplicantDbus) Roam(wpaInterfaceName dbus.ObjectPath, bssid string) error {
+	if _, err := net.ParseMAC(bssid); err != nil {
+		return errors.New("invalid value for bssid")
+	}
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "Roam", bssid)
+}
+
+// GetRoamTime returns how long the last roam took
+func (wpaDbus *WpaSupplicantDbus) GetRoamTime(wpaInterfaceName dbus.ObjectPath) (time.Duration, error) {
+	var roamTime uint32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "RoamTime", &roamTime)
+	return time.Duration(roamTime) * time.Millisecond, err
+}
+
+// GetRoamComplete reports whether the last roam attempt succeeded
+func (wpaDbus *WpaSupplicantDbus) GetRoamComplete(wpaInterfaceName dbus.ObjectPath) (bool, error) {
+	var roamComplete bool
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "RoamComplete", &roamComplete)
+	return roamComplete, err
+}
+
+// GetSessionLength returns how long the interface stayed with the previous access point
+func (wpaDbus *WpaSupplicantDbus) GetSessionLength(wpaInterfaceName dbus.ObjectPath) (time.Duration, error) {
+	var sessionLength uint32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "SessionLength", &sessionLength)
+	return time.Duration(sessionLength) * time.Millisecond, err
+}
+
+// GetBSSTMStatus returns the status code of the last BSS transition management response
+func (wpaDbus *WpaSupplicantDbus) GetBSSTMStatus(wpaInterfaceName dbus.ObjectPath) (uint32, error) {
+	var status uint32
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "BSSTMStatus", &status)
+	return status, err
+}
+
+// GetCurrentBSS returns the object path of the BSS the interface is connected to, "/" when disconnected
+func (wpaDbus *WpaSupplicantDbus) GetCurrentBSS(wpaInterfaceName dbus.ObjectPath) (dbus.ObjectPath, error) {
+	var bss dbus.ObjectPath
+	err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "CurrentBSS", &bss)
+	return bss, err
+}
+
+// SubscribeRoamEvents forwards a RoamEvent to eventChan every time the CurrentBSS of the interface
+// changes from one access point to another. The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeRoamEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan RoamEvent) (func(), error) {
+	currentBSSID := ""
+	if bss, err := wpaDbus.GetCurrentBSS(wpaInterfaceName); err == nil && bss != noBSS {
+		currentBSSID, _ = wpaDbus.GetBSSID(bss)
+	}
+	return subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, "PropertiesChanged", func(signal *dbus.Signal, done <-chan struct{}) {
+		properties := signalVariants(signal, 0)
+		if _, ok := properties["CurrentBSS"]; !ok {
+			return
+		}
+		bss := variantObjectPath(properties, "CurrentBSS")
+		if bss == "" || bss == noBSS {
+			currentBSSID = ""
+			return
+		}
+		newBSSID, err := wpaDbus.GetBSSID(bss)
+		if err != nil {
+			return
+		}
+		oldBSSID := currentBSSID
+		currentBSSID = newBSSID
+		if oldBSSID == "" || oldBSSID == newBSSID {
+			return
+		}
+		select {
+		case eventChan <- wpaDbus.readRoamEvent(wpaInterfaceName, oldBSSID, newBSSID):
+		case <-done:
+		}
+	})
+}
+
+func (wpaDbus *WpaSupplicantDbus) readRoamEvent(wpaInterfaceName dbus.ObjectPath, oldBSSID, newBSSID string) RoamEvent {
+	event := RoamEvent{OldBSSID: oldBSSID, NewBSSID: newBSSID}
+	event.Duration, _ = wpaDbus.GetRoamTime(wpaInterfaceName)
+	event.Complete, _ = wpaDbus.GetRoamComplete(wpaInterfaceName)
+	event.SessionLength, _ = wpaDbus.GetSessionLength(wpaInterfaceName)
+	event.BSSTMStatus, _ = wpaDbus.GetBSSTMStatus(wpaInterfaceName)
+	return event
+}
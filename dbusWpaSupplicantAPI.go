@@ -1,11 +1,13 @@
 package wpaSuppDBusLib
 
 import (
+	"errors"
 	"fmt"
 	"github.com/godbus/dbus/v5"
 	"os"
 	"path"
 	"reflect"
+	"sync"
 )
 
 type Driver string
@@ -26,6 +28,24 @@ const (
 	DriverNone        Driver = "None"
 )
 
+// EnsurePolicy decides what EnsureInterface does with an existing interface that differs from the
+// desired one. An interface that matches is always adopted as is.
+type EnsurePolicy uint8
+
+const (
+	// EnsureAdopt takes over the existing interface without changing it. An adopted interface that
+	// differs from the desired one is tracked with its live driver, bridge and config file but is
+	// not added to CreatedWPAInterfaces, since its running config is not known.
+	EnsureAdopt EnsurePolicy = 0
+	// EnsureReconcile updates the networks of the running interface with ReconcileNetworks. It is
+	// recreated when the driver, bridge or config file differ since those can not be changed at runtime.
+	EnsureReconcile EnsurePolicy = 1
+	// EnsureRecreate removes the interface and creates it again
+	EnsureRecreate EnsurePolicy = 2
+)
+
+var ensurePolicySlice = []EnsurePolicy{EnsureAdopt, EnsureReconcile, EnsureRecreate}
+
 type WpaSupplicantDbus struct {
 	dbusCon              *dbus.Conn
 	logger               Logger
@@ -36,6 +56,20 @@ type WpaSupplicantDbus struct {
 	DebugTimeStamp       bool
 	DebugLevel           string
 	CreatedWPAInterfaces map[string]WPAInterface
+	interfaceConfigs     map[string]interfaceConfig
+	signalSubscriptions  []*signalSubscription
+	signalOnce           sync.Once
+	mutex                sync.Mutex
+}
+
+// interfaceConfig keeps what was used to create an interface so it can be
+// regenerated later without the caller having to supply it again.
+type interfaceConfig struct {
+	ifname           string
+	bridgeName       string
+	driver           Driver
+	configFile       string
+	unsubscribeState func()
 }
 
 func NewWpaSupplicantAPIWithLogger(logger Logger) (*WpaSupplicantDbus, error) {
@@ -43,12 +77,13 @@ func NewWpaSupplicantAPIWithLogger(logger Logger) (*WpaSupplicantDbus, error) {
 	if err != nil {
 		return nil, err
 	}
-	supDaemon := WpaSupplicantDbus{dbusCon: con, logger: logger, CreatedWPAInterfaces: make(map[string]WPAInterface)}
+	supDaemon := WpaSupplicantDbus{dbusCon: con, logger: logger, CreatedWPAInterfaces: make(map[string]WPAInterface), interfaceConfigs: make(map[string]interfaceConfig)}
+	watchInterfaceRemovals(&supDaemon)
 	return &supDaemon, nil
 }
 
 func NewWpaSupplicantAPI() (*WpaSupplicantDbus, error) {
-	logger := newDefaultLogger()
+	logger := NewRedactingLogger(newDefaultLogger())
 	return NewWpaSupplicantAPIWithLogger(logger)
 }
 
@@ -56,27 +91,176 @@ func (wpaDbus *WpaSupplicantDbus) Close() error {
 	return wpaDbus.dbusCon.Close()
 }
 
+// CreateInterface writes the config of wpaInterface and lets the supplicant create the interface
+// from it. Configs using EAP methods, features, ciphers or modes the supplicant or the driver do not
+// support are rejected, an interface created for such a config is removed again.
 func (wpaDbus *WpaSupplicantDbus) CreateInterface(interfaceName, bridgeName string, driver Driver, wpaInterface WPAInterface, pathToSaveInterfaceConfig string, stateChangeChan chan string) (dbus.ObjectPath, error) {
-	confStr := wpaInterface.ToConfigString()
-	fileName := ""
-	if driver == DriverWired {
-		fileName = fmt.Sprintf("wpa_supplicant-wired-%s.conf", interfaceName)
-	} else {
-		fileName = fmt.Sprintf("wpa_supplicant-%s.conf", interfaceName)
+	supplicantCaps, err := wpaDbus.GetSupplicantCapabilities()
+	if err != nil {
+		return "", err
 	}
-	fullPath := path.Join(pathToSaveInterfaceConfig, fileName)
-	err := os.WriteFile(fullPath, []byte(confStr), 0600)
+	err = wpaInterface.ValidateAgainst(Capabilities{Supplicant: supplicantCaps})
 	if err != nil {
 		return "", err
 	}
-	ifPath, err := createInterface(wpaDbus, interfaceName, bridgeName, driver, fullPath, stateChangeChan)
+	fullPath := interfaceConfigPath(interfaceName, driver, pathToSaveInterfaceConfig)
+	err = writeInterfaceConfig(wpaInterface, fullPath)
+	if err != nil {
+		return "", err
+	}
+	ifPath, unsubscribeState, err := createInterface(wpaDbus, interfaceName, bridgeName, driver, fullPath, stateChangeChan)
+	if err != nil {
+		return "", err
+	}
+	interfaceCaps, err := wpaDbus.GetInterfaceCapabilities(ifPath)
+	if err == nil {
+		err = wpaInterface.ValidateAgainst(Capabilities{Interface: interfaceCaps})
+	}
 	if err != nil {
+		unsubscribeState()
+		if removeErr := removeInterface(wpaDbus, ifPath); removeErr != nil {
+			return "", MultiError{err, removeErr}
+		}
 		return "", err
 	}
+	wpaDbus.mutex.Lock()
+	defer wpaDbus.mutex.Unlock()
 	wpaDbus.CreatedWPAInterfaces[string(ifPath)] = wpaInterface
+	wpaDbus.interfaceConfigs[string(ifPath)] = interfaceConfig{
+		ifname:           interfaceName,
+		bridgeName:       bridgeName,
+		driver:           driver,
+		configFile:       fullPath,
+		unsubscribeState: unsubscribeState,
+	}
+	return ifPath, nil
+}
+
+// EnsureInterface is CreateInterface for an interface that may already exist, e.g. because the
+// supplicant kept running while the caller restarted. The desired config is validated against the
+// capabilities of the supplicant and the existing interface like in CreateInterface. An existing
+// interface is compared to the desired one by driver, bridge, config file and networks, and then adopted, reconciled or
+// recreated according to policy. Secrets like psk or password can not be read back from the
+// supplicant, a changed secret alone is therefore not detected. Ensuring an interface that is
+// already tracked replaces its state change subscription, so stateChangeChan receives every state
+// change once.
+func (wpaDbus *WpaSupplicantDbus) EnsureInterface(interfaceName, bridgeName string, driver Driver, wpaInterface WPAInterface, pathToSaveInterfaceConfig string, stateChangeChan chan string, policy EnsurePolicy) (dbus.ObjectPath, error) {
+	if !contains(ensurePolicySlice, policy) {
+		return "", errors.New("invalid value for ensure policy")
+	}
+	ifPath, err := getInterface(wpaDbus, interfaceName)
+	if isInterfaceUnknown(err) {
+		return wpaDbus.CreateInterface(interfaceName, bridgeName, driver, wpaInterface, pathToSaveInterfaceConfig, stateChangeChan)
+	}
+	if err != nil {
+		return "", err
+	}
+	supplicantCaps, err := wpaDbus.GetSupplicantCapabilities()
+	if err != nil {
+		return "", err
+	}
+	interfaceCaps, err := wpaDbus.GetInterfaceCapabilities(ifPath)
+	if err != nil {
+		return "", err
+	}
+	err = wpaInterface.ValidateAgainst(Capabilities{Supplicant: supplicantCaps, Interface: interfaceCaps})
+	if err != nil {
+		return "", err
+	}
+	fullPath := interfaceConfigPath(interfaceName, driver, pathToSaveInterfaceConfig)
+	info, err := wpaDbus.readInterfaceInfo(ifPath)
+	if err != nil {
+		return "", err
+	}
+	setupMatches := info.Driver == driver && info.BridgeIfname == bridgeName && info.ConfigFile == fullPath
+	networksMatch, err := wpaDbus.networksMatch(ifPath, wpaInterface.network)
+	if err != nil {
+		return "", err
+	}
+	ifConfig := interfaceConfig{
+		ifname:     interfaceName,
+		bridgeName: bridgeName,
+		driver:     driver,
+		configFile: fullPath,
+	}
+	adoptedAsIs := false
+	switch {
+	case setupMatches && networksMatch:
+	case policy == EnsureAdopt:
+		adoptedAsIs = true
+		ifConfig.bridgeName = info.BridgeIfname
+		ifConfig.driver = info.Driver
+		ifConfig.configFile = info.ConfigFile
+	case policy == EnsureReconcile && setupMatches:
+		err = writeInterfaceConfig(wpaInterface, fullPath)
+		if err != nil {
+			return "", err
+		}
+		_, err = wpaDbus.ReconcileNetworks(ifPath, wpaInterface, ReconcileOptions{UpdateSecrets: true})
+		if err != nil {
+			return "", err
+		}
+	default:
+		err = removeInterface(wpaDbus, ifPath)
+		if err != nil {
+			return "", err
+		}
+		return wpaDbus.CreateInterface(interfaceName, bridgeName, driver, wpaInterface, pathToSaveInterfaceConfig, stateChangeChan)
+	}
+	wpaDbus.mutex.Lock()
+	previous := wpaDbus.interfaceConfigs[string(ifPath)]
+	wpaDbus.mutex.Unlock()
+	if previous.unsubscribeState != nil {
+		previous.unsubscribeState()
+	}
+	ifConfig.unsubscribeState, err = watchStateChanges(wpaDbus, ifPath, stateChangeChan)
+	if err != nil {
+		forgetInterface(wpaDbus, string(ifPath))
+		return "", err
+	}
+	wpaDbus.mutex.Lock()
+	defer wpaDbus.mutex.Unlock()
+	if adoptedAsIs {
+		delete(wpaDbus.CreatedWPAInterfaces, string(ifPath))
+	} else {
+		wpaDbus.CreatedWPAInterfaces[string(ifPath)] = wpaInterface
+	}
+	wpaDbus.interfaceConfigs[string(ifPath)] = ifConfig
 	return ifPath, nil
 }
 
+// networksMatch compares the desired networks to the networks configured on the interface
+func (wpaDbus *WpaSupplicantDbus) networksMatch(wpaInterfaceName dbus.ObjectPath, networks []Network) (bool, error) {
+	paths, err := wpaDbus.GetNetworks(wpaInterfaceName)
+	if err != nil {
+		return false, err
+	}
+	live := make([]map[string]string, 0, len(paths))
+	for _, networkPath := range paths {
+		properties, err := wpaDbus.GetNetworkProperties(networkPath)
+		if err != nil {
+			return false, err
+		}
+		live = append(live, properties)
+	}
+	return networksMatch(networks, live), nil
+}
+
+func interfaceConfigPath(interfaceName string, driver Driver, pathToSaveInterfaceConfig string) string {
+	fileName := ""
+	if driver == DriverWired {
+		fileName = fmt.Sprintf("wpa_supplicant-wired-%s.conf", interfaceName)
+	} else {
+		fileName = fmt.Sprintf("wpa_supplicant-%s.conf", interfaceName)
+	}
+	return path.Join(pathToSaveInterfaceConfig, fileName)
+}
+
+func writeInterfaceConfig(wpaInterface WPAInterface, fullPath string) error {
+	confStr := wpaInterface.ToConfigString()
+	return os.WriteFile(fullPath, []byte(confStr), 0600)
+}
+
 func (wpaDbus *WpaSupplicantDbus) ExpectDisconnect(wpaInterfaceName string) error {
 	return expectDisconnect(wpaDbus, wpaInterfaceName)
 }
@@ -89,14 +273,50 @@ func (wpaDbus *WpaSupplicantDbus) RemoveInterface(wpaInterfaceName dbus.ObjectPa
 	return removeInterface(wpaDbus, wpaInterfaceName)
 }
 
+// EAPLogoff forces the interface to log off from IEEE 802.1X authentication (sends EAPOL-Logoff)
+func (wpaDbus *WpaSupplicantDbus) EAPLogoff(wpaInterfaceName dbus.ObjectPath) error {
+	return eapLogoff(wpaDbus, wpaInterfaceName)
+}
+
+// EAPLogon re-enables IEEE 802.1X authentication after an EAPLogoff, starting a fresh session
+func (wpaDbus *WpaSupplicantDbus) EAPLogon(wpaInterfaceName dbus.ObjectPath) error {
+	return eapLogon(wpaDbus, wpaInterfaceName)
+}
+
+// SupplicantRunning reports whether wpa_supplicant currently owns its name on the system bus
+func (wpaDbus *WpaSupplicantDbus) SupplicantRunning() (bool, error) {
+	var running bool
+	err := wpaDbus.dbusCon.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, dbusWPAname).Store(&running)
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return false, err
+	}
+	return running, nil
+}
+
+// TrackedInterfaces returns the network interface name of every interface created through
+// CreateInterface, keyed by its supplicant object path
+func (wpaDbus *WpaSupplicantDbus) TrackedInterfaces() map[dbus.ObjectPath]string {
+	wpaDbus.mutex.Lock()
+	defer wpaDbus.mutex.Unlock()
+	interfaces := make(map[dbus.ObjectPath]string, len(wpaDbus.interfaceConfigs))
+	for ifPath, ifConfig := range wpaDbus.interfaceConfigs {
+		interfaces[dbus.ObjectPath(ifPath)] = ifConfig.ifname
+	}
+	return interfaces
+}
+
+// ReadAllProperties refreshes the root properties. A failing property does not stop the others
+// from being read, the returned MultiError holds every failure.
 func (wpaDbus *WpaSupplicantDbus) ReadAllProperties() error {
-	readWFDIEs(wpaDbus)
-	readCapabilities(wpaDbus)
-	readDebugShowKeys(wpaDbus)
-	readDebugTimeStamp(wpaDbus)
-	readDebugLevel(wpaDbus)
-	readEapMethods(wpaDbus)
-	return nil
+	errs := make(MultiError, 0)
+	for _, read := range []func(*WpaSupplicantDbus) error{readWFDIEs, readCapabilities, readDebugShowKeys,
+		readDebugTimeStamp, readDebugLevel, readEapMethods} {
+		if err := read(wpaDbus); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.errorOrNil()
 }
 
 func contains(slice interface{}, values interface{}) bool {
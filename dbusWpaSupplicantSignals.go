@@ -0,0 +1,209 @@
+package wpaSuppDBusLib
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+)
+
+var signalBufferSize = 32
+
+// signalSubscription routes the signals matching path, interface and member to its handler.
+// Each subscription has its own goroutine and buffer so a slow handler does not stall the others.
+// Signals arriving while the buffer of a subscription is full are dropped for that subscription.
+type signalSubscription struct {
+	path       dbus.ObjectPath
+	iface      string
+	member     string
+	signalChan chan *dbus.Signal
+	done       chan struct{}
+	matchOpts  []dbus.MatchOption
+}
+
+func (s *signalSubscription) matches(signal *dbus.Signal) bool {
+	if s.path != "" && s.path != signal.Path {
+		return false
+	}
+	return signal.Name == s.iface+"."+s.member
+}
+
+// subscribeSignal registers a match rule for the signal and calls handler for every occurrence.
+// An empty path matches the signal on every object. The returned function unsubscribes. done is
+// closed on unsubscribe, a handler forwarding to a caller owned channel must select on it so it
+// does not block forever once the caller stopped reading.
+func subscribeSignal(wpaDbus *WpaSupplicantDbus, path dbus.ObjectPath, iface, member string, handler func(signal *dbus.Signal, done <-chan struct{})) (func(), error) {
+	matchOpts := []dbus.MatchOption{
+		dbus.WithMatchInterface(iface),
+		dbus.WithMatchMember(member),
+	}
+	if path != "" {
+		matchOpts = append(matchOpts, dbus.WithMatchObjectPath(path))
+	}
+	err := wpaDbus.dbusCon.AddMatchSignal(matchOpts...)
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return nil, err
+	}
+	subscription := &signalSubscription{
+		path:       path,
+		iface:      iface,
+		member:     member,
+		signalChan: make(chan *dbus.Signal, signalBufferSize),
+		done:       make(chan struct{}),
+		matchOpts:  matchOpts,
+	}
+	wpaDbus.signalOnce.Do(func() {
+		dispatchChan := make(chan *dbus.Signal, signalBufferSize)
+		wpaDbus.dbusCon.Signal(dispatchChan)
+		go dispatchSignals(wpaDbus, dispatchChan)
+	})
+	wpaDbus.mutex.Lock()
+	wpaDbus.signalSubscriptions = append(wpaDbus.signalSubscriptions, subscription)
+	wpaDbus.mutex.Unlock()
+	go func() {
+		for {
+			select {
+			case <-subscription.done:
+				return
+			case signal := <-subscription.signalChan:
+				handler(signal, subscription.done)
+			}
+		}
+	}()
+	return func() {
+		unsubscribeSignal(wpaDbus, subscription)
+	}, nil
+}
+
+func unsubscribeSignal(wpaDbus *WpaSupplicantDbus, subscription *signalSubscription) {
+	wpaDbus.mutex.Lock()
+	defer wpaDbus.mutex.Unlock()
+	for i := 0; i < len(wpaDbus.signalSubscriptions); i++ {
+		if wpaDbus.signalSubscriptions[i] == subscription {
+			wpaDbus.signalSubscriptions = append(wpaDbus.signalSubscriptions[:i], wpaDbus.signalSubscriptions[i+1:]...)
+			close(subscription.done)
+			err := wpaDbus.dbusCon.RemoveMatchSignal(subscription.matchOpts...)
+			if err != nil {
+				wpaDbus.logger.Warn(err)
+			}
+			return
+		}
+	}
+}
+
+func dispatchSignals(wpaDbus *WpaSupplicantDbus, dispatchChan chan *dbus.Signal) {
+	for signal := range dispatchChan {
+		wpaDbus.mutex.Lock()
+		subscriptions := make([]*signalSubscription, len(wpaDbus.signalSubscriptions))
+		copy(subscriptions, wpaDbus.signalSubscriptions)
+		wpaDbus.mutex.Unlock()
+		for _, subscription := range subscriptions {
+			if !subscription.matches(signal) {
+				continue
+			}
+			select {
+			case subscription.signalChan <- signal:
+			case <-subscription.done:
+			default:
+				wpaDbus.logger.Warn(fmt.Sprintf("dropping %s signal of %s, its handler is not keeping up", signal.Name, signal.Path))
+			}
+		}
+	}
+}
+
+// signalVariants returns body element i of the signal as a property dictionary
+func signalVariants(signal *dbus.Signal, i int) map[string]dbus.Variant {
+	if len(signal.Body) <= i {
+		return nil
+	}
+	properties, _ := signal.Body[i].(map[string]dbus.Variant)
+	return properties
+}
+
+// signalObjectPath returns body element i of the signal as an object path
+func signalObjectPath(signal *dbus.Signal, i int) dbus.ObjectPath {
+	if len(signal.Body) <= i {
+		return ""
+	}
+	objPath, _ := signal.Body[i].(dbus.ObjectPath)
+	return objPath
+}
+
+// variantInt returns an integer dictionary entry regardless of its D-Bus integer type
+func variantInt(properties map[string]dbus.Variant, key string) int64 {
+	value, ok := properties[key]
+	if !ok {
+		return 0
+	}
+	switch number := value.Value().(type) {
+	case byte:
+		return int64(number)
+	case int16:
+		return int64(number)
+	case uint16:
+		return int64(number)
+	case int32:
+		return int64(number)
+	case uint32:
+		return int64(number)
+	case int64:
+		return number
+	case uint64:
+		return int64(number)
+	}
+	return 0
+}
+
+// variantString returns a string dictionary entry, byte arrays are converted as is
+func variantString(properties map[string]dbus.Variant, key string) string {
+	value, ok := properties[key]
+	if !ok {
+		return ""
+	}
+	switch str := value.Value().(type) {
+	case string:
+		return str
+	case []byte:
+		return string(str)
+	}
+	return ""
+}
+
+// variantObjectPath returns an object path dictionary entry
+func variantObjectPath(properties map[string]dbus.Variant, key string) dbus.ObjectPath {
+	value, ok := properties[key]
+	if !ok {
+		return ""
+	}
+	objPath, _ := value.Value().(dbus.ObjectPath)
+	return objPath
+}
+
+// variantStrings returns a string array dictionary entry
+func variantStrings(properties map[string]dbus.Variant, key string) []string {
+	value, ok := properties[key]
+	if !ok {
+		return nil
+	}
+	strs, _ := value.Value().([]string)
+	return strs
+}
+
+// variantBytes returns a byte array dictionary entry
+func variantBytes(properties map[string]dbus.Variant, key string) []byte {
+	value, ok := properties[key]
+	if !ok {
+		return nil
+	}
+	bytes, _ := value.Value().([]byte)
+	return bytes
+}
+
+// variantBool returns a boolean dictionary entry
+func variantBool(properties map[string]dbus.Variant, key string) bool {
+	value, ok := properties[key]
+	if !ok {
+		return false
+	}
+	b, _ := value.Value().(bool)
+	return b
+}
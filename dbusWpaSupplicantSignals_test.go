@@ -0,0 +1,29 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+	"testing"
+)
+
+func TestDispatchSignalsSkipsStuckHandler(t *testing.T) {
+	recorder := &recordingLogger{}
+	stuck := &signalSubscription{iface: dbusWPAInterfacename, member: "PropertiesChanged",
+		signalChan: make(chan *dbus.Signal, 1), done: make(chan struct{})}
+	other := &signalSubscription{iface: dbusWPAInterfacename, member: "PropertiesChanged",
+		signalChan: make(chan *dbus.Signal, 3), done: make(chan struct{})}
+	wpaDbus := &WpaSupplicantDbus{logger: recorder, signalSubscriptions: []*signalSubscription{stuck, other}}
+
+	dispatchChan := make(chan *dbus.Signal, 3)
+	for i := 0; i < 3; i++ {
+		dispatchChan <- &dbus.Signal{Path: "/fi/w1/wpa_supplicant1/Interfaces/0", Name: dbusWPAInterfacename + ".PropertiesChanged"}
+	}
+	close(dispatchChan)
+	dispatchSignals(wpaDbus, dispatchChan)
+
+	if len(other.signalChan) != 3 {
+		t.Errorf("expected 3 signals despite the stuck handler, got %d", len(other.signalChan))
+	}
+	if len(stuck.signalChan) != 1 || len(recorder.messages) != 2 {
+		t.Errorf("signals beyond the buffer of the stuck handler must be dropped and logged: %v", recorder.messages)
+	}
+}
@@ -3,6 +3,7 @@ package wpaSuppDBusLib
 import (
 	"errors"
 	"github.com/godbus/dbus/v5"
+	"strings"
 )
 
 var dbusWPAname = "fi.w1.wpa_supplicant1"
@@ -11,6 +12,51 @@ var dbusWPAInterfacename = "fi.w1.wpa_supplicant1.Interface"
 
 var dbusWPAObjectPath = dbus.ObjectPath("/fi/w1/wpa_supplicant1")
 
+// MultiError collects the errors of an operation that keeps going after a failure
+type MultiError []error
+
+func (m MultiError) Error() string {
+	messages := make([]string, 0, len(m))
+	for _, err := range m {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the collected errors so errors.Is and errors.As look into each of them
+func (m MultiError) Unwrap() []error {
+	return m
+}
+
+// Is reports whether any collected error matches target. errors.Is only walks Unwrap() []error
+// from Go 1.20 on, Is keeps it working on older toolchains.
+func (m MultiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first collected error that matches target, see Is
+func (m MultiError) As(target interface{}) bool {
+	for _, err := range m {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorOrNil returns nil when no error was collected so callers can return it directly
+func (m MultiError) errorOrNil() error {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
 func newConn() (*dbus.Conn, error) {
 	con, err := dbus.ConnectSystemBus()
 	if err != nil {
@@ -19,7 +65,9 @@ func newConn() (*dbus.Conn, error) {
 	return con, nil
 }
 
-func createInterface(wpaDbus *WpaSupplicantDbus, interfaceName, bridgeName string, driver Driver, pathToSaveInterfaceConfig string, stateChangeChan chan string) (dbus.ObjectPath, error) {
+// createInterface creates the interface and subscribes to its state changes. The returned function
+// cancels the subscription, an interface whose subscription fails is removed again.
+func createInterface(wpaDbus *WpaSupplicantDbus, interfaceName, bridgeName string, driver Driver, pathToSaveInterfaceConfig string, stateChangeChan chan string) (dbus.ObjectPath, func(), error) {
 	obj := wpaDbus.dbusCon.Object(dbusWPAname, dbusWPAObjectPath)
 	var result interface{}
 	argMap := make(map[string]interface{})
@@ -30,35 +78,39 @@ func createInterface(wpaDbus *WpaSupplicantDbus, interfaceName, bridgeName strin
 	err := obj.Call(dbusWPAname+".CreateInterface", 0, argMap).Store(&result)
 	if err != nil {
 		wpaDbus.logger.Error(err)
-		return "", err
+		return "", nil, err
 	}
 	if _, ok := result.(dbus.ObjectPath); !ok {
-		return "", errors.New("unknown return type from dbus. expected string")
+		return "", nil, errors.New("unknown return type from dbus. expected string")
 	}
 	interfaceNameRet := result.(dbus.ObjectPath)
-	objInterface := wpaDbus.dbusCon.Object(dbusWPAInterfacename, interfaceNameRet)
 
-	if err = wpaDbus.dbusCon.AddMatchSignal(
-		dbus.WithMatchObjectPath(objInterface.Path()),
-		dbus.WithMatchInterface(objInterface.Destination()),
-		dbus.WithMatchMember("PropertiesChanged"),
-	); err != nil {
-		panic(err)
+	unsubscribe, err := watchStateChanges(wpaDbus, interfaceNameRet, stateChangeChan)
+	if err != nil {
+		if removeErr := removeInterface(wpaDbus, interfaceNameRet); removeErr != nil {
+			return "", nil, MultiError{err, removeErr}
+		}
+		return "", nil, err
 	}
-	signalChan := make(chan *dbus.Signal)
-	wpaDbus.dbusCon.Signal(signalChan)
-
-	go stateChangeListenFunc(stateChangeChan, signalChan)
+	return interfaceNameRet, unsubscribe, nil
+}
 
-	return interfaceNameRet, nil
+func watchStateChanges(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath, stateChangeChan chan string) (func(), error) {
+	return subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, "PropertiesChanged", func(signal *dbus.Signal, done <-chan struct{}) {
+		stateChangeListenFunc(stateChangeChan, signal, done)
+	})
 }
 
-func stateChangeListenFunc(stateChangeChan chan string, signalChan chan *dbus.Signal) {
-	for changedProp := range signalChan {
-		for i := 0; i < len(changedProp.Body); i++ {
-			noTypeMap := changedProp.Body[i].(map[string]dbus.Variant)
-			if value, contains := noTypeMap["State"]; contains {
-				stateChangeChan <- value.String()
+func stateChangeListenFunc(stateChangeChan chan string, changedProp *dbus.Signal, done <-chan struct{}) {
+	for i := 0; i < len(changedProp.Body); i++ {
+		noTypeMap, ok := changedProp.Body[i].(map[string]dbus.Variant)
+		if !ok {
+			continue
+		}
+		if value, contains := noTypeMap["State"]; contains {
+			select {
+			case stateChangeChan <- value.String():
+			case <-done:
 			}
 		}
 	}
@@ -71,7 +123,7 @@ func removeInterface(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPat
 		wpaDbus.logger.Error(err)
 		return err
 	}
-	delete(wpaDbus.CreatedWPAInterfaces, string(wpaInterfaceName))
+	forgetInterface(wpaDbus, string(wpaInterfaceName))
 	return nil
 }
 
@@ -82,10 +134,107 @@ func expectDisconnect(wpaDbus *WpaSupplicantDbus, wpaInterfaceName string) error
 		wpaDbus.logger.Error(err)
 		return err
 	}
+	forgetInterface(wpaDbus, wpaInterfaceName)
+	return nil
+}
+
+// forgetInterface stops tracking the interface and cancels its state change subscription
+func forgetInterface(wpaDbus *WpaSupplicantDbus, wpaInterfaceName string) {
+	wpaDbus.mutex.Lock()
+	ifConfig := wpaDbus.interfaceConfigs[wpaInterfaceName]
 	delete(wpaDbus.CreatedWPAInterfaces, wpaInterfaceName)
+	delete(wpaDbus.interfaceConfigs, wpaInterfaceName)
+	wpaDbus.mutex.Unlock()
+	if ifConfig.unsubscribeState != nil {
+		ifConfig.unsubscribeState()
+	}
+}
+
+func eapLogoff(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "EAPLogoff")
+}
+
+func eapLogon(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "EAPLogon")
+}
+
+func reassociate(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath) error {
+	return callInterfaceMethod(wpaDbus, wpaInterfaceName, "Reassociate")
+}
+
+func callInterfaceMethod(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath, method string, args ...interface{}) error {
+	return callObjectMethod(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, method, args...)
+}
+
+func callObjectMethod(wpaDbus *WpaSupplicantDbus, objPath dbus.ObjectPath, iface, method string, args ...interface{}) error {
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, objPath)
+	err := obj.Call(iface+"."+method, 0, args...).Err
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func readInterfaceProperty(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath, property string, value interface{}) error {
+	return readObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, property, value)
+}
+
+func readObjectProperty(wpaDbus *WpaSupplicantDbus, objPath dbus.ObjectPath, iface, property string, value interface{}) error {
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, objPath)
+	err := obj.Call("org.freedesktop.DBus.Properties.Get", 0, iface, property).Store(value)
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func readAllObjectProperties(wpaDbus *WpaSupplicantDbus, objPath dbus.ObjectPath, iface string) (map[string]dbus.Variant, error) {
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, objPath)
+	var properties map[string]dbus.Variant
+	err := obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, iface).Store(&properties)
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return nil, err
+	}
+	return properties, nil
+}
+
+func writeInterfaceProperty(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath, property string, value interface{}) error {
+	return writeObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAInterfacename, property, value)
+}
+
+func writeObjectProperty(wpaDbus *WpaSupplicantDbus, objPath dbus.ObjectPath, iface, property string, value interface{}) error {
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, objPath)
+	err := obj.Call("org.freedesktop.DBus.Properties.Set", 0, iface, property, dbus.MakeVariant(value)).Err
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return err
+	}
 	return nil
 }
 
+// isInterfaceUnknown reports whether err is the error GetInterface returns for an interface the
+// supplicant does not control
+func isInterfaceUnknown(err error) bool {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr.Name == dbusWPAname+".InterfaceUnknown"
+	}
+	return false
+}
+
+// isObjectUnknown reports whether the object no longer exists, e.g. because the interface was
+// removed between listing and reading it
+func isObjectUnknown(err error) bool {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr.Name == "org.freedesktop.DBus.Error.UnknownObject"
+	}
+	return false
+}
+
 func getInterface(wpaDbus *WpaSupplicantDbus, networkInterfaceName string) (dbus.ObjectPath, error) {
 	obj := wpaDbus.dbusCon.Object(dbusWPAname, dbusWPAObjectPath)
 	var result interface{}
@@ -132,7 +281,7 @@ func readDebugShowKeys(wpaDbus *WpaSupplicantDbus) error {
 
 func readDebugTimeStamp(wpaDbus *WpaSupplicantDbus) error {
 	obj := wpaDbus.dbusCon.Object(dbusWPAname, dbusWPAObjectPath)
-	err := obj.Call("org.freedesktop.DBus.Properties.Get", 0, dbusWPAname, "DebugTimeStamp").Store(&wpaDbus.DebugTimeStamp)
+	err := obj.Call("org.freedesktop.DBus.Properties.Get", 0, dbusWPAname, "DebugTimestamp").Store(&wpaDbus.DebugTimeStamp)
 	if err != nil {
 		wpaDbus.logger.Error(err)
 		return err
@@ -0,0 +1,267 @@
+package wpaSuppDBusLib
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"net"
+)
+
+var dbusWPAWPSname = "fi.w1.wpa_supplicant1.Interface.WPS"
+
+type WPSRole string
+type WPSType string
+type WPSEventType string
+
+const (
+	WPSRoleEnrollee    WPSRole      = "enrollee"
+	WPSRoleRegistrar   WPSRole      = "registrar"
+	WPSTypePBC         WPSType      = "pbc"
+	WPSTypePIN         WPSType      = "pin"
+	WPSEventSuccess    WPSEventType = "success"
+	WPSEventFail       WPSEventType = "fail"
+	WPSEventM2D        WPSEventType = "m2d"
+	WPSEventPBCOverlap WPSEventType = "pbc-overlap"
+)
+
+var wpsRoleSlice = []WPSRole{WPSRoleEnrollee, WPSRoleRegistrar}
+var wpsTypeSlice = []WPSType{WPSTypePBC, WPSTypePIN}
+
+// WPSParams are the arguments of a WPS run. For enrollee PIN runs an empty Pin makes the
+// supplicant generate one, which is returned by WPSStart. Registrar runs require the AP PIN
+// and the BSSID of the access point. BSSID is optional for enrollee runs.
+type WPSParams struct {
+	Role  WPSRole
+	Type  WPSType
+	Pin   string
+	BSSID string
+}
+
+// WPSEvent is a typed WPS Event signal. ConfigError, Msg and ErrorIndication are set for
+// fail events, the registrar description fields for m2d events.
+type WPSEvent struct {
+	Type            WPSEventType
+	ConfigError     int64
+	Msg             int64
+	ErrorIndication int64
+	DeviceName      string
+	Manufacturer    string
+	ModelName       string
+	ModelNumber     string
+	SerialNumber    string
+	ConfigMethods   int64
+	DevPasswordID   int64
+}
+
+// WPSCredentials are the network credentials received by a successful WPS enrollee run
+type WPSCredentials struct {
+	BSSID    string
+	SSID     []byte
+	AuthType []string
+	EncrType []string
+	Key      []byte
+	KeyIndex uint32
+}
+
+// WPSStart starts a WPS run and returns the PIN generated by the supplicant, if any
+func (wpaDbus *WpaSupplicantDbus) WPSStart(wpaInterfaceName dbus.ObjectPath, params WPSParams) (string, error) {
+	args, err := params.toDBusArgs()
+	if err != nil {
+		return "", err
+	}
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, wpaInterfaceName)
+	var result map[string]dbus.Variant
+	err = obj.Call(dbusWPAWPSname+".Start", 0, args).Store(&result)
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return "", err
+	}
+	return variantString(result, "Pin"), nil
+}
+
+// WPSStartPBC starts a push button enrollee run against any access point in PBC mode
+func (wpaDbus *WpaSupplicantDbus) WPSStartPBC(wpaInterfaceName dbus.ObjectPath) error {
+	_, err := wpaDbus.WPSStart(wpaInterfaceName, WPSParams{Role: WPSRoleEnrollee, Type: WPSTypePBC})
+	return err
+}
+
+// WPSCancel cancels the ongoing WPS run
+func (wpaDbus *WpaSupplicantDbus) WPSCancel(wpaInterfaceName dbus.ObjectPath) error {
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, wpaInterfaceName)
+	err := obj.Call(dbusWPAWPSname+".Cancel", 0).Err
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// GetWPSProcessCredentials reports whether the supplicant adds the networks received through
+// WPS by itself. When false only the Credentials signal is emitted.
+func (wpaDbus *WpaSupplicantDbus) GetWPSProcessCredentials(wpaInterfaceName dbus.ObjectPath) (bool, error) {
+	var processCredentials bool
+	err := readObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "ProcessCredentials", &processCredentials)
+	return processCredentials, err
+}
+
+func (wpaDbus *WpaSupplicantDbus) SetWPSProcessCredentials(wpaInterfaceName dbus.ObjectPath, processCredentials bool) error {
+	return writeObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "ProcessCredentials", processCredentials)
+}
+
+// GetWPSConfigMethods returns the space separated list of advertised WPS config methods
+func (wpaDbus *WpaSupplicantDbus) GetWPSConfigMethods(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var configMethods string
+	err := readObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "ConfigMethods", &configMethods)
+	return configMethods, err
+}
+
+func (wpaDbus *WpaSupplicantDbus) SetWPSConfigMethods(wpaInterfaceName dbus.ObjectPath, configMethods string) error {
+	return writeObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "ConfigMethods", configMethods)
+}
+
+func (wpaDbus *WpaSupplicantDbus) GetWPSDeviceName(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var deviceName string
+	err := readObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "DeviceName", &deviceName)
+	return deviceName, err
+}
+
+func (wpaDbus *WpaSupplicantDbus) SetWPSDeviceName(wpaInterfaceName dbus.ObjectPath, deviceName string) error {
+	return writeObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "DeviceName", deviceName)
+}
+
+func (wpaDbus *WpaSupplicantDbus) GetWPSManufacturer(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	var manufacturer string
+	err := readObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "Manufacturer", &manufacturer)
+	return manufacturer, err
+}
+
+func (wpaDbus *WpaSupplicantDbus) SetWPSManufacturer(wpaInterfaceName dbus.ObjectPath, manufacturer string) error {
+	return writeObjectProperty(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "Manufacturer", manufacturer)
+}
+
+// SubscribeWPSEvents forwards the WPS Event signals of the interface to eventChan.
+// The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeWPSEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan WPSEvent) (func(), error) {
+	return subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "Event", func(signal *dbus.Signal, done <-chan struct{}) {
+		if len(signal.Body) < 1 {
+			return
+		}
+		name, _ := signal.Body[0].(string)
+		args := signalVariants(signal, 1)
+		event := WPSEvent{
+			Type:            WPSEventType(name),
+			ConfigError:     variantInt(args, "config_error"),
+			Msg:             variantInt(args, "msg"),
+			ErrorIndication: variantInt(args, "error_indication"),
+			DeviceName:      variantString(args, "dev_name"),
+			Manufacturer:    variantString(args, "manufacturer"),
+			ModelName:       variantString(args, "model_name"),
+			ModelNumber:     variantString(args, "model_number"),
+			SerialNumber:    variantString(args, "serial_number"),
+			ConfigMethods:   variantInt(args, "config_methods"),
+			DevPasswordID:   variantInt(args, "dev_password_id"),
+		}
+		select {
+		case eventChan <- event:
+		case <-done:
+		}
+	})
+}
+
+// SubscribeWPSCredentials forwards the WPS Credentials signals of the interface to credentialsChan.
+// The returned function cancels the subscription.
+func (wpaDbus *WpaSupplicantDbus) SubscribeWPSCredentials(wpaInterfaceName dbus.ObjectPath, credentialsChan chan WPSCredentials) (func(), error) {
+	return subscribeSignal(wpaDbus, wpaInterfaceName, dbusWPAWPSname, "Credentials", func(signal *dbus.Signal, done <-chan struct{}) {
+		args := signalVariants(signal, 0)
+		credentials := WPSCredentials{}
+		if bssid, ok := args["BSSID"].Value().([]byte); ok {
+			credentials.BSSID = net.HardwareAddr(bssid).String()
+		}
+		credentials.SSID, _ = args["SSID"].Value().([]byte)
+		credentials.AuthType, _ = args["AuthType"].Value().([]string)
+		credentials.EncrType, _ = args["EncrType"].Value().([]string)
+		credentials.Key, _ = args["Key"].Value().([]byte)
+		credentials.KeyIndex = uint32(variantInt(args, "KeyIndex"))
+		select {
+		case credentialsChan <- credentials:
+		case <-done:
+		}
+	})
+}
+
+// ToNetwork converts the credentials into a network block. Only open and WPA/WPA2 personal
+// credentials can be converted, WEP and enterprise credentials are rejected.
+func (c *WPSCredentials) ToNetwork() (*Network, error) {
+	builder := NewNetworkBuilder().WithSSID(string(c.SSID))
+	protos := make([]Proto, 0)
+	for _, authType := range c.AuthType {
+		switch authType {
+		case "wpa-psk":
+			protos = append(protos, WPAProto)
+		case "wpa2-psk":
+			protos = append(protos, WPA2Proto)
+		case "open":
+		default:
+			return nil, fmt.Errorf("unsupported wps auth type %s", authType)
+		}
+	}
+	pairWise := make([]PairWise, 0)
+	for _, encrType := range c.EncrType {
+		switch encrType {
+		case "aes":
+			pairWise = append(pairWise, PairWiseCCMP)
+		case "tkip":
+			pairWise = append(pairWise, PairWiseTKIP)
+		case "none":
+		default:
+			return nil, fmt.Errorf("unsupported wps encryption type %s", encrType)
+		}
+	}
+	if len(protos) == 0 {
+		return builder.WithKeyManagement(NONE).Build()
+	}
+	if len(c.Key) == 0 {
+		return nil, errors.New("wps credentials carry no key")
+	}
+	builder.WithKeyManagement(WpaPSK).WithProto(protos...).WithPSK(string(c.Key))
+	if len(pairWise) > 0 {
+		builder.WithPairWise(pairWise...)
+	}
+	return builder.Build()
+}
+
+func (p *WPSParams) toDBusArgs() (map[string]interface{}, error) {
+	if !contains(wpsRoleSlice, p.Role) {
+		return nil, errors.New("invalid value for wps role")
+	}
+	if !contains(wpsTypeSlice, p.Type) {
+		return nil, errors.New("invalid value for wps type")
+	}
+	if p.Role == WPSRoleRegistrar && (p.Type != WPSTypePIN || p.Pin == "" || p.BSSID == "") {
+		return nil, errors.New("wps registrar requires type pin, the ap pin and the bssid")
+	}
+	if p.Type == WPSTypePBC && p.Pin != "" {
+		return nil, errors.New("wps pbc does not take a pin")
+	}
+	args := map[string]interface{}{
+		"Role": string(p.Role),
+		"Type": string(p.Type),
+	}
+	if p.Pin != "" {
+		args["Pin"] = p.Pin
+	}
+	if p.BSSID != "" {
+		bssid, err := net.ParseMAC(p.BSSID)
+		if err != nil || len(bssid) != 6 {
+			return nil, errors.New("invalid value for bssid")
+		}
+		args["Bssid"] = []byte(bssid)
+	}
+	return args, nil
+}
+
+func (c WPSCredentials) String() string {
+	return fmt.Sprintf("WPSCredentials{BSSID: %s, SSID: %s, AuthType: %v, EncrType: %v, Key: %d bytes}",
+		c.BSSID, hex.EncodeToString(c.SSID), c.AuthType, c.EncrType, len(c.Key))
+}
@@ -0,0 +1,67 @@
+package wpaSuppDBusLib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWPSCredentialsToNetwork(t *testing.T) {
+	credentials := WPSCredentials{
+		SSID:     []byte("sensors"),
+		AuthType: []string{"wpa2-psk"},
+		EncrType: []string{"aes"},
+		Key:      []byte("sensor-passphrase"),
+	}
+	network, err := credentials.ToNetwork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	confStr := network.ToConfigString()
+	for _, expected := range []string{"ssid=\"sensors\"", "key_mgmt=WPA-PSK", "proto=RSN", "pairwise=CCMP", "psk=\"sensor-passphrase\""} {
+		if !strings.Contains(confStr, expected) {
+			t.Errorf("expected %s in %s", expected, confStr)
+		}
+	}
+
+	credentials = WPSCredentials{SSID: []byte("open"), AuthType: []string{"open"}, EncrType: []string{"none"}}
+	network, err = credentials.ToNetwork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(network.ToConfigString(), "key_mgmt=NONE") {
+		t.Errorf("open credentials should convert to key_mgmt=NONE")
+	}
+
+	for _, authType := range []string{"wpa2-eap", "shared"} {
+		credentials = WPSCredentials{SSID: []byte("net"), AuthType: []string{authType}, Key: []byte("12345678")}
+		if _, err = credentials.ToNetwork(); err == nil {
+			t.Errorf("expected %s credentials to be rejected", authType)
+		}
+	}
+}
+
+func TestWPSParamsValidation(t *testing.T) {
+	invalid := map[string]WPSParams{
+		"unknown role":            {Role: "observer", Type: WPSTypePBC},
+		"pbc with pin":            {Role: WPSRoleEnrollee, Type: WPSTypePBC, Pin: "12345670"},
+		"registrar without pin":   {Role: WPSRoleRegistrar, Type: WPSTypePIN, BSSID: "00:11:22:33:44:55"},
+		"registrar without bssid": {Role: WPSRoleRegistrar, Type: WPSTypePIN, Pin: "12345670"},
+		"invalid bssid":           {Role: WPSRoleEnrollee, Type: WPSTypePIN, BSSID: "00:11:22"},
+	}
+	for name, params := range invalid {
+		if _, err := params.toDBusArgs(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	args, err := (&WPSParams{Role: WPSRoleEnrollee, Type: WPSTypePIN, BSSID: "00:11:22:33:44:55"}).toDBusArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := args["Pin"]; ok {
+		t.Errorf("pin should be left out so the supplicant generates one")
+	}
+	if bssid, ok := args["Bssid"].([]byte); !ok || len(bssid) != 6 {
+		t.Errorf("bssid should be sent as 6 bytes")
+	}
+}
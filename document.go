@@ -0,0 +1,950 @@
+package wpaSuppDBusLib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+// The document types are the JSON and YAML form of interfaces, networks, credentials and EAP
+// methods. Options that are not set are left out. Decoding always goes through the builders, so a
+// document that decodes without error is as valid as one built in Go.
+//
+// MarshalJSON and MarshalYAML mask every secret so interfaces can be logged safely, a redacted
+// document is rejected when it is decoded. Marshal the result of ToDocument to export the
+// configuration including its secrets.
+
+// InterfaceDocument is the serialized form of a WPAInterface
+type InterfaceDocument struct {
+	CtrlInterface          string               `json:"ctrl_interface,omitempty" yaml:"ctrl_interface,omitempty"`
+	CtrlInterfaceGroup     string               `json:"ctrl_interface_group,omitempty" yaml:"ctrl_interface_group,omitempty"`
+	EapolVersion           *EapolVersion        `json:"eapol_version,omitempty" yaml:"eapol_version,omitempty"`
+	ApScan                 *ApScan              `json:"ap_scan,omitempty" yaml:"ap_scan,omitempty"`
+	FastReauth             *FastReauth          `json:"fast_reauth,omitempty" yaml:"fast_reauth,omitempty"`
+	PMKLifetime            *uint32              `json:"dot11RSNAConfigPMKLifetime,omitempty" yaml:"dot11RSNAConfigPMKLifetime,omitempty"`
+	PMKReauthThreshold     *uint32              `json:"dot11RSNAConfigPMKReauthThreshold,omitempty" yaml:"dot11RSNAConfigPMKReauthThreshold,omitempty"`
+	SATimeout              *uint32              `json:"dot11RSNAConfigSATimeout,omitempty" yaml:"dot11RSNAConfigSATimeout,omitempty"`
+	Country                string               `json:"country,omitempty" yaml:"country,omitempty"`
+	UpdateConfig           *UpdateConfig        `json:"update_config,omitempty" yaml:"update_config,omitempty"`
+	UUID                   string               `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	DeviceName             string               `json:"device_name,omitempty" yaml:"device_name,omitempty"`
+	Manufacturer           string               `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	ConfigMethods          []WPSConfigMethod    `json:"config_methods,omitempty" yaml:"config_methods,omitempty"`
+	P2PDisabled            *P2PDisabled         `json:"p2p_disabled,omitempty" yaml:"p2p_disabled,omitempty"`
+	BSSExpirationAge       *uint32              `json:"bss_expiration_age,omitempty" yaml:"bss_expiration_age,omitempty"`
+	BSSExpirationScanCount *uint32              `json:"bss_expiration_scan_count,omitempty" yaml:"bss_expiration_scan_count,omitempty"`
+	Autoscan               *AutoscanDocument    `json:"autoscan,omitempty" yaml:"autoscan,omitempty"`
+	PassiveScan            *PassiveScan         `json:"passive_scan,omitempty" yaml:"passive_scan,omitempty"`
+	FilterSSIDs            *FilterSSIDs         `json:"filter_ssids,omitempty" yaml:"filter_ssids,omitempty"`
+	MacAddr                *MacAddrPolicy       `json:"mac_addr,omitempty" yaml:"mac_addr,omitempty"`
+	PreassocMacAddr        *MacAddrPolicy       `json:"preassoc_mac_addr,omitempty" yaml:"preassoc_mac_addr,omitempty"`
+	RandAddrLifetime       *uint32              `json:"rand_addr_lifetime,omitempty" yaml:"rand_addr_lifetime,omitempty"`
+	DriverParam            string               `json:"driver_param,omitempty" yaml:"driver_param,omitempty"`
+	PMF                    *PMF                 `json:"pmf,omitempty" yaml:"pmf,omitempty"`
+	SAEGroups              []SAEGroup           `json:"sae_groups,omitempty" yaml:"sae_groups,omitempty"`
+	ExternalSIM            *ExternalSIM         `json:"external_sim,omitempty" yaml:"external_sim,omitempty"`
+	WowlanTriggers         []WowlanTrigger      `json:"wowlan_triggers,omitempty" yaml:"wowlan_triggers,omitempty"`
+	ExtPasswordBackend     string               `json:"ext_password_backend,omitempty" yaml:"ext_password_backend,omitempty"`
+	Interworking           *Interworking        `json:"interworking,omitempty" yaml:"interworking,omitempty"`
+	HS20                   *HS20                `json:"hs20,omitempty" yaml:"hs20,omitempty"`
+	AutoInterworking       *AutoInterworking    `json:"auto_interworking,omitempty" yaml:"auto_interworking,omitempty"`
+	UserMPM                *UserMPM             `json:"user_mpm,omitempty" yaml:"user_mpm,omitempty"`
+	MaxPeerLinks           *uint8               `json:"max_peer_links,omitempty" yaml:"max_peer_links,omitempty"`
+	MeshMaxInactivity      *uint32              `json:"mesh_max_inactivity,omitempty" yaml:"mesh_max_inactivity,omitempty"`
+	OKC                    *OKC                 `json:"okc,omitempty" yaml:"okc,omitempty"`
+	Networks               []NetworkDocument    `json:"networks,omitempty" yaml:"networks,omitempty"`
+	Credentials            []CredentialDocument `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+}
+
+// AutoscanDocument is the autoscan module of an interface. Module is periodic or exponential,
+// Limit is only used by exponential.
+type AutoscanDocument struct {
+	Module   string `json:"module" yaml:"module"`
+	Interval uint32 `json:"interval" yaml:"interval"`
+	Limit    uint32 `json:"limit,omitempty" yaml:"limit,omitempty"`
+}
+
+// NetworkDocument is the serialized form of a Network
+type NetworkDocument struct {
+	SSID                string               `json:"ssid,omitempty" yaml:"ssid,omitempty"`
+	ScanSSID            *ScanSSID            `json:"scan_ssid,omitempty" yaml:"scan_ssid,omitempty"`
+	BSSID               string               `json:"bssid,omitempty" yaml:"bssid,omitempty"`
+	Priority            uint                 `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Mode                *Mode                `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Frequency           uint32               `json:"frequency,omitempty" yaml:"frequency,omitempty"`
+	Proto               []Proto              `json:"proto,omitempty" yaml:"proto,omitempty"`
+	KeyMgmt             []KeyManagement      `json:"key_mgmt,omitempty" yaml:"key_mgmt,omitempty"`
+	AuthAlg             []AuthAlg            `json:"auth_alg,omitempty" yaml:"auth_alg,omitempty"`
+	Pairwise            []PairWise           `json:"pairwise,omitempty" yaml:"pairwise,omitempty"`
+	Group               []Group              `json:"group,omitempty" yaml:"group,omitempty"`
+	PSK                 string               `json:"psk,omitempty" yaml:"psk,omitempty"`
+	ExternalPSK         string               `json:"psk_ext,omitempty" yaml:"psk_ext,omitempty"`
+	SAEPassword         string               `json:"sae_password,omitempty" yaml:"sae_password,omitempty"`
+	SAEPasswordID       string               `json:"sae_password_id,omitempty" yaml:"sae_password_id,omitempty"`
+	EapolFlags          *EapolFlag           `json:"eapol_flags,omitempty" yaml:"eapol_flags,omitempty"`
+	EAP                 []EAPDocument        `json:"eap,omitempty" yaml:"eap,omitempty"`
+	Macsec              *MacsecDocument      `json:"macsec,omitempty" yaml:"macsec,omitempty"`
+	Mesh                *MeshDocument        `json:"mesh,omitempty" yaml:"mesh,omitempty"`
+	AP                  *APDocument          `json:"ap,omitempty" yaml:"ap,omitempty"`
+	Bgscan              *BgscanDocument      `json:"bgscan,omitempty" yaml:"bgscan,omitempty"`
+	ProactiveKeyCaching *ProactiveKeyCaching `json:"proactive_key_caching,omitempty" yaml:"proactive_key_caching,omitempty"`
+}
+
+// MacsecDocument holds the IEEE 802.1AE options of a network
+type MacsecDocument struct {
+	Policy        *MacsecPolicy        `json:"policy,omitempty" yaml:"policy,omitempty"`
+	IntegOnly     *MacsecIntegOnly     `json:"integ_only,omitempty" yaml:"integ_only,omitempty"`
+	ReplayProtect *MacsecReplayProtect `json:"replay_protect,omitempty" yaml:"replay_protect,omitempty"`
+	ReplayWindow  *uint32              `json:"replay_window,omitempty" yaml:"replay_window,omitempty"`
+	Offload       *MacsecOffload       `json:"offload,omitempty" yaml:"offload,omitempty"`
+	Port          *uint16              `json:"port,omitempty" yaml:"port,omitempty"`
+	MKACAK        string               `json:"mka_cak,omitempty" yaml:"mka_cak,omitempty"`
+	MKACKN        string               `json:"mka_ckn,omitempty" yaml:"mka_ckn,omitempty"`
+	MKAPriority   *uint8               `json:"mka_priority,omitempty" yaml:"mka_priority,omitempty"`
+}
+
+// MeshDocument holds the IEEE 802.11s options of a mesh network
+type MeshDocument struct {
+	Fwding        *MeshFwding `json:"fwding,omitempty" yaml:"fwding,omitempty"`
+	RSSIThreshold *int16      `json:"rssi_threshold,omitempty" yaml:"rssi_threshold,omitempty"`
+	MaxRetries    *uint8      `json:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+}
+
+// APDocument holds the options of an AP or mesh network
+type APDocument struct {
+	HT40          *HT40        `json:"ht40,omitempty" yaml:"ht40,omitempty"`
+	VHT           *VHT         `json:"vht,omitempty" yaml:"vht,omitempty"`
+	HE            *HE          `json:"he,omitempty" yaml:"he,omitempty"`
+	BeaconInt     *uint16      `json:"beacon_int,omitempty" yaml:"beacon_int,omitempty"`
+	DTIMPeriod    *uint8       `json:"dtim_period,omitempty" yaml:"dtim_period,omitempty"`
+	MaxInactivity *uint32      `json:"max_inactivity,omitempty" yaml:"max_inactivity,omitempty"`
+	WPSDisabled   *WPSDisabled `json:"wps_disabled,omitempty" yaml:"wps_disabled,omitempty"`
+}
+
+// BgscanDocument is the background scan module of a network. Module is simple or learn,
+// DBFile is only used by learn.
+type BgscanDocument struct {
+	Module          string `json:"module" yaml:"module"`
+	ShortInterval   uint32 `json:"short_interval" yaml:"short_interval"`
+	SignalThreshold int16  `json:"signal_threshold" yaml:"signal_threshold"`
+	LongInterval    uint32 `json:"long_interval" yaml:"long_interval"`
+	DBFile          string `json:"db_file,omitempty" yaml:"db_file,omitempty"`
+}
+
+// EAPDocument is the serialized form of an EAP method. Type is the EAP method name (TLS, PEAP,
+// TTLS or MD5) and decides which of the other fields apply.
+type EAPDocument struct {
+	Type               string        `json:"type" yaml:"type"`
+	Identity           string        `json:"identity,omitempty" yaml:"identity,omitempty"`
+	AnonymousIdentity  string        `json:"anonymous_identity,omitempty" yaml:"anonymous_identity,omitempty"`
+	Username           string        `json:"username,omitempty" yaml:"username,omitempty"`
+	Password           string        `json:"password,omitempty" yaml:"password,omitempty"`
+	PasswordHash       string        `json:"password_hash,omitempty" yaml:"password_hash,omitempty"`
+	ExternalPassword   string        `json:"password_ext,omitempty" yaml:"password_ext,omitempty"`
+	CaCert             string        `json:"ca_cert,omitempty" yaml:"ca_cert,omitempty"`
+	ClientCert         string        `json:"client_cert,omitempty" yaml:"client_cert,omitempty"`
+	PrivateKey         string        `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	PrivateKeyPassword string        `json:"private_key_passwd,omitempty" yaml:"private_key_passwd,omitempty"`
+	PEAPVersion        *PEAPVersion  `json:"peap_version,omitempty" yaml:"peap_version,omitempty"`
+	InnerAuth          innerAuthType `json:"phase2,omitempty" yaml:"phase2,omitempty"`
+}
+
+// CredentialDocument is the serialized form of a Credential
+type CredentialDocument struct {
+	Realm                     string        `json:"realm,omitempty" yaml:"realm,omitempty"`
+	Username                  string        `json:"username,omitempty" yaml:"username,omitempty"`
+	Password                  string        `json:"password,omitempty" yaml:"password,omitempty"`
+	CaCert                    string        `json:"ca_cert,omitempty" yaml:"ca_cert,omitempty"`
+	ClientCert                string        `json:"client_cert,omitempty" yaml:"client_cert,omitempty"`
+	PrivateKey                string        `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	PrivateKeyPassword        string        `json:"private_key_passwd,omitempty" yaml:"private_key_passwd,omitempty"`
+	IMSI                      string        `json:"imsi,omitempty" yaml:"imsi,omitempty"`
+	Milenage                  string        `json:"milenage,omitempty" yaml:"milenage,omitempty"`
+	Domain                    []string      `json:"domain,omitempty" yaml:"domain,omitempty"`
+	RoamingConsortium         string        `json:"roaming_consortium,omitempty" yaml:"roaming_consortium,omitempty"`
+	RequiredRoamingConsortium string        `json:"required_roaming_consortium,omitempty" yaml:"required_roaming_consortium,omitempty"`
+	EAP                       CredentialEAP `json:"eap,omitempty" yaml:"eap,omitempty"`
+	InnerAuth                 innerAuthType `json:"phase2,omitempty" yaml:"phase2,omitempty"`
+	Priority                  uint          `json:"priority,omitempty" yaml:"priority,omitempty"`
+	ProvisioningSP            string        `json:"provisioning_sp,omitempty" yaml:"provisioning_sp,omitempty"`
+	SPPriority                *uint8        `json:"sp_priority,omitempty" yaml:"sp_priority,omitempty"`
+	OCSP                      *OCSP         `json:"ocsp,omitempty" yaml:"ocsp,omitempty"`
+}
+
+// ToDocument returns the serializable form of the interface
+func (wpa *WPAInterface) ToDocument() InterfaceDocument {
+	doc := InterfaceDocument{CtrlInterface: wpa.ctrlInterface, CtrlInterfaceGroup: wpa.ctrlInterfaceGroup}
+	if wpa.eapolVersion != defaultEapolVersion {
+		eapolVersion := wpa.eapolVersion
+		doc.EapolVersion = &eapolVersion
+	}
+	if wpa.apScan != defaultApScan {
+		apScan := wpa.apScan
+		doc.ApScan = &apScan
+	}
+	if wpa.fastReauth != defaultFastReauth {
+		fastReauth := wpa.fastReauth
+		doc.FastReauth = &fastReauth
+	}
+	if wpa.pmkLifetime != defaultDot11RSNAConfigPMKLifetime {
+		pmkLifetime := wpa.pmkLifetime
+		doc.PMKLifetime = &pmkLifetime
+	}
+	if wpa.interworking != defaultInterworking {
+		interworking := wpa.interworking
+		doc.Interworking = &interworking
+	}
+	if wpa.hs20 != defaultHS20 {
+		hs20 := wpa.hs20
+		doc.HS20 = &hs20
+	}
+	if wpa.autoInterworking != defaultAutoInterworking {
+		autoInterworking := wpa.autoInterworking
+		doc.AutoInterworking = &autoInterworking
+	}
+	if wpa.userMPM != defaultUserMPM {
+		userMPM := wpa.userMPM
+		doc.UserMPM = &userMPM
+	}
+	if wpa.maxPeerLinks != defaultMaxPeerLinks {
+		maxPeerLinks := wpa.maxPeerLinks
+		doc.MaxPeerLinks = &maxPeerLinks
+	}
+	if wpa.meshMaxInactivity != defaultMeshMaxInactivity {
+		meshMaxInactivity := wpa.meshMaxInactivity
+		doc.MeshMaxInactivity = &meshMaxInactivity
+	}
+	if wpa.okc != defaultOKC {
+		okc := wpa.okc
+		doc.OKC = &okc
+	}
+	wpa.globals.toDocument(&doc)
+	for i := range wpa.network {
+		doc.Networks = append(doc.Networks, wpa.network[i].ToDocument())
+	}
+	for i := range wpa.credential {
+		doc.Credentials = append(doc.Credentials, wpa.credential[i].ToDocument())
+	}
+	return doc
+}
+
+func (g *interfaceGlobals) toDocument(doc *InterfaceDocument) {
+	global := *g
+	doc.Country = global.country
+	doc.UUID = global.uuid
+	doc.DeviceName = global.deviceName
+	doc.Manufacturer = global.manufacturer
+	doc.ConfigMethods = global.configMethods
+	doc.DriverParam = global.driverParam
+	doc.SAEGroups = global.saeGroups
+	doc.WowlanTriggers = global.wowlanTriggers
+	if global.extPasswordBackend != "" {
+		doc.ExtPasswordBackend = global.extPasswordBackendValue()
+	}
+	if global.updateConfig != defaultUpdateConfig {
+		doc.UpdateConfig = &global.updateConfig
+	}
+	if global.p2pDisabled != defaultP2PDisabled {
+		doc.P2PDisabled = &global.p2pDisabled
+	}
+	if global.bssExpirationAge != defaultBSSExpirationAge {
+		doc.BSSExpirationAge = &global.bssExpirationAge
+	}
+	if global.bssExpirationScanCount != defaultBSSExpirationScanCount {
+		doc.BSSExpirationScanCount = &global.bssExpirationScanCount
+	}
+	if global.autoscan.module != "" {
+		doc.Autoscan = &AutoscanDocument{Module: global.autoscan.module, Interval: global.autoscan.interval, Limit: global.autoscan.limit}
+	}
+	if global.passiveScan != defaultPassiveScan {
+		doc.PassiveScan = &global.passiveScan
+	}
+	if global.filterSSIDs != defaultFilterSSIDs {
+		doc.FilterSSIDs = &global.filterSSIDs
+	}
+	if global.macAddr != defaultMacAddr {
+		doc.MacAddr = &global.macAddr
+	}
+	if global.preassocMacAddr != defaultPreassocMacAddr {
+		doc.PreassocMacAddr = &global.preassocMacAddr
+	}
+	if global.randAddrLifetime != defaultRandAddrLifetime {
+		doc.RandAddrLifetime = &global.randAddrLifetime
+	}
+	if global.pmkReauthThreshold != defaultDot11RSNAConfigPMKReauthThreshold {
+		doc.PMKReauthThreshold = &global.pmkReauthThreshold
+	}
+	if global.saTimeout != defaultDot11RSNAConfigSATimeout {
+		doc.SATimeout = &global.saTimeout
+	}
+	if global.pmf != defaultPMF {
+		doc.PMF = &global.pmf
+	}
+	if global.externalSIM != defaultExternalSIM {
+		doc.ExternalSIM = &global.externalSIM
+	}
+}
+
+// Build validates the document and returns the interface it describes
+func (doc *InterfaceDocument) Build() (*WPAInterface, error) {
+	builder := NewWpaInterfaceBuilder()
+	if doc.CtrlInterface != "" {
+		builder.WithCtrlInterface(doc.CtrlInterface)
+	}
+	if doc.CtrlInterfaceGroup != "" {
+		builder.WithCtrlInterfaceGroup(doc.CtrlInterfaceGroup)
+	}
+	if doc.EapolVersion != nil {
+		builder.WithEapolVersion(*doc.EapolVersion)
+	}
+	if doc.ApScan != nil {
+		builder.WithApScan(*doc.ApScan)
+	}
+	if doc.FastReauth != nil {
+		builder.WithFastReauth(*doc.FastReauth)
+	}
+	if doc.PMKLifetime != nil {
+		builder.WithDot11RSNAConfigPMKLifetime(*doc.PMKLifetime)
+	}
+	if doc.PMKReauthThreshold != nil {
+		builder.WithDot11RSNAConfigPMKReauthThreshold(*doc.PMKReauthThreshold)
+	}
+	if doc.SATimeout != nil {
+		builder.WithDot11RSNAConfigSATimeout(*doc.SATimeout)
+	}
+	if doc.Country != "" {
+		builder.WithCountry(doc.Country)
+	}
+	if doc.UpdateConfig != nil {
+		builder.WithUpdateConfig(*doc.UpdateConfig)
+	}
+	if doc.UUID != "" {
+		builder.WithUUID(doc.UUID)
+	}
+	if doc.DeviceName != "" {
+		builder.WithDeviceName(doc.DeviceName)
+	}
+	if doc.Manufacturer != "" {
+		builder.WithManufacturer(doc.Manufacturer)
+	}
+	if len(doc.ConfigMethods) > 0 {
+		builder.WithConfigMethods(doc.ConfigMethods...)
+	}
+	if doc.P2PDisabled != nil {
+		builder.WithP2PDisabled(*doc.P2PDisabled)
+	}
+	if doc.BSSExpirationAge != nil || doc.BSSExpirationScanCount != nil {
+		age, scanCount := defaultBSSExpirationAge, defaultBSSExpirationScanCount
+		if doc.BSSExpirationAge != nil {
+			age = *doc.BSSExpirationAge
+		}
+		if doc.BSSExpirationScanCount != nil {
+			scanCount = *doc.BSSExpirationScanCount
+		}
+		builder.WithBSSExpiration(age, scanCount)
+	}
+	if doc.Autoscan != nil {
+		switch doc.Autoscan.Module {
+		case "periodic":
+			builder.WithAutoscanPeriodic(doc.Autoscan.Interval)
+		case "exponential":
+			builder.WithAutoscanExponential(doc.Autoscan.Interval, doc.Autoscan.Limit)
+		default:
+			return nil, fmt.Errorf("invalid value for autoscan module %q", doc.Autoscan.Module)
+		}
+	}
+	if doc.PassiveScan != nil {
+		builder.WithPassiveScan(*doc.PassiveScan)
+	}
+	if doc.FilterSSIDs != nil {
+		builder.WithFilterSSIDs(*doc.FilterSSIDs)
+	}
+	if doc.MacAddr != nil {
+		builder.WithMacAddr(*doc.MacAddr)
+	}
+	if doc.PreassocMacAddr != nil {
+		builder.WithPreassocMacAddr(*doc.PreassocMacAddr)
+	}
+	if doc.RandAddrLifetime != nil {
+		builder.WithRandAddrLifetime(*doc.RandAddrLifetime)
+	}
+	if doc.DriverParam != "" {
+		builder.WithDriverParam(doc.DriverParam)
+	}
+	if doc.PMF != nil {
+		builder.WithPMF(*doc.PMF)
+	}
+	if len(doc.SAEGroups) > 0 {
+		builder.WithSAEGroups(doc.SAEGroups...)
+	}
+	if doc.ExternalSIM != nil {
+		builder.WithExternalSIM(*doc.ExternalSIM)
+	}
+	if len(doc.WowlanTriggers) > 0 {
+		builder.WithWowlanTriggers(doc.WowlanTriggers...)
+	}
+	if doc.ExtPasswordBackend != "" {
+		backend, parameters := doc.ExtPasswordBackend, ""
+		if separator := strings.Index(backend, ":"); separator >= 0 {
+			backend, parameters = backend[:separator], backend[separator+1:]
+		}
+		builder.WithExtPasswordBackend(ExtPasswordBackend(backend), parameters)
+	}
+	if doc.Interworking != nil {
+		builder.WithInterworking(*doc.Interworking)
+	}
+	if doc.HS20 != nil {
+		builder.WithHS20(*doc.HS20)
+	}
+	if doc.AutoInterworking != nil {
+		builder.WithAutoInterworking(*doc.AutoInterworking)
+	}
+	if doc.UserMPM != nil {
+		builder.WithUserMPM(*doc.UserMPM)
+	}
+	if doc.MaxPeerLinks != nil {
+		builder.WithMaxPeerLinks(*doc.MaxPeerLinks)
+	}
+	if doc.MeshMaxInactivity != nil {
+		builder.WithMeshMaxInactivity(*doc.MeshMaxInactivity)
+	}
+	if doc.OKC != nil {
+		builder.WithOKC(*doc.OKC)
+	}
+	networks := make([]Network, 0, len(doc.Networks))
+	for i := range doc.Networks {
+		network, err := doc.Networks[i].Build()
+		if err != nil {
+			return nil, fmt.Errorf("network %d: %w", i, err)
+		}
+		networks = append(networks, *network)
+	}
+	builder.WithNetwork(networks...)
+	credentials := make([]Credential, 0, len(doc.Credentials))
+	for i := range doc.Credentials {
+		credential, err := doc.Credentials[i].Build()
+		if err != nil {
+			return nil, fmt.Errorf("credential %d: %w", i, err)
+		}
+		credentials = append(credentials, *credential)
+	}
+	builder.WithCredential(credentials...)
+	return builder.Build()
+}
+
+// ToDocument returns the serializable form of the network
+func (net *Network) ToDocument() NetworkDocument {
+	doc := NetworkDocument{
+		SSID:          net.ssid,
+		BSSID:         net.bssid,
+		Priority:      net.priority,
+		Frequency:     net.frequency,
+		Proto:         net.proto,
+		KeyMgmt:       net.keyMngnt,
+		AuthAlg:       net.authAlg,
+		Pairwise:      net.pairWise,
+		Group:         net.group,
+		PSK:           net.psk,
+		ExternalPSK:   net.pskExternal,
+		SAEPassword:   net.saePassword,
+		SAEPasswordID: net.saePasswordID,
+	}
+	if net.scanSsid != -1 {
+		scanSsid := net.scanSsid
+		doc.ScanSSID = &scanSsid
+	}
+	if net.mode != -1 {
+		mode := net.mode
+		doc.Mode = &mode
+	}
+	if net.eaPol != -1 {
+		eaPol := net.eaPol
+		doc.EapolFlags = &eaPol
+	}
+	for i := range net.eap {
+		doc.EAP = append(doc.EAP, net.eap[i].toDocument())
+	}
+	if net.macsec.isSet() {
+		doc.Macsec = net.macsec.toDocument()
+	}
+	if net.mesh.isSet() {
+		doc.Mesh = net.mesh.toDocument()
+	}
+	if net.ap != newAPConfig() {
+		doc.AP = net.ap.toDocument()
+	}
+	if net.bgscan.module != "" {
+		doc.Bgscan = &BgscanDocument{
+			Module:          net.bgscan.module,
+			ShortInterval:   net.bgscan.shortInterval,
+			SignalThreshold: net.bgscan.signalThreshold,
+			LongInterval:    net.bgscan.longInterval,
+			DBFile:          net.bgscan.dbFile,
+		}
+	}
+	if net.pkc != -1 {
+		pkc := net.pkc
+		doc.ProactiveKeyCaching = &pkc
+	}
+	return doc
+}
+
+func (m macsecConfig) toDocument() *MacsecDocument {
+	doc := &MacsecDocument{MKACAK: m.mkaCak, MKACKN: m.mkaCkn}
+	if m.policy != -1 {
+		doc.Policy = &m.policy
+	}
+	if m.integOnly != -1 {
+		doc.IntegOnly = &m.integOnly
+	}
+	if m.replayProtect != -1 {
+		doc.ReplayProtect = &m.replayProtect
+	}
+	if m.replayWindow != -1 {
+		replayWindow := uint32(m.replayWindow)
+		doc.ReplayWindow = &replayWindow
+	}
+	if m.offload != -1 {
+		doc.Offload = &m.offload
+	}
+	if m.port != -1 {
+		port := uint16(m.port)
+		doc.Port = &port
+	}
+	if m.mkaPriority != -1 {
+		mkaPriority := uint8(m.mkaPriority)
+		doc.MKAPriority = &mkaPriority
+	}
+	return doc
+}
+
+func (m meshConfig) toDocument() *MeshDocument {
+	doc := &MeshDocument{}
+	if m.fwding != -1 {
+		doc.Fwding = &m.fwding
+	}
+	if m.rssiThreshold != defaultMeshRSSIThreshold {
+		doc.RSSIThreshold = &m.rssiThreshold
+	}
+	if m.maxRetries != -1 {
+		maxRetries := uint8(m.maxRetries)
+		doc.MaxRetries = &maxRetries
+	}
+	return doc
+}
+
+func (a apConfig) toDocument() *APDocument {
+	doc := &APDocument{}
+	if a.ht40 != -1 {
+		doc.HT40 = &a.ht40
+	}
+	if a.vht != -1 {
+		doc.VHT = &a.vht
+	}
+	if a.he != -1 {
+		doc.HE = &a.he
+	}
+	if a.beaconInt != -1 {
+		beaconInt := uint16(a.beaconInt)
+		doc.BeaconInt = &beaconInt
+	}
+	if a.dtimPeriod != -1 {
+		dtimPeriod := uint8(a.dtimPeriod)
+		doc.DTIMPeriod = &dtimPeriod
+	}
+	if a.maxInactivity != -1 {
+		maxInactivity := uint32(a.maxInactivity)
+		doc.MaxInactivity = &maxInactivity
+	}
+	if a.wpsDisabled != -1 {
+		doc.WPSDisabled = &a.wpsDisabled
+	}
+	return doc
+}
+
+// Build validates the document and returns the network it describes
+func (doc *NetworkDocument) Build() (*Network, error) {
+	if err := checkNotRedacted(doc.PSK, doc.SAEPassword); err != nil {
+		return nil, err
+	}
+	if doc.Macsec != nil {
+		if err := checkNotRedacted(doc.Macsec.MKACAK); err != nil {
+			return nil, err
+		}
+	}
+	builder := NewNetworkBuilder()
+	if doc.SSID != "" {
+		builder.WithSSID(doc.SSID)
+	}
+	if doc.ScanSSID != nil {
+		builder.WithScanSSID(*doc.ScanSSID)
+	}
+	if doc.BSSID != "" {
+		builder.WithBSSID(doc.BSSID)
+	}
+	if doc.Priority != 0 {
+		builder.WithPriority(doc.Priority)
+	}
+	if doc.Mode != nil {
+		builder.WithMode(*doc.Mode)
+	}
+	if doc.Frequency != 0 {
+		builder.WithFrequency(doc.Frequency)
+	}
+	if len(doc.Proto) > 0 {
+		builder.WithProto(doc.Proto...)
+	}
+	if len(doc.KeyMgmt) > 0 {
+		builder.WithKeyManagement(doc.KeyMgmt...)
+	}
+	if len(doc.AuthAlg) > 0 {
+		builder.WithAuthAlg(doc.AuthAlg...)
+	}
+	if len(doc.Pairwise) > 0 {
+		builder.WithPairWise(doc.Pairwise...)
+	}
+	if len(doc.Group) > 0 {
+		builder.WithGroup(doc.Group...)
+	}
+	if doc.PSK != "" {
+		builder.WithPSK(doc.PSK)
+	}
+	if doc.ExternalPSK != "" {
+		builder.WithExternalPSK(doc.ExternalPSK)
+	}
+	if doc.SAEPassword != "" {
+		builder.WithSAEPassword(doc.SAEPassword)
+	}
+	if doc.SAEPasswordID != "" {
+		builder.WithSAEPasswordID(doc.SAEPasswordID)
+	}
+	if doc.EapolFlags != nil {
+		builder.WithEapolFlag(*doc.EapolFlags)
+	}
+	if len(doc.EAP) > 0 {
+		methods := make([]eapMethod, 0, len(doc.EAP))
+		for i := range doc.EAP {
+			method, err := doc.EAP[i].Build()
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, method)
+		}
+		builder.WithEAPMethods(methods...)
+	}
+	if doc.Macsec != nil {
+		doc.Macsec.apply(builder)
+	}
+	if doc.Mesh != nil {
+		doc.Mesh.apply(builder)
+	}
+	if doc.AP != nil {
+		doc.AP.apply(builder)
+	}
+	if doc.Bgscan != nil {
+		switch doc.Bgscan.Module {
+		case "simple":
+			builder.WithBgscanSimple(doc.Bgscan.ShortInterval, doc.Bgscan.SignalThreshold, doc.Bgscan.LongInterval)
+		case "learn":
+			builder.WithBgscanLearn(doc.Bgscan.ShortInterval, doc.Bgscan.SignalThreshold, doc.Bgscan.LongInterval, doc.Bgscan.DBFile)
+		default:
+			return nil, fmt.Errorf("invalid value for bgscan module %q", doc.Bgscan.Module)
+		}
+	}
+	if doc.ProactiveKeyCaching != nil {
+		builder.WithProactiveKeyCaching(*doc.ProactiveKeyCaching)
+	}
+	return builder.Build()
+}
+
+func (doc *MacsecDocument) apply(builder networkBuilder) {
+	if doc.Policy != nil {
+		builder.WithMacsecPolicy(*doc.Policy)
+	}
+	if doc.IntegOnly != nil {
+		builder.WithMacsecIntegOnly(*doc.IntegOnly)
+	}
+	if doc.ReplayProtect != nil {
+		builder.WithMacsecReplayProtect(*doc.ReplayProtect)
+	}
+	if doc.ReplayWindow != nil {
+		builder.WithMacsecReplayWindow(*doc.ReplayWindow)
+	}
+	if doc.Offload != nil {
+		builder.WithMacsecOffload(*doc.Offload)
+	}
+	if doc.Port != nil {
+		builder.WithMacsecPort(*doc.Port)
+	}
+	if doc.MKACAK != "" || doc.MKACKN != "" {
+		builder.WithMKAPSK(doc.MKACAK, doc.MKACKN)
+	}
+	if doc.MKAPriority != nil {
+		builder.WithMKAPriority(*doc.MKAPriority)
+	}
+}
+
+func (doc *MeshDocument) apply(builder networkBuilder) {
+	if doc.Fwding != nil {
+		builder.WithMeshFwding(*doc.Fwding)
+	}
+	if doc.RSSIThreshold != nil {
+		builder.WithMeshRSSIThreshold(*doc.RSSIThreshold)
+	}
+	if doc.MaxRetries != nil {
+		builder.WithDot11MeshMaxRetries(*doc.MaxRetries)
+	}
+}
+
+func (doc *APDocument) apply(builder networkBuilder) {
+	if doc.HT40 != nil {
+		builder.WithHT40(*doc.HT40)
+	}
+	if doc.VHT != nil {
+		builder.WithVHT(*doc.VHT)
+	}
+	if doc.HE != nil {
+		builder.WithHE(*doc.HE)
+	}
+	if doc.BeaconInt != nil {
+		builder.WithBeaconInt(*doc.BeaconInt)
+	}
+	if doc.DTIMPeriod != nil {
+		builder.WithDTIMPeriod(*doc.DTIMPeriod)
+	}
+	if doc.MaxInactivity != nil {
+		builder.WithAPMaxInactivity(*doc.MaxInactivity)
+	}
+	if doc.WPSDisabled != nil {
+		builder.WithWPSDisabled(*doc.WPSDisabled)
+	}
+}
+
+// Build validates the document and returns the EAP method selected by Type
+func (doc *EAPDocument) Build() (eapMethod, error) {
+	if err := checkNotRedacted(doc.Password, doc.PasswordHash, doc.PrivateKeyPassword); err != nil {
+		return nil, err
+	}
+	switch doc.Type {
+	case "TLS":
+		builder := NewTLSBuilder()
+		builder.WithIdentity(doc.Identity).WithCaCertPath(doc.CaCert).WithClientCertPath(doc.ClientCert).
+			WithPrivateKeyPath(doc.PrivateKey).WithPrivateKeyPassword(doc.PrivateKeyPassword)
+		return builder.Build()
+	case "PEAP":
+		builder := NewPEAPBuilder()
+		builder.WithAnonymousIdentity(doc.AnonymousIdentity).WithIdentity(doc.Identity).WithPassword(doc.Password).
+			WithPasswordHash(doc.PasswordHash).WithExternalPassword(doc.ExternalPassword).WithCaCertPath(doc.CaCert).
+			WithInnerAuthType(doc.InnerAuth)
+		if doc.PEAPVersion != nil {
+			builder.WithPEAPVersion(*doc.PEAPVersion)
+		}
+		return builder.Build()
+	case "TTLS":
+		builder := NewTTLSBuilder()
+		builder.WithAnonymousIdentity(doc.AnonymousIdentity).WithIdentity(doc.Identity).WithPassword(doc.Password).
+			WithPasswordHash(doc.PasswordHash).WithExternalPassword(doc.ExternalPassword).WithCaCertPath(doc.CaCert).
+			WithInnerAuthType(doc.InnerAuth)
+		return builder.Build()
+	case "MD5":
+		builder := NewMd5EApBuilder()
+		builder.WithUsername(doc.Username).WithPassword(doc.Password).WithExternalPassword(doc.ExternalPassword)
+		return builder.Build()
+	case "":
+		return nil, errors.New("missing eap type")
+	}
+	return nil, fmt.Errorf("invalid value for eap type %q", doc.Type)
+}
+
+func (t *tlsMethod) toDocument() EAPDocument {
+	return EAPDocument{Type: t.GetEAPName(), Identity: t.identity, CaCert: t.caCertPath, ClientCert: t.clientCert,
+		PrivateKey: t.privateKey, PrivateKeyPassword: t.privateKeyPassword}
+}
+
+func (p *peapMethod) toDocument() EAPDocument {
+	doc := EAPDocument{Type: p.GetEAPName(), AnonymousIdentity: p.anonymousIdentity, Identity: p.identity,
+		Password: p.password, PasswordHash: p.passwordHash, ExternalPassword: p.passwordExternal, CaCert: p.caCertPath, InnerAuth: p.innerAuth}
+	if p.peapVersion != -1 {
+		peapVersion := p.peapVersion
+		doc.PEAPVersion = &peapVersion
+	}
+	return doc
+}
+
+func (t *ttlsMethod) toDocument() EAPDocument {
+	return EAPDocument{Type: t.GetEAPName(), AnonymousIdentity: t.anonymousIdentity, Identity: t.identity,
+		Password: t.password, PasswordHash: t.passwordHash, ExternalPassword: t.passwordExternal, CaCert: t.caCertPath, InnerAuth: t.innerAuth}
+}
+
+func (m *md5EapMethod) toDocument() EAPDocument {
+	return EAPDocument{Type: m.GetEAPName(), Username: m.username, Password: m.password, ExternalPassword: m.passwordExternal}
+}
+
+// ToDocument returns the serializable form of the credential
+func (c *Credential) ToDocument() CredentialDocument {
+	doc := CredentialDocument{
+		Realm:                     c.realm,
+		Username:                  c.username,
+		Password:                  c.password,
+		CaCert:                    c.caCertPath,
+		ClientCert:                c.clientCert,
+		PrivateKey:                c.privateKey,
+		PrivateKeyPassword:        c.privateKeyPassword,
+		IMSI:                      c.imsi,
+		Milenage:                  c.milenage,
+		Domain:                    c.domain,
+		RoamingConsortium:         c.roamingConsortium,
+		RequiredRoamingConsortium: c.requiredRoamingConsortium,
+		EAP:                       c.eap,
+		InnerAuth:                 c.innerAuth,
+		Priority:                  c.priority,
+		ProvisioningSP:            c.provisioningSP,
+	}
+	if c.spPriority != -1 {
+		spPriority := uint8(c.spPriority)
+		doc.SPPriority = &spPriority
+	}
+	if c.ocsp != -1 {
+		ocsp := c.ocsp
+		doc.OCSP = &ocsp
+	}
+	return doc
+}
+
+// Build validates the document and returns the credential it describes
+func (doc *CredentialDocument) Build() (*Credential, error) {
+	if err := checkNotRedacted(doc.Password, doc.PrivateKeyPassword); err != nil {
+		return nil, err
+	}
+	builder := NewCredentialBuilder()
+	builder.WithRealm(doc.Realm).WithUsername(doc.Username).WithPassword(doc.Password).WithCaCertPath(doc.CaCert).
+		WithClientCertPath(doc.ClientCert).WithPrivateKeyPath(doc.PrivateKey).WithPrivateKeyPassword(doc.PrivateKeyPassword).
+		WithIMSI(doc.IMSI).WithMilenage(doc.Milenage).WithRoamingConsortium(doc.RoamingConsortium).
+		WithRequiredRoamingConsortium(doc.RequiredRoamingConsortium).WithEAP(doc.EAP).WithInnerAuthType(doc.InnerAuth).
+		WithPriority(doc.Priority).WithProvisioningSP(doc.ProvisioningSP)
+	if len(doc.Domain) > 0 {
+		builder.WithDomain(doc.Domain...)
+	}
+	if doc.SPPriority != nil {
+		builder.WithSPPriority(*doc.SPPriority)
+	}
+	if doc.OCSP != nil {
+		builder.WithOCSP(*doc.OCSP)
+	}
+	return builder.Build()
+}
+
+func (wpa WPAInterface) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wpa.ToDocument().redacted())
+}
+
+func (wpa *WPAInterface) UnmarshalJSON(data []byte) error {
+	var doc InterfaceDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return wpa.fromDocument(doc)
+}
+
+func (wpa WPAInterface) MarshalYAML() (interface{}, error) {
+	return wpa.ToDocument().redacted(), nil
+}
+
+func (wpa *WPAInterface) UnmarshalYAML(value *yaml.Node) error {
+	var doc InterfaceDocument
+	if err := value.Decode(&doc); err != nil {
+		return err
+	}
+	return wpa.fromDocument(doc)
+}
+
+func (wpa *WPAInterface) fromDocument(doc InterfaceDocument) error {
+	built, err := doc.Build()
+	if err != nil {
+		return err
+	}
+	*wpa = *built
+	return nil
+}
+
+func (net Network) MarshalJSON() ([]byte, error) {
+	return json.Marshal(net.ToDocument().redacted())
+}
+
+func (net *Network) UnmarshalJSON(data []byte) error {
+	var doc NetworkDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return net.fromDocument(doc)
+}
+
+func (net Network) MarshalYAML() (interface{}, error) {
+	return net.ToDocument().redacted(), nil
+}
+
+func (net *Network) UnmarshalYAML(value *yaml.Node) error {
+	var doc NetworkDocument
+	if err := value.Decode(&doc); err != nil {
+		return err
+	}
+	return net.fromDocument(doc)
+}
+
+func (net *Network) fromDocument(doc NetworkDocument) error {
+	built, err := doc.Build()
+	if err != nil {
+		return err
+	}
+	*net = *built
+	return nil
+}
+
+func (c Credential) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToDocument().redacted())
+}
+
+func (c *Credential) UnmarshalJSON(data []byte) error {
+	var doc CredentialDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return c.fromDocument(doc)
+}
+
+func (c Credential) MarshalYAML() (interface{}, error) {
+	return c.ToDocument().redacted(), nil
+}
+
+func (c *Credential) UnmarshalYAML(value *yaml.Node) error {
+	var doc CredentialDocument
+	if err := value.Decode(&doc); err != nil {
+		return err
+	}
+	return c.fromDocument(doc)
+}
+
+func (c *Credential) fromDocument(doc CredentialDocument) error {
+	built, err := doc.Build()
+	if err != nil {
+		return err
+	}
+	*c = *built
+	return nil
+}
+
+// MarshalEAPMethod returns the JSON document of an EAP method including its type and secrets
+func MarshalEAPMethod(method eapMethod) ([]byte, error) {
+	return json.Marshal(method.toDocument())
+}
+
+// UnmarshalEAPMethod builds the EAP method described by a JSON document, the type field selects the method
+func UnmarshalEAPMethod(data []byte) (eapMethod, error) {
+	var doc EAPDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Build()
+}
@@ -0,0 +1,66 @@
+package wpaSuppDBusLib
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	peapBuilder := NewPEAPBuilder()
+	eapPEAP, _ := peapBuilder.WithIdentity("user_name").WithPassword("user_password").WithPEAPVersion(PEAPVersion0).WithInnerAuthType(InnerAuthMsChapV2).Build()
+	corp, _ := NewNetworkBuilder().WithSSID("corp").WithKeyManagement(WpaEAP).WithPriority(3).WithEAPMethods(eapPEAP).Build()
+	home, _ := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithPSK("very secret passphrase").WithBgscanSimple(30, -70, 3600).Build()
+	wpa, err := NewWpaInterfaceBuilder().WithCtrlInterface("/run/wpa_supplicant").WithApScan(ApScanOff).WithCountry("DE").WithNetwork(*corp, *home).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(wpa.ToDocument())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"eap":[{"type":"PEAP"`) {
+		t.Errorf("eap type missing from %s", data)
+	}
+	var fromJSON WPAInterface
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromJSON.ToConfigString() != wpa.ToConfigString() {
+		t.Errorf("json round trip changed the config:\n%s\n%s", fromJSON.ToConfigString(), wpa.ToConfigString())
+	}
+
+	data, err = yaml.Marshal(wpa.ToDocument())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromYAML WPAInterface
+	if err := yaml.Unmarshal(data, &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if fromYAML.ToConfigString() != wpa.ToConfigString() {
+		t.Errorf("yaml round trip changed the config:\n%s\n%s", fromYAML.ToConfigString(), wpa.ToConfigString())
+	}
+}
+
+func TestDocumentDecodeValidates(t *testing.T) {
+	invalid := map[string]string{
+		"unknown eap type":  `{"ssid":"corp","key_mgmt":["WPA-EAP"],"eap":[{"type":"FAST"}]}`,
+		"missing eap type":  `{"ssid":"corp","key_mgmt":["WPA-EAP"],"eap":[{"identity":"user"}]}`,
+		"psk without ssid":  `{"key_mgmt":["WPA-PSK"],"psk":"very secret passphrase"}`,
+		"bad bgscan module": `{"ssid":"home","key_mgmt":["NONE"],"bgscan":{"module":"fast"}}`,
+	}
+	for name, data := range invalid {
+		var net Network
+		if err := json.Unmarshal([]byte(data), &net); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	var wpa WPAInterface
+	if err := yaml.Unmarshal([]byte("country: germany\nnetworks:\n  - ssid: home\n    key_mgmt: [NONE]\n"), &wpa); err == nil {
+		t.Errorf("invalid country must be rejected")
+	}
+}
@@ -2,7 +2,10 @@ package wpaSuppDBusLib
 
 type eapMethod interface {
 	ToConfigString() string
+	ToRedactedConfigString() string
 	GetEAPName() string
+	configFields() []configField
+	toDocument() EAPDocument
 }
 
 type eapBuilder interface {
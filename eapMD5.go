@@ -2,12 +2,12 @@ package wpaSuppDBusLib
 
 import (
 	"errors"
-	"fmt"
 )
 
 type md5EapMethod struct {
-	username string `json:"username"`
-	password string `json:"password"`
+	username         string
+	password         string
+	passwordExternal string
 }
 
 func (m *md5EapMethod) GetEAPName() string {
@@ -15,12 +15,20 @@ func (m *md5EapMethod) GetEAPName() string {
 }
 
 func (m *md5EapMethod) ToConfigString() string {
-	return fmt.Sprintf("  username=\"%s\"\n  password=\"%s\"\n", m.username, m.password)
+	return renderConfigFields("  ", m.configFields())
+}
+
+func (m *md5EapMethod) configFields() []configField {
+	if m.passwordExternal != "" {
+		return []configField{quotedField("username", m.username), rawField("password", "ext:"+m.passwordExternal)}
+	}
+	return []configField{quotedField("username", m.username), quotedField("password", m.password)}
 }
 
 type MD5EAPBuilder struct {
-	username string `json:"username"`
-	password string `json:"password"`
+	username         string
+	password         string
+	passwordExternal string
 }
 
 func NewMd5EApBuilder() MD5EAPBuilder {
@@ -37,14 +45,22 @@ func (b *MD5EAPBuilder) WithPassword(password string) *MD5EAPBuilder {
 	return b
 }
 
+// WithExternalPassword references the password by name in the ext_password_backend of the
+// interface instead of writing it to the config (password=ext:<name>)
+func (b *MD5EAPBuilder) WithExternalPassword(name string) *MD5EAPBuilder {
+	b.passwordExternal = name
+	return b
+}
+
 func (b *MD5EAPBuilder) Build() (eapMethod, error) {
 	err := b.validate()
 	if err != nil {
 		return nil, err
 	}
 	md5 := md5EapMethod{
-		username: b.username,
-		password: b.password,
+		username:         b.username,
+		password:         b.password,
+		passwordExternal: b.passwordExternal,
 	}
 	return &md5, nil
 }
@@ -53,8 +69,11 @@ func (b *MD5EAPBuilder) validate() error {
 	if b.username == "" {
 		return errors.New("invalid username")
 	}
-	if b.password == "" {
-		return errors.New("invalid password")
+	if countSet(b.password, b.passwordExternal) != 1 {
+		return errors.New("invalid password. set either a password or an external password")
+	}
+	if b.passwordExternal != "" && !isExternalName(b.passwordExternal) {
+		return errors.New("invalid value for external password name")
 	}
 	return nil
 }
@@ -3,7 +3,6 @@ package wpaSuppDBusLib
 import (
 	"errors"
 	"fmt"
-	"strings"
 )
 
 type PEAPVersion int
@@ -17,12 +16,14 @@ var peapVersionSlice = []PEAPVersion{PEAPVersion0, PEAPVersion1}
 var allowedInnerAuthTypes = []innerAuthType{InnerAuthMsChapV2, InnerAuthMD5, InnerAuthGTC}
 
 type peapMethod struct {
-	anonymousIdentity string        `json:"anonymousIdentity,omitempty"`
-	identity          string        `json:"identity"`
-	password          string        `json:"password"`
-	peapVersion       PEAPVersion   `json:"peaplabel,omitempty"`
-	caCertPath        string        `json:"ca_cert,omitempty"`
-	innerAuth         innerAuthType `json:"phase2"`
+	anonymousIdentity string
+	identity          string
+	password          string
+	passwordHash      string
+	passwordExternal  string
+	peapVersion       PEAPVersion
+	caCertPath        string
+	innerAuth         innerAuthType
 }
 
 func (p *peapMethod) GetEAPName() string {
@@ -30,35 +31,47 @@ func (p *peapMethod) GetEAPName() string {
 }
 
 func (p *peapMethod) ToConfigString() string {
-	builder := strings.Builder{}
+	return renderConfigFields("  ", p.configFields())
+}
+
+func (p *peapMethod) configFields() []configField {
+	fields := make([]configField, 0)
 	if p.anonymousIdentity != "" {
-		builder.WriteString(fmt.Sprintf("  anonymous_identity=\"%s\"\n", p.anonymousIdentity))
+		fields = append(fields, quotedField("anonymous_identity", p.anonymousIdentity))
 	}
 	if p.identity != "" {
-		builder.WriteString(fmt.Sprintf("  identity=\"%s\"\n", p.identity))
+		fields = append(fields, quotedField("identity", p.identity))
 	}
 	if p.password != "" {
-		builder.WriteString(fmt.Sprintf("  password=\"%s\"\n", p.password))
+		fields = append(fields, quotedField("password", p.password))
+	}
+	if p.passwordHash != "" {
+		fields = append(fields, rawField("password", "hash:"+p.passwordHash))
+	}
+	if p.passwordExternal != "" {
+		fields = append(fields, rawField("password", "ext:"+p.passwordExternal))
 	}
 	if p.peapVersion != -1 {
-		builder.WriteString(fmt.Sprintf("  phase1=\"peaplabel=%d\"", p.peapVersion))
+		fields = append(fields, quotedField("phase1", fmt.Sprintf("peaplabel=%d", p.peapVersion)))
 	}
 	if p.caCertPath != "" {
-		builder.WriteString(fmt.Sprintf("  ca_cert=\"%s\"\n", p.caCertPath))
+		fields = append(fields, quotedField("ca_cert", p.caCertPath))
 	}
 	if p.innerAuth != "" {
-		builder.WriteString(fmt.Sprintf("  phase2=\"auth=%s\"\n", p.innerAuth))
+		fields = append(fields, quotedField("phase2", fmt.Sprintf("auth=%s", p.innerAuth)))
 	}
-	return builder.String()
+	return fields
 }
 
 type PEAPBuilder struct {
-	anonymousIdentity string        `json:"anonymousIdentity,omitempty"`
-	identity          string        `json:"identity"`
-	password          string        `json:"password"`
-	peapVersion       PEAPVersion   `json:"peaplabel,omitempty"`
-	caCertPath        string        `json:"ca_cert,omitempty"`
-	innerAuth         innerAuthType `json:"phase2"`
+	anonymousIdentity string
+	identity          string
+	password          string
+	passwordHash      string
+	passwordExternal  string
+	peapVersion       PEAPVersion
+	caCertPath        string
+	innerAuth         innerAuthType
 }
 
 func NewPEAPBuilder() PEAPBuilder {
@@ -82,6 +95,21 @@ func (b *PEAPBuilder) WithPassword(password string) *PEAPBuilder {
 	return b
 }
 
+// WithPasswordHash sets the NtPasswordHash (16 bytes as 32 hex digits) instead of the plaintext
+// password, it requires MSCHAPV2 as inner authentication. wpa_supplicant only accepts hashes from
+// config files, AddNetwork refuses networks using them.
+func (b *PEAPBuilder) WithPasswordHash(ntHash string) *PEAPBuilder {
+	b.passwordHash = ntHash
+	return b
+}
+
+// WithExternalPassword references the password by name in the ext_password_backend of the
+// interface instead of writing it to the config (password=ext:<name>)
+func (b *PEAPBuilder) WithExternalPassword(name string) *PEAPBuilder {
+	b.passwordExternal = name
+	return b
+}
+
 func (b *PEAPBuilder) WithPEAPVersion(peapVersion PEAPVersion) *PEAPBuilder {
 	b.peapVersion = peapVersion
 	return b
@@ -106,6 +134,8 @@ func (b *PEAPBuilder) Build() (eapMethod, error) {
 		anonymousIdentity: b.anonymousIdentity,
 		identity:          b.identity,
 		password:          b.password,
+		passwordHash:      b.passwordHash,
+		passwordExternal:  b.passwordExternal,
 		peapVersion:       b.peapVersion,
 		caCertPath:        b.caCertPath,
 		innerAuth:         b.innerAuth,
@@ -117,8 +147,17 @@ func (b *PEAPBuilder) validate() error {
 	if b.identity == "" {
 		return errors.New("invalid identity")
 	}
-	if b.password == "" {
-		return errors.New("invalid password")
+	if countSet(b.password, b.passwordHash, b.passwordExternal) != 1 {
+		return errors.New("invalid password. set either a password, a password hash or an external password")
+	}
+	if b.passwordExternal != "" && !isExternalName(b.passwordExternal) {
+		return errors.New("invalid value for external password name")
+	}
+	if b.passwordHash != "" && !isNtPasswordHash(b.passwordHash) {
+		return errors.New("invalid password hash. must be 32 hex digits")
+	}
+	if b.passwordHash != "" && b.innerAuth != InnerAuthMsChapV2 {
+		return errors.New("password hash requires inner auth MSCHAPV2")
 	}
 	if b.innerAuth == "" {
 		return errors.New("invalid inner auth (empty)")
@@ -2,16 +2,14 @@ package wpaSuppDBusLib
 
 import (
 	"errors"
-	"fmt"
-	"strings"
 )
 
 type tlsMethod struct {
-	identity           string `json:"identity"`
-	caCertPath         string `json:"ca_cert,omitempty"`
-	clientCert         string `json:"client_cert"`
-	privateKey         string `json:"private_key"`
-	privateKeyPassword string `json:"private_key_passwd,omitempty"`
+	identity           string
+	caCertPath         string
+	clientCert         string
+	privateKey         string
+	privateKeyPassword string
 }
 
 func (t *tlsMethod) GetEAPName() string {
@@ -19,31 +17,35 @@ func (t *tlsMethod) GetEAPName() string {
 }
 
 func (t *tlsMethod) ToConfigString() string {
-	builder := strings.Builder{}
+	return renderConfigFields("  ", t.configFields())
+}
+
+func (t *tlsMethod) configFields() []configField {
+	fields := make([]configField, 0)
 	if t.identity != "" {
-		builder.WriteString(fmt.Sprintf("  identity=\"%s\"\n", t.identity))
+		fields = append(fields, quotedField("identity", t.identity))
 	}
 	if t.caCertPath != "" {
-		builder.WriteString(fmt.Sprintf("  ca_cert=\"%s\"\n", t.caCertPath))
+		fields = append(fields, quotedField("ca_cert", t.caCertPath))
 	}
 	if t.clientCert != "" {
-		builder.WriteString(fmt.Sprintf("  client_cert=\"%s\"\n", t.clientCert))
+		fields = append(fields, quotedField("client_cert", t.clientCert))
 	}
 	if t.privateKey != "" {
-		builder.WriteString(fmt.Sprintf("  private_key=\"%s\"\n", t.privateKey))
+		fields = append(fields, quotedField("private_key", t.privateKey))
 	}
 	if t.privateKeyPassword != "" {
-		builder.WriteString(fmt.Sprintf("  private_key_passwd=\"%s\"\n", t.privateKeyPassword))
+		fields = append(fields, quotedField("private_key_passwd", t.privateKeyPassword))
 	}
-	return builder.String()
+	return fields
 }
 
 type TLSBuilder struct {
-	identity           string `json:"identity"`
-	caCertPath         string `json:"ca_cert,omitempty"`
-	clientCert         string `json:"client_cert"`
-	privateKey         string `json:"private_key"`
-	privateKeyPassword string `json:"private_key_passwd,omitempty"`
+	identity           string
+	caCertPath         string
+	clientCert         string
+	privateKey         string
+	privateKeyPassword string
 }
 
 func NewTLSBuilder() TLSBuilder {
@@ -3,17 +3,18 @@ package wpaSuppDBusLib
 import (
 	"errors"
 	"fmt"
-	"strings"
 )
 
 var allowedTTLSInnerAuthTypes = []innerAuthType{InnerAuthPAP, InnerAuthMsChap, InnerAuthMsChapV2, InnerAuthChap, InnerAuthMD5, InnerAuthGTC}
 
 type ttlsMethod struct {
-	anonymousIdentity string        `json:"anonymousIdentity,omitempty"`
-	identity          string        `json:"identity"`
-	caCertPath        string        `json:"ca_cert,omitempty"`
-	password          string        `json:"password,omitempty"`
-	innerAuth         innerAuthType `json:"phase2"`
+	anonymousIdentity string
+	identity          string
+	caCertPath        string
+	password          string
+	passwordHash      string
+	passwordExternal  string
+	innerAuth         innerAuthType
 }
 
 func (t *ttlsMethod) GetEAPName() string {
@@ -21,31 +22,43 @@ func (t *ttlsMethod) GetEAPName() string {
 }
 
 func (t *ttlsMethod) ToConfigString() string {
-	builder := strings.Builder{}
+	return renderConfigFields("  ", t.configFields())
+}
+
+func (t *ttlsMethod) configFields() []configField {
+	fields := make([]configField, 0)
 	if t.anonymousIdentity != "" {
-		builder.WriteString(fmt.Sprintf("   anonymous_identity=\"%s\"\n", t.anonymousIdentity))
+		fields = append(fields, quotedField("anonymous_identity", t.anonymousIdentity))
 	}
 	if t.identity != "" {
-		builder.WriteString(fmt.Sprintf("  identity=\"%s\"\n", t.identity))
+		fields = append(fields, quotedField("identity", t.identity))
 	}
 	if t.caCertPath != "" {
-		builder.WriteString(fmt.Sprintf("  ca_cert=\"%s\"\n", t.caCertPath))
+		fields = append(fields, quotedField("ca_cert", t.caCertPath))
 	}
 	if t.password != "" {
-		builder.WriteString(fmt.Sprintf("  password=\"%s\"\n", t.password))
+		fields = append(fields, quotedField("password", t.password))
+	}
+	if t.passwordHash != "" {
+		fields = append(fields, rawField("password", "hash:"+t.passwordHash))
+	}
+	if t.passwordExternal != "" {
+		fields = append(fields, rawField("password", "ext:"+t.passwordExternal))
 	}
 	if t.innerAuth != "" {
-		builder.WriteString(fmt.Sprintf("  phase2=\"auth=%s\"\n", t.innerAuth))
+		fields = append(fields, quotedField("phase2", fmt.Sprintf("auth=%s", t.innerAuth)))
 	}
-	return builder.String()
+	return fields
 }
 
 type TTLSBuilder struct {
-	anonymousIdentity string        `json:"anonymousIdentity,omitempty"`
-	identity          string        `json:"identity"`
-	caCertPath        string        `json:"ca_cert,omitempty"`
-	password          string        `json:"password"`
-	innerAuth         innerAuthType `json:"phase2"`
+	anonymousIdentity string
+	identity          string
+	caCertPath        string
+	password          string
+	passwordHash      string
+	passwordExternal  string
+	innerAuth         innerAuthType
 }
 
 func NewTTLSBuilder() TTLSBuilder {
@@ -72,6 +85,21 @@ func (t *TTLSBuilder) WithPassword(password string) *TTLSBuilder {
 	return t
 }
 
+// WithPasswordHash sets the NtPasswordHash (16 bytes as 32 hex digits) instead of the plaintext
+// password, it requires MSCHAP or MSCHAPV2 as inner authentication. wpa_supplicant only accepts
+// hashes from config files, AddNetwork refuses networks using them.
+func (t *TTLSBuilder) WithPasswordHash(ntHash string) *TTLSBuilder {
+	t.passwordHash = ntHash
+	return t
+}
+
+// WithExternalPassword references the password by name in the ext_password_backend of the
+// interface instead of writing it to the config (password=ext:<name>)
+func (t *TTLSBuilder) WithExternalPassword(name string) *TTLSBuilder {
+	t.passwordExternal = name
+	return t
+}
+
 func (t *TTLSBuilder) WithInnerAuthType(innerAuthType innerAuthType) *TTLSBuilder {
 	t.innerAuth = innerAuthType
 	return t
@@ -87,6 +115,8 @@ func (t *TTLSBuilder) Build() (eapMethod, error) {
 		identity:          t.identity,
 		caCertPath:        t.caCertPath,
 		password:          t.password,
+		passwordHash:      t.passwordHash,
+		passwordExternal:  t.passwordExternal,
 		innerAuth:         t.innerAuth,
 	}
 	return &tls, nil
@@ -96,8 +126,17 @@ func (t *TTLSBuilder) validate() error {
 	if t.identity == "" {
 		return errors.New("invalid identity")
 	}
-	if t.password == "" {
-		return errors.New("invalid password")
+	if countSet(t.password, t.passwordHash, t.passwordExternal) != 1 {
+		return errors.New("invalid password. set either a password, a password hash or an external password")
+	}
+	if t.passwordExternal != "" && !isExternalName(t.passwordExternal) {
+		return errors.New("invalid value for external password name")
+	}
+	if t.passwordHash != "" && !isNtPasswordHash(t.passwordHash) {
+		return errors.New("invalid password hash. must be 32 hex digits")
+	}
+	if t.passwordHash != "" && t.innerAuth != InnerAuthMsChap && t.innerAuth != InnerAuthMsChapV2 {
+		return errors.New("password hash requires inner auth MSCHAP or MSCHAPV2")
 	}
 	if t.innerAuth == "" {
 		return errors.New("invalid inner auth (empty)")
@@ -0,0 +1,571 @@
+package wpaSuppDBusLib
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	estCaCertsPath      = "/cacerts"
+	estSimpleEnroll     = "/simpleenroll"
+	estSimpleReenroll   = "/simplereenroll"
+	estCaCertFileName   = "ca.pem"
+	estClientCertName   = "client.pem"
+	estPrivateKeyName   = "client.key"
+	estMaxResponseBytes = 1 << 20
+)
+
+var oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+var defaultESTTimeout = 30 * time.Second
+
+// ESTClient enrolls EAP-TLS client certificates against an EST (RFC 7030) server and stores
+// the issued certificate and key under its storage directory.
+type ESTClient struct {
+	serverURL     string
+	trustAnchors  *x509.CertPool
+	username      string
+	password      string
+	bootstrapCert *tls.Certificate
+	identity      string
+	storageDir    string
+	eapCaCertPath string
+	timeout       time.Duration
+	logger        Logger
+	transport     *http.Transport
+	mutex         sync.Mutex
+	clientCert    *tls.Certificate
+}
+
+type estClientBuilder interface {
+	WithServerURL(serverURL string) estClientBuilder
+	WithTrustAnchors(pool *x509.CertPool) estClientBuilder
+	WithTrustAnchorPath(caCertPath string) estClientBuilder
+	WithBasicAuth(username, password string) estClientBuilder
+	WithBootstrapCertificate(certPath, keyPath string) estClientBuilder
+	WithIdentity(identity string) estClientBuilder
+	WithStorageDir(dir string) estClientBuilder
+	WithEAPCaCertPath(caCertPath string) estClientBuilder
+	WithTimeout(timeout time.Duration) estClientBuilder
+	WithLogger(logger Logger) estClientBuilder
+	Build() (*ESTClient, error)
+}
+
+type ESTClientBuilder struct {
+	serverURL         string
+	trustAnchors      *x509.CertPool
+	trustAnchorPath   string
+	username          string
+	password          string
+	bootstrapCertPath string
+	bootstrapKeyPath  string
+	identity          string
+	storageDir        string
+	eapCaCertPath     string
+	timeout           time.Duration
+	logger            Logger
+}
+
+func NewESTClientBuilder() estClientBuilder {
+	builder := ESTClientBuilder{
+		timeout: defaultESTTimeout,
+	}
+	return &builder
+}
+
+// WithServerURL sets the EST base URL, e.g. https://est.example.com/.well-known/est
+// or https://est.example.com/.well-known/est/<label>
+func (b *ESTClientBuilder) WithServerURL(serverURL string) estClientBuilder {
+	b.serverURL = strings.TrimSuffix(serverURL, "/")
+	return b
+}
+
+// WithTrustAnchors sets the explicit trust anchors used to authenticate the EST server
+func (b *ESTClientBuilder) WithTrustAnchors(pool *x509.CertPool) estClientBuilder {
+	b.trustAnchors = pool
+	return b
+}
+
+// WithTrustAnchorPath loads the explicit trust anchors used to authenticate the EST server from a PEM file
+func (b *ESTClientBuilder) WithTrustAnchorPath(caCertPath string) estClientBuilder {
+	b.trustAnchorPath = caCertPath
+	return b
+}
+
+// WithBasicAuth sets the bootstrap HTTP basic credentials used for the initial enrollment
+func (b *ESTClientBuilder) WithBasicAuth(username, password string) estClientBuilder {
+	b.username = username
+	b.password = password
+	return b
+}
+
+// WithBootstrapCertificate sets a bootstrap TLS client certificate (e.g. a manufacturer certificate)
+// used for the initial enrollment
+func (b *ESTClientBuilder) WithBootstrapCertificate(certPath, keyPath string) estClientBuilder {
+	b.bootstrapCertPath = certPath
+	b.bootstrapKeyPath = keyPath
+	return b
+}
+
+// WithIdentity sets the device identity. It is used as the CSR subject and as the EAP-TLS identity.
+func (b *ESTClientBuilder) WithIdentity(identity string) estClientBuilder {
+	b.identity = identity
+	return b
+}
+
+// WithStorageDir sets the directory where the CA bundle, client certificate and private key are stored
+func (b *ESTClientBuilder) WithStorageDir(dir string) estClientBuilder {
+	b.storageDir = dir
+	return b
+}
+
+// WithEAPCaCertPath sets the CA used by the supplicant to validate the authentication server.
+// If not set the CA certificates distributed by the EST server are used.
+func (b *ESTClientBuilder) WithEAPCaCertPath(caCertPath string) estClientBuilder {
+	b.eapCaCertPath = caCertPath
+	return b
+}
+
+func (b *ESTClientBuilder) WithTimeout(timeout time.Duration) estClientBuilder {
+	b.timeout = timeout
+	return b
+}
+
+func (b *ESTClientBuilder) WithLogger(logger Logger) estClientBuilder {
+	b.logger = logger
+	return b
+}
+
+func (b *ESTClientBuilder) Build() (*ESTClient, error) {
+	err := b.validate()
+	if err != nil {
+		return nil, err
+	}
+	trustAnchors := b.trustAnchors
+	if b.trustAnchorPath != "" {
+		content, err := os.ReadFile(b.trustAnchorPath)
+		if err != nil {
+			return nil, err
+		}
+		trustAnchors = x509.NewCertPool()
+		if !trustAnchors.AppendCertsFromPEM(content) {
+			return nil, errors.New("no certificates found in trust anchor file")
+		}
+	}
+	var bootstrapCert *tls.Certificate
+	if b.bootstrapCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(b.bootstrapCertPath, b.bootstrapKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		bootstrapCert = &cert
+	}
+	logger := b.logger
+	if logger == nil {
+		logger = newDefaultLogger()
+	}
+	client := ESTClient{
+		serverURL:     b.serverURL,
+		trustAnchors:  trustAnchors,
+		username:      b.username,
+		password:      b.password,
+		bootstrapCert: bootstrapCert,
+		identity:      b.identity,
+		storageDir:    b.storageDir,
+		eapCaCertPath: b.eapCaCertPath,
+		timeout:       b.timeout,
+		logger:        logger,
+	}
+	client.transport = &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:              trustAnchors,
+		MinVersion:           tls.VersionTLS12,
+		GetClientCertificate: client.getClientCertificate,
+	}}
+	return &client, nil
+}
+
+func (b *ESTClientBuilder) validate() error {
+	serverURL, err := url.Parse(b.serverURL)
+	if err != nil || serverURL.Host == "" {
+		return errors.New("invalid value for server url")
+	}
+	if serverURL.Scheme != "https" {
+		return errors.New("invalid value for server url. EST requires https")
+	}
+	if b.identity == "" {
+		return errors.New("invalid value for identity")
+	}
+	if b.storageDir == "" {
+		return errors.New("invalid value for storage dir")
+	}
+	if (b.bootstrapCertPath == "") != (b.bootstrapKeyPath == "") {
+		return errors.New("bootstrap certificate and key must be specified together")
+	}
+	if b.timeout <= 0 {
+		return errors.New("invalid value for timeout")
+	}
+	return nil
+}
+
+// ClientCertPath returns where the issued client certificate is stored
+func (c *ESTClient) ClientCertPath() string {
+	return path.Join(c.storageDir, estClientCertName)
+}
+
+// PrivateKeyPath returns where the private key of the issued certificate is stored
+func (c *ESTClient) PrivateKeyPath() string {
+	return path.Join(c.storageDir, estPrivateKeyName)
+}
+
+// CaCertPath returns where the CA certificates distributed by the EST server are stored
+func (c *ESTClient) CaCertPath() string {
+	return path.Join(c.storageDir, estCaCertFileName)
+}
+
+// CACerts fetches the current CA certificates from the EST server (/cacerts)
+func (c *ESTClient) CACerts(ctx context.Context) ([]*x509.Certificate, error) {
+	body, err := c.do(ctx, http.MethodGet, estCaCertsPath, nil, c.bootstrapCert)
+	if err != nil {
+		return nil, err
+	}
+	return parseCertsOnlyPKCS7(body)
+}
+
+// Enroll generates a new key, requests a certificate with /simpleenroll using the bootstrap
+// credentials and returns a TLS eap method referencing the stored certificate and key.
+func (c *ESTClient) Enroll(ctx context.Context) (eapMethod, error) {
+	return c.enroll(ctx, estSimpleEnroll, c.bootstrapCert)
+}
+
+// Reenroll generates a new key and renews the current certificate with /simplereenroll,
+// authenticating with the certificate being renewed. The files are replaced in place.
+func (c *ESTClient) Reenroll(ctx context.Context) (eapMethod, error) {
+	current, err := tls.LoadX509KeyPair(c.ClientCertPath(), c.PrivateKeyPath())
+	if err != nil {
+		return nil, err
+	}
+	return c.enroll(ctx, estSimpleReenroll, &current)
+}
+
+// NeedsReenrollment reports whether the stored certificate is missing or expires within renewBefore
+func (c *ESTClient) NeedsReenrollment(renewBefore time.Duration) (bool, error) {
+	notAfter, err := readCertificateExpiry(c.ClientCertPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	return time.Until(notAfter) <= renewBefore, nil
+}
+
+// RunReenrollment checks the stored certificate every checkInterval and re-enrolls it once it
+// expires within renewBefore. If no certificate is stored yet a fresh enrollment is made.
+// It returns when ctx is cancelled, or right away if checkInterval is not positive.
+func (c *ESTClient) RunReenrollment(ctx context.Context, renewBefore, checkInterval time.Duration) error {
+	if checkInterval <= 0 {
+		return errors.New("invalid value for check interval")
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		c.renewIfNeeded(ctx, renewBefore)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *ESTClient) renewIfNeeded(ctx context.Context, renewBefore time.Duration) {
+	needed, err := c.NeedsReenrollment(renewBefore)
+	if err != nil {
+		c.logger.Error(err)
+		return
+	}
+	if !needed {
+		return
+	}
+	if _, err = os.Stat(c.ClientCertPath()); err != nil {
+		_, err = c.Enroll(ctx)
+	} else {
+		_, err = c.Reenroll(ctx)
+	}
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("est enrollment for %s failed: %v", c.identity, err))
+		return
+	}
+	c.logger.Info(fmt.Sprintf("est enrollment for %s renewed", c.identity))
+}
+
+func (c *ESTClient) enroll(ctx context.Context, operation string, clientCert *tls.Certificate) (eapMethod, error) {
+	caCerts, err := c.CACerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: c.identity},
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.do(ctx, http.MethodPost, operation, csr, clientCert)
+	if err != nil {
+		return nil, err
+	}
+	issued, err := parseCertsOnlyPKCS7(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(issued) == 0 {
+		return nil, errors.New("est server returned no certificate")
+	}
+	if !publicKeyMatches(issued[0], &key.PublicKey) {
+		return nil, errors.New("est server returned a certificate for a different key")
+	}
+	err = c.store(caCerts, issued, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.tlsMethod()
+}
+
+func (c *ESTClient) tlsMethod() (eapMethod, error) {
+	caCertPath := c.eapCaCertPath
+	if caCertPath == "" {
+		caCertPath = c.CaCertPath()
+	}
+	tlsBuilder := NewTLSBuilder()
+	return tlsBuilder.
+		WithIdentity(c.identity).
+		WithCaCertPath(caCertPath).
+		WithClientCertPath(c.ClientCertPath()).
+		WithPrivateKeyPath(c.PrivateKeyPath()).Build()
+}
+
+// renameFile moves the stored files into place, tests replace it to fail a rename
+var renameFile = os.Rename
+
+// store writes the CA bundle, certificate and key to temporary files first and only renames them
+// into place once all of them are written, so a failed write leaves the previous files untouched.
+// The renames are not atomic as a group, a reader may briefly see the new key next to the previous
+// certificate. When a rename fails the files renamed before are restored.
+func (c *ESTClient) store(caCerts, issued []*x509.Certificate, key *ecdsa.PrivateKey) error {
+	err := os.MkdirAll(c.storageDir, 0700)
+	if err != nil {
+		return err
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		fileName string
+		content  []byte
+		perm     os.FileMode
+	}{
+		{c.PrivateKeyPath(), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600},
+		{c.ClientCertPath(), encodeCertificates(issued), 0644},
+		{c.CaCertPath(), encodeCertificates(caCerts), 0644},
+	}
+	tmpNames := make([]string, 0, len(files))
+	backups := make([]string, len(files))
+	defer func() {
+		for _, tmpName := range append(tmpNames, backups...) {
+			if tmpName != "" {
+				_ = os.Remove(tmpName)
+			}
+		}
+	}()
+	for i, file := range files {
+		tmpName, err := writeTempFile(file.fileName, file.content, file.perm)
+		if err != nil {
+			return err
+		}
+		tmpNames = append(tmpNames, tmpName)
+		previous, err := os.ReadFile(file.fileName)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		backups[i], err = writeTempFile(file.fileName, previous, file.perm)
+		if err != nil {
+			return err
+		}
+	}
+	for i, file := range files {
+		err = renameFile(tmpNames[i], file.fileName)
+		if err == nil {
+			continue
+		}
+		errs := MultiError{err}
+		for j := i - 1; j >= 0; j-- {
+			var restoreErr error
+			if backups[j] == "" {
+				restoreErr = os.Remove(files[j].fileName)
+			} else {
+				restoreErr = renameFile(backups[j], files[j].fileName)
+			}
+			if restoreErr != nil {
+				errs = append(errs, restoreErr)
+			}
+		}
+		return errs.errorOrNil()
+	}
+	return nil
+}
+
+// getClientCertificate presents the certificate of the current request. Connections are only
+// reused while the certificate stays the same, see do.
+func (c *ESTClient) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if c.clientCert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return c.clientCert, nil
+}
+
+func (c *ESTClient) do(ctx context.Context, method, operation string, der []byte, clientCert *tls.Certificate) ([]byte, error) {
+	var reqBody io.Reader
+	if der != nil {
+		reqBody = strings.NewReader(base64.StdEncoding.EncodeToString(der))
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if clientCert != c.clientCert {
+		// connections authenticated with the previous certificate must not be reused
+		c.transport.CloseIdleConnections()
+		c.clientCert = clientCert
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.serverURL+operation, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if der != nil {
+		req.Header.Set("Content-Type", "application/pkcs10")
+		req.Header.Set("Content-Transfer-Encoding", "base64")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	client := http.Client{Transport: c.transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, estMaxResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, fmt.Errorf("est request pending, retry after %s", resp.Header.Get("Retry-After"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("est %s failed with status %s: %s", operation, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return decodeESTBase64(respBody)
+}
+
+func decodeESTBase64(body []byte) ([]byte, error) {
+	compact := bytes.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, body)
+	return base64.StdEncoding.DecodeString(string(compact))
+}
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// parseCertsOnlyPKCS7 extracts the certificates of a degenerate (certs-only) PKCS#7 SignedData
+func parseCertsOnlyPKCS7(der []byte) ([]*x509.Certificate, error) {
+	var contentInfo pkcs7ContentInfo
+	_, err := asn1.Unmarshal(der, &contentInfo)
+	if err != nil {
+		return nil, err
+	}
+	if !contentInfo.ContentType.Equal(oidPKCS7SignedData) {
+		return nil, errors.New("unexpected pkcs7 content type")
+	}
+	var signedData pkcs7SignedData
+	_, err = asn1.Unmarshal(contentInfo.Content.Bytes, &signedData)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificates(signedData.Certificates.Bytes)
+}
+
+func encodeCertificates(certs []*x509.Certificate) []byte {
+	buffer := bytes.Buffer{}
+	for _, cert := range certs {
+		_ = pem.Encode(&buffer, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buffer.Bytes()
+}
+
+func publicKeyMatches(cert *x509.Certificate, key *ecdsa.PublicKey) bool {
+	certKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	return ok && certKey.Equal(key)
+}
+
+// writeTempFile writes content to a temporary file next to fileName and returns its name. Renaming
+// it into place lets readers, including the supplicant, never observe a partially written file.
+func writeTempFile(fileName string, content []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(path.Dir(fileName), "."+path.Base(fileName)+".*")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
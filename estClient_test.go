@@ -0,0 +1,274 @@
+package wpaSuppDBusLib
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var oidPKCS7Data = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+
+// encodeCertsOnlyPKCS7 builds a degenerate (certs-only) PKCS#7 SignedData
+func encodeCertsOnlyPKCS7(certs []*x509.Certificate) ([]byte, error) {
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+	innerContentInfo, err := asn1.Marshal(struct{ ContentType asn1.ObjectIdentifier }{oidPKCS7Data})
+	if err != nil {
+		return nil, err
+	}
+	emptySet := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true}
+	signedData, err := asn1.Marshal(pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: emptySet,
+		ContentInfo:      asn1.RawValue{FullBytes: innerContentInfo},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      emptySet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidPKCS7SignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData},
+	})
+}
+
+// estStandIn is a minimal in-process EST server issuing certificates from a throwaway CA
+type estStandIn struct {
+	t           *testing.T
+	caCert      *x509.Certificate
+	caKey       *ecdsa.PrivateKey
+	validity    time.Duration
+	serial      int64
+	reenrolled  int
+	lastSubject string
+}
+
+func newESTStandIn(t *testing.T, validity time.Duration) *estStandIn {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "est test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(der)
+	return &estStandIn{t: t, caCert: caCert, caKey: caKey, validity: validity, serial: 1}
+}
+
+func (s *estStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/est/cacerts":
+		s.writeCerts(w, []*x509.Certificate{s.caCert})
+	case "/.well-known/est/simpleenroll":
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "bootstrap" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.issue(w, r)
+	case "/.well-known/est/simplereenroll":
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, err := r.TLS.PeerCertificates[0].Verify(x509.VerifyOptions{Roots: s.pool(), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.reenrolled++
+		s.issue(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *estStandIn) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.caCert)
+	return pool
+}
+
+func (s *estStandIn) issue(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	der, err := decodeESTBase64(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil || csr.CheckSignature() != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.serial++
+	s.lastSubject = csr.Subject.CommonName
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(s.serial),
+		Subject:      csr.Subject,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(s.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, &template, s.caCert, csr.PublicKey, s.caKey)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	cert, _ := x509.ParseCertificate(certDer)
+	s.writeCerts(w, []*x509.Certificate{cert})
+}
+
+func (s *estStandIn) writeCerts(w http.ResponseWriter, certs []*x509.Certificate) {
+	der, err := encodeCertsOnlyPKCS7(certs)
+	if err != nil {
+		s.t.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pkcs7-mime; smime-type=certs-only")
+	w.Header().Set("Content-Transfer-Encoding", "base64")
+	_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(der)))
+}
+
+func startESTStandIn(t *testing.T, standIn *estStandIn) (*httptest.Server, *x509.CertPool) {
+	server := httptest.NewUnstartedServer(standIn)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	trustAnchors := x509.NewCertPool()
+	trustAnchors.AddCert(server.Certificate())
+	return server, trustAnchors
+}
+
+func TestESTEnrollAndReenroll(t *testing.T) {
+	standIn := newESTStandIn(t, time.Hour)
+	server, trustAnchors := startESTStandIn(t, standIn)
+	storageDir := t.TempDir()
+
+	client, err := NewESTClientBuilder().
+		WithServerURL(server.URL+"/.well-known/est").
+		WithTrustAnchors(trustAnchors).
+		WithBasicAuth("bootstrap", "secret").
+		WithIdentity("sensor-01").
+		WithStorageDir(storageDir).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlsEap, err := client.Enroll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if standIn.lastSubject != "sensor-01" {
+		t.Errorf("csr subject %q does not carry the identity", standIn.lastSubject)
+	}
+	confStr := tlsEap.ToConfigString()
+	for _, expected := range []string{"identity=\"sensor-01\"", client.ClientCertPath(), client.PrivateKeyPath(), client.CaCertPath()} {
+		if !strings.Contains(confStr, expected) {
+			t.Errorf("tls config %q does not contain %q", confStr, expected)
+		}
+	}
+	keyInfo, err := os.Stat(client.PrivateKeyPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyInfo.Mode().Perm() != 0600 {
+		t.Errorf("private key stored with mode %o", keyInfo.Mode().Perm())
+	}
+	if _, err = tls.LoadX509KeyPair(client.ClientCertPath(), client.PrivateKeyPath()); err != nil {
+		t.Errorf("stored certificate and key do not match: %v", err)
+	}
+	if entries, _ := os.ReadDir(storageDir); len(entries) != 3 {
+		t.Errorf("expected ca, certificate and key in the storage dir, found %d files", len(entries))
+	}
+
+	needed, err := client.NeedsReenrollment(30 * time.Minute)
+	if err != nil || needed {
+		t.Fatalf("fresh certificate must not need re-enrollment (needed=%v, err=%v)", needed, err)
+	}
+	client.renewIfNeeded(context.Background(), 2*time.Hour)
+	if standIn.reenrolled != 1 {
+		t.Errorf("expected one re-enrollment, got %d", standIn.reenrolled)
+	}
+	if err = client.RunReenrollment(context.Background(), time.Hour, 0); err == nil {
+		t.Errorf("zero check interval must be rejected")
+	}
+}
+
+func TestESTStoreRestoresPreviousFiles(t *testing.T) {
+	client := &ESTClient{storageDir: t.TempDir()}
+	for _, fileName := range []string{client.PrivateKeyPath(), client.ClientCertPath()} {
+		if err := os.WriteFile(fileName, []byte("previous"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	renameFile = func(oldPath, newPath string) error {
+		if newPath == client.CaCertPath() {
+			return errors.New("rename failed")
+		}
+		return os.Rename(oldPath, newPath)
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.store(nil, nil, key); err == nil {
+		t.Fatal("store must fail when a file can not be renamed into place")
+	}
+	for _, fileName := range []string{client.PrivateKeyPath(), client.ClientCertPath()} {
+		if content, _ := os.ReadFile(fileName); string(content) != "previous" {
+			t.Errorf("%s was not restored", fileName)
+		}
+	}
+	if entries, _ := os.ReadDir(client.storageDir); len(entries) != 2 {
+		t.Errorf("temporary files left in the storage dir, found %d entries", len(entries))
+	}
+}
+
+func TestESTEnrollRejectsBadBootstrapCredentials(t *testing.T) {
+	standIn := newESTStandIn(t, time.Hour)
+	server, trustAnchors := startESTStandIn(t, standIn)
+
+	client, err := NewESTClientBuilder().
+		WithServerURL(server.URL+"/.well-known/est").
+		WithTrustAnchors(trustAnchors).
+		WithBasicAuth("bootstrap", "wrong").
+		WithIdentity("sensor-01").
+		WithStorageDir(t.TempDir()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Enroll(context.Background()); err == nil {
+		t.Errorf("enrollment with wrong credentials must fail")
+	}
+}
@@ -1,5 +1,7 @@
 module git.dev.zgrp.net/litecom/libs/wpaSupplicantDbusLib
 
-go 1.17
+go 1.18
 
 require github.com/godbus/dbus/v5 v5.1.0
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,88 @@
+package wpaSuppDBusLib
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// rtmgrpLink is the rtnetlink multicast group for link notifications (RTMGRP_LINK)
+const rtmgrpLink = 0x1
+
+// watchLinkUp subscribes to rtnetlink link notifications and signals every down to up
+// transition of ifname. The returned channel is closed when ctx is cancelled or the socket fails.
+func watchLinkUp(ctx context.Context, ifname string) (<-chan struct{}, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: rtmgrpLink})
+	if err == nil {
+		err = syscall.SetNonblock(fd, true)
+	}
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	// a non blocking descriptor is registered with the runtime poller so Close unblocks Read
+	socket := os.NewFile(uintptr(fd), "rtnetlink")
+	go func() {
+		<-ctx.Done()
+		socket.Close()
+	}()
+
+	linkUpChan := make(chan struct{}, 1)
+	go func() {
+		defer close(linkUpChan)
+		up := readOperStateUp(ifname)
+		buffer := make([]byte, syscall.Getpagesize()*4)
+		for {
+			n, err := socket.Read(buffer)
+			if err != nil {
+				return
+			}
+			messages, err := syscall.ParseNetlinkMessage(buffer[:n])
+			if err != nil {
+				continue
+			}
+			for _, message := range messages {
+				running, matches := parseLinkMessage(message, ifname)
+				if !matches {
+					continue
+				}
+				if running && !up {
+					select {
+					case linkUpChan <- struct{}{}:
+					default:
+					}
+				}
+				up = running
+			}
+		}
+	}()
+	return linkUpChan, nil
+}
+
+func parseLinkMessage(message syscall.NetlinkMessage, ifname string) (bool, bool) {
+	if message.Header.Type != syscall.RTM_NEWLINK || len(message.Data) < syscall.SizeofIfInfomsg {
+		return false, false
+	}
+	info := (*syscall.IfInfomsg)(unsafe.Pointer(&message.Data[0]))
+	attrs, err := syscall.ParseNetlinkRouteAttr(&message)
+	if err != nil {
+		return false, false
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type == syscall.IFLA_IFNAME && strings.TrimRight(string(attr.Value), "\x00") == ifname {
+			return info.Flags&syscall.IFF_RUNNING != 0, true
+		}
+	}
+	return false, false
+}
+
+func readOperStateUp(ifname string) bool {
+	state, err := os.ReadFile("/sys/class/net/" + ifname + "/operstate")
+	return err == nil && strings.TrimSpace(string(state)) == "up"
+}
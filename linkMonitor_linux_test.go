@@ -0,0 +1,45 @@
+package wpaSuppDBusLib
+
+import (
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// newLinkMessage builds a RTM_NEWLINK message carrying ifname and the given interface flags
+func newLinkMessage(msgType uint16, ifname string, flags uint32) syscall.NetlinkMessage {
+	info := syscall.IfInfomsg{Family: syscall.AF_UNSPEC, Index: 2, Flags: flags}
+	data := make([]byte, syscall.SizeofIfInfomsg)
+	copy(data, (*[syscall.SizeofIfInfomsg]byte)(unsafe.Pointer(&info))[:])
+	name := append([]byte(ifname), 0)
+	header := syscall.RtAttr{Len: uint16(syscall.SizeofRtAttr + len(name)), Type: syscall.IFLA_IFNAME}
+	attr := append((*[syscall.SizeofRtAttr]byte)(unsafe.Pointer(&header))[:], name...)
+	for len(attr)%syscall.RTA_ALIGNTO != 0 {
+		attr = append(attr, 0)
+	}
+	data = append(data, attr...)
+	return syscall.NetlinkMessage{
+		Header: syscall.NlMsghdr{Len: uint32(syscall.NLMSG_HDRLEN + len(data)), Type: msgType},
+		Data:   data,
+	}
+}
+
+func TestParseLinkMessage(t *testing.T) {
+	running, matches := parseLinkMessage(newLinkMessage(syscall.RTM_NEWLINK, "eth0", syscall.IFF_UP|syscall.IFF_RUNNING), "eth0")
+	if !matches || !running {
+		t.Errorf("running eth0 parsed as matches=%v running=%v", matches, running)
+	}
+	running, matches = parseLinkMessage(newLinkMessage(syscall.RTM_NEWLINK, "eth0", syscall.IFF_UP), "eth0")
+	if !matches || running {
+		t.Errorf("eth0 without carrier parsed as matches=%v running=%v", matches, running)
+	}
+	if _, matches = parseLinkMessage(newLinkMessage(syscall.RTM_NEWLINK, "eth1", syscall.IFF_RUNNING), "eth0"); matches {
+		t.Errorf("message for eth1 must not match eth0")
+	}
+	if _, matches = parseLinkMessage(newLinkMessage(syscall.RTM_DELLINK, "eth0", 0), "eth0"); matches {
+		t.Errorf("RTM_DELLINK must be ignored")
+	}
+	if _, matches = parseLinkMessage(syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: syscall.RTM_NEWLINK}, Data: []byte{0, 0}}, "eth0"); matches {
+		t.Errorf("truncated message must be ignored")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package wpaSuppDBusLib
+
+import (
+	"context"
+	"errors"
+)
+
+func watchLinkUp(ctx context.Context, ifname string) (<-chan struct{}, error) {
+	return nil, errors.New("link monitoring is only supported on linux")
+}
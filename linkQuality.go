@@ -0,0 +1,216 @@
+package wpaSuppDBusLib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"time"
+)
+
+// invalidNoise is reported by the supplicant when the driver does not provide a noise floor
+var invalidNoise int32 = 9999
+
+type LinkQualityMetric string
+type LinkQualityEventType string
+
+const (
+	MetricRSSI        LinkQualityMetric = "rssi"
+	MetricAverageRSSI LinkQualityMetric = "avg-rssi"
+	MetricLinkSpeed   LinkQualityMetric = "linkspeed"
+	MetricSNR         LinkQualityMetric = "snr"
+)
+
+const (
+	LinkQualityDegraded  LinkQualityEventType = "degraded"
+	LinkQualityRecovered LinkQualityEventType = "recovered"
+)
+
+// LinkQuality is the result of a SignalPoll. RSSI and noise are in dBm, link speed in Mbps and
+// frequencies in MHz. Values the driver does not report are zero.
+type LinkQuality struct {
+	Time              time.Time
+	RSSI              int32
+	Noise             int32
+	LinkSpeed         int32
+	Frequency         uint32
+	CenterFrequency1  int32
+	CenterFrequency2  int32
+	ChannelWidth      string
+	AverageRSSI       int32
+	AverageBeaconRSSI int32
+	RxMCS             int32
+	TxMCS             int32
+	RxVHTMCS          int32
+	TxVHTMCS          int32
+	RxVHTNSS          int32
+	TxVHTNSS          int32
+}
+
+// SNR returns the signal to noise ratio in dB, or false when the driver reports no noise floor
+func (l *LinkQuality) SNR() (int32, bool) {
+	if l.Noise == 0 || l.Noise == invalidNoise {
+		return 0, false
+	}
+	return l.RSSI - l.Noise, true
+}
+
+// LinkQualityThresholds are the lower bounds a link must stay above. A zero value disables
+// the corresponding check.
+type LinkQualityThresholds struct {
+	MinRSSI        int32
+	MinAverageRSSI int32
+	MinLinkSpeed   int32
+	MinSNR         int32
+}
+
+// LinkQualityEvent is emitted when a metric drops below its threshold and again when it
+// is back at or above it.
+type LinkQualityEvent struct {
+	Type      LinkQualityEventType
+	Metric    LinkQualityMetric
+	Value     int32
+	Threshold int32
+	Sample    LinkQuality
+}
+
+// SignalPoll reads the current signal and link information of a connected interface
+func (wpaDbus *WpaSupplicantDbus) SignalPoll(wpaInterfaceName dbus.ObjectPath) (*LinkQuality, error) {
+	sample, err := signalPoll(wpaDbus, wpaInterfaceName)
+	if err != nil {
+		wpaDbus.logger.Error(err)
+		return nil, err
+	}
+	return sample, nil
+}
+
+func signalPoll(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath) (*LinkQuality, error) {
+	obj := wpaDbus.dbusCon.Object(dbusWPAname, wpaInterfaceName)
+	var properties map[string]dbus.Variant
+	err := obj.Call(dbusWPAInterfacename+".SignalPoll", 0).Store(&properties)
+	if err != nil {
+		return nil, err
+	}
+	return newLinkQuality(time.Now(), properties), nil
+}
+
+// MonitorLinkQuality polls the interface every interval until ctx is cancelled. Every sample is
+// sent on the returned sample channel, threshold crossings on the event channel. Both channels
+// are closed when ctx is cancelled. Samples are dropped while the sample channel is full, so a
+// caller only interested in events does not need to read it. Failed polls, e.g. while
+// disconnected, are logged and skipped.
+func (wpaDbus *WpaSupplicantDbus) MonitorLinkQuality(ctx context.Context, wpaInterfaceName dbus.ObjectPath, interval time.Duration, thresholds LinkQualityThresholds) (<-chan LinkQuality, <-chan LinkQualityEvent, error) {
+	if interval <= 0 {
+		return nil, nil, errors.New("invalid value for link quality poll interval")
+	}
+	sampleChan, eventChan := monitorLinkQuality(ctx, interval, thresholds, func() (*LinkQuality, error) {
+		sample, err := signalPoll(wpaDbus, wpaInterfaceName)
+		if err != nil {
+			wpaDbus.logger.Warn(fmt.Sprintf("signal poll of %s failed: %v", wpaInterfaceName, err))
+		}
+		return sample, err
+	})
+	return sampleChan, eventChan, nil
+}
+
+// monitorLinkQuality calls poll every interval and feeds the samples to a linkQualityTracker
+func monitorLinkQuality(ctx context.Context, interval time.Duration, thresholds LinkQualityThresholds, poll func() (*LinkQuality, error)) (<-chan LinkQuality, <-chan LinkQualityEvent) {
+	sampleChan := make(chan LinkQuality, signalBufferSize)
+	eventChan := make(chan LinkQualityEvent, signalBufferSize)
+	tracker := newLinkQualityTracker(thresholds)
+	go func() {
+		defer close(sampleChan)
+		defer close(eventChan)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			sample, err := poll()
+			if err != nil {
+				continue
+			}
+			select {
+			case sampleChan <- *sample:
+			default:
+			}
+			for _, event := range tracker.update(*sample) {
+				select {
+				case eventChan <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return sampleChan, eventChan
+}
+
+// linkQualityTracker remembers which metrics are below their threshold so that an event is only
+// emitted on the transition and not for every degraded sample
+type linkQualityTracker struct {
+	thresholds LinkQualityThresholds
+	degraded   map[LinkQualityMetric]bool
+}
+
+func newLinkQualityTracker(thresholds LinkQualityThresholds) *linkQualityTracker {
+	return &linkQualityTracker{
+		thresholds: thresholds,
+		degraded:   make(map[LinkQualityMetric]bool),
+	}
+}
+
+func (t *linkQualityTracker) update(sample LinkQuality) []LinkQualityEvent {
+	events := make([]LinkQualityEvent, 0)
+	check := func(metric LinkQualityMetric, value, threshold int32, available bool) {
+		if threshold == 0 || !available {
+			return
+		}
+		below := value < threshold
+		if below == t.degraded[metric] {
+			return
+		}
+		t.degraded[metric] = below
+		eventType := LinkQualityRecovered
+		if below {
+			eventType = LinkQualityDegraded
+		}
+		events = append(events, LinkQualityEvent{
+			Type:      eventType,
+			Metric:    metric,
+			Value:     value,
+			Threshold: threshold,
+			Sample:    sample,
+		})
+	}
+	check(MetricRSSI, sample.RSSI, t.thresholds.MinRSSI, sample.RSSI != 0)
+	check(MetricAverageRSSI, sample.AverageRSSI, t.thresholds.MinAverageRSSI, sample.AverageRSSI != 0)
+	check(MetricLinkSpeed, sample.LinkSpeed, t.thresholds.MinLinkSpeed, sample.LinkSpeed != 0)
+	snr, snrAvailable := sample.SNR()
+	check(MetricSNR, snr, t.thresholds.MinSNR, snrAvailable)
+	return events
+}
+
+func newLinkQuality(timestamp time.Time, properties map[string]dbus.Variant) *LinkQuality {
+	return &LinkQuality{
+		Time:              timestamp,
+		RSSI:              int32(variantInt(properties, "rssi")),
+		Noise:             int32(variantInt(properties, "noise")),
+		LinkSpeed:         int32(variantInt(properties, "linkspeed")),
+		Frequency:         uint32(variantInt(properties, "frequency")),
+		CenterFrequency1:  int32(variantInt(properties, "center-frq1")),
+		CenterFrequency2:  int32(variantInt(properties, "center-frq2")),
+		ChannelWidth:      variantString(properties, "width"),
+		AverageRSSI:       int32(variantInt(properties, "avg-rssi")),
+		AverageBeaconRSSI: int32(variantInt(properties, "avg-beacon-rssi")),
+		RxMCS:             int32(variantInt(properties, "rx-mcs")),
+		TxMCS:             int32(variantInt(properties, "tx-mcs")),
+		RxVHTMCS:          int32(variantInt(properties, "rx-vht-mcs")),
+		TxVHTMCS:          int32(variantInt(properties, "tx-vht-mcs")),
+		RxVHTNSS:          int32(variantInt(properties, "rx-vht-nss")),
+		TxVHTNSS:          int32(variantInt(properties, "tx-vht-nss")),
+	}
+}
@@ -0,0 +1,73 @@
+package wpaSuppDBusLib
+
+import (
+	"context"
+	"github.com/godbus/dbus/v5"
+	"testing"
+	"time"
+)
+
+func TestNewLinkQuality(t *testing.T) {
+	properties := map[string]dbus.Variant{
+		"rssi":        dbus.MakeVariant(int32(-61)),
+		"noise":       dbus.MakeVariant(int32(-92)),
+		"linkspeed":   dbus.MakeVariant(int32(433)),
+		"frequency":   dbus.MakeVariant(uint32(5180)),
+		"width":       dbus.MakeVariant("80 MHz"),
+		"center-frq1": dbus.MakeVariant(int32(5210)),
+		"avg-rssi":    dbus.MakeVariant(int32(-63)),
+		"rx-vht-nss":  dbus.MakeVariant(uint32(2)),
+	}
+	sample := newLinkQuality(time.Now(), properties)
+	if sample.RSSI != -61 || sample.LinkSpeed != 433 || sample.Frequency != 5180 || sample.ChannelWidth != "80 MHz" ||
+		sample.CenterFrequency1 != 5210 || sample.AverageRSSI != -63 || sample.RxVHTNSS != 2 {
+		t.Errorf("unexpected link quality %+v", sample)
+	}
+	if snr, ok := sample.SNR(); !ok || snr != 31 {
+		t.Errorf("unexpected snr %d", snr)
+	}
+	sample.Noise = invalidNoise
+	if _, ok := sample.SNR(); ok {
+		t.Errorf("snr must be unavailable without a noise floor")
+	}
+}
+
+func TestLinkQualityTracker(t *testing.T) {
+	tracker := newLinkQualityTracker(LinkQualityThresholds{MinRSSI: -70, MinLinkSpeed: 50})
+
+	if events := tracker.update(LinkQuality{RSSI: -60, LinkSpeed: 300}); len(events) != 0 {
+		t.Errorf("healthy link must not emit events, got %v", events)
+	}
+	events := tracker.update(LinkQuality{RSSI: -75, LinkSpeed: 300})
+	if len(events) != 1 || events[0].Type != LinkQualityDegraded || events[0].Metric != MetricRSSI || events[0].Value != -75 {
+		t.Errorf("expected rssi degradation, got %v", events)
+	}
+	if events = tracker.update(LinkQuality{RSSI: -78, LinkSpeed: 300}); len(events) != 0 {
+		t.Errorf("degradation must only be reported once, got %v", events)
+	}
+	events = tracker.update(LinkQuality{RSSI: -65, LinkSpeed: 24})
+	if len(events) != 2 || events[0].Type != LinkQualityRecovered || events[1].Metric != MetricLinkSpeed {
+		t.Errorf("expected rssi recovery and link speed degradation, got %v", events)
+	}
+}
+
+func TestMonitorLinkQualityWithoutSampleReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	polls := 0
+	_, eventChan := monitorLinkQuality(ctx, time.Millisecond, LinkQualityThresholds{MinRSSI: -70}, func() (*LinkQuality, error) {
+		polls++
+		if polls <= 2*signalBufferSize {
+			return &LinkQuality{RSSI: -50}, nil
+		}
+		return &LinkQuality{RSSI: -80}, nil
+	})
+	select {
+	case event := <-eventChan:
+		if event.Type != LinkQualityDegraded || event.Metric != MetricRSSI {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("degradation not reported while the sample channel is not read")
+	}
+}
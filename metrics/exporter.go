@@ -0,0 +1,417 @@
+// Package metrics exports the state of wpa_supplicant and of the interfaces tracked by a
+// wpaSuppDBusLib.WpaSupplicantDbus in the Prometheus text exposition format. It has no
+// dependency on the Prometheus client library; an Exporter is an http.Handler that can be
+// mounted on any local HTTP server.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	wpaSuppDBusLib "git.dev.zgrp.net/litecom/libs/wpaSupplicantDbusLib"
+)
+
+// DefaultTimeToCompletedBuckets are the histogram buckets, in seconds, used when NewExporter
+// is called without buckets
+var DefaultTimeToCompletedBuckets = []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120}
+
+var eventBufferSize = 32
+
+// supplicantStates are the values of the Interface State property
+var supplicantStates = []string{"disconnected", "inactive", "scanning", "authenticating", "associating",
+	"associated", "4way_handshake", "group_handshake", "completed", "interface_disabled", "unknown"}
+
+// connectingStates start the time-to-completed measurement, idleStates abandon it
+var connectingStates = []string{"scanning", "authenticating", "associating", "associated", "4way_handshake", "group_handshake"}
+var idleStates = []string{"inactive", "interface_disabled"}
+
+// Source is the part of the supplicant API read by the exporter.
+// *wpaSuppDBusLib.WpaSupplicantDbus implements it.
+type Source interface {
+	SupplicantRunning() (bool, error)
+	TrackedInterfaces() map[dbus.ObjectPath]string
+	GetState(wpaInterfaceName dbus.ObjectPath) (string, error)
+	SignalPoll(wpaInterfaceName dbus.ObjectPath) (*wpaSuppDBusLib.LinkQuality, error)
+	GetBSSs(wpaInterfaceName dbus.ObjectPath) ([]dbus.ObjectPath, error)
+	SubscribeStateChanges(wpaInterfaceName dbus.ObjectPath, stateChan chan string) (func(), error)
+	SubscribeEAPEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan wpaSuppDBusLib.EAPEvent) (func(), error)
+	SubscribeScanDone(wpaInterfaceName dbus.ObjectPath, resultChan chan bool) (func(), error)
+}
+
+// Exporter collects per interface metrics from the supplicant signals and serves them together
+// with values read at scrape time (supplicant up, RSSI, link speed, BSS count).
+type Exporter struct {
+	source     Source
+	buckets    []float64
+	mutex      sync.Mutex
+	interfaces map[dbus.ObjectPath]*interfaceMetrics
+	logger     wpaSuppDBusLib.Logger
+}
+
+type eapResultKey struct {
+	method string
+	result string
+}
+
+type interfaceMetrics struct {
+	ifname          string
+	state           string
+	stateEntries    map[string]uint64
+	eapMethod       string
+	eapResults      map[eapResultKey]uint64
+	connectStart    time.Time
+	timeToCompleted *histogram
+	scans           map[bool]uint64
+	stop            func()
+}
+
+// NewExporter creates an exporter reading from source. Empty buckets fall back to
+// DefaultTimeToCompletedBuckets.
+func NewExporter(source Source, buckets ...float64) *Exporter {
+	if len(buckets) == 0 {
+		buckets = DefaultTimeToCompletedBuckets
+	}
+	return &Exporter{
+		source:     source,
+		buckets:    buckets,
+		interfaces: make(map[dbus.ObjectPath]*interfaceMetrics),
+		logger:     stdLogger{},
+	}
+}
+
+// WithLogger sets the logger for interfaces that can not be collected. By default they are
+// logged with the standard log package.
+func (e *Exporter) WithLogger(logger wpaSuppDBusLib.Logger) *Exporter {
+	e.logger = logger
+	return e
+}
+
+// Run synchronises the collected interfaces with the tracked interfaces every interval until ctx
+// is cancelled, then drops every subscription. Without Run the interfaces are synchronised on scrape.
+// It returns right away if interval is not positive.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("invalid value for sync interval")
+	}
+	e.Sync()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.stopAll()
+			return nil
+		case <-ticker.C:
+			e.Sync()
+		}
+	}
+}
+
+// Sync starts collecting for newly tracked interfaces and stops for removed ones
+func (e *Exporter) Sync() {
+	tracked := e.source.TrackedInterfaces()
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for ifPath, metrics := range e.interfaces {
+		if _, ok := tracked[ifPath]; !ok {
+			metrics.stop()
+			delete(e.interfaces, ifPath)
+		}
+	}
+	for ifPath, ifname := range tracked {
+		if _, ok := e.interfaces[ifPath]; ok {
+			continue
+		}
+		metrics, err := e.track(ifPath, ifname)
+		if err != nil {
+			e.logger.Warn(fmt.Sprintf("unable to collect metrics of %s: %v", ifname, err))
+			continue
+		}
+		e.interfaces[ifPath] = metrics
+	}
+}
+
+// ServeHTTP writes the current metrics in the Prometheus text format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.Sync()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(e.collect()))
+}
+
+func (e *Exporter) track(ifPath dbus.ObjectPath, ifname string) (*interfaceMetrics, error) {
+	metrics := &interfaceMetrics{
+		ifname:          ifname,
+		stateEntries:    make(map[string]uint64),
+		eapResults:      make(map[eapResultKey]uint64),
+		timeToCompleted: newHistogram(e.buckets),
+		scans:           make(map[bool]uint64),
+	}
+	if state, err := e.source.GetState(ifPath); err == nil {
+		metrics.state = state
+		if contains(connectingStates, state) {
+			metrics.connectStart = time.Now()
+		}
+	}
+	stateChan := make(chan string, eventBufferSize)
+	eapChan := make(chan wpaSuppDBusLib.EAPEvent, eventBufferSize)
+	scanChan := make(chan bool, eventBufferSize)
+	unsubscribers := make([]func(), 0, 3)
+	done := make(chan struct{})
+	metrics.stop = func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+		close(done)
+	}
+	subscriptions := []func() (func(), error){
+		func() (func(), error) { return e.source.SubscribeStateChanges(ifPath, stateChan) },
+		func() (func(), error) { return e.source.SubscribeEAPEvents(ifPath, eapChan) },
+		func() (func(), error) { return e.source.SubscribeScanDone(ifPath, scanChan) },
+	}
+	for _, subscribe := range subscriptions {
+		unsubscribe, err := subscribe()
+		if err != nil {
+			metrics.stop()
+			return nil, err
+		}
+		unsubscribers = append(unsubscribers, unsubscribe)
+	}
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case state := <-stateChan:
+				e.mutex.Lock()
+				metrics.onState(state, time.Now())
+				e.mutex.Unlock()
+			case event := <-eapChan:
+				e.mutex.Lock()
+				metrics.onEAP(event)
+				e.mutex.Unlock()
+			case success := <-scanChan:
+				e.mutex.Lock()
+				metrics.scans[success]++
+				e.mutex.Unlock()
+			}
+		}
+	}()
+	return metrics, nil
+}
+
+func (e *Exporter) stopAll() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for ifPath, metrics := range e.interfaces {
+		metrics.stop()
+		delete(e.interfaces, ifPath)
+	}
+}
+
+func (m *interfaceMetrics) onState(state string, now time.Time) {
+	if state == m.state {
+		return
+	}
+	m.state = state
+	m.stateEntries[state]++
+	switch {
+	case state == "completed":
+		if !m.connectStart.IsZero() {
+			m.timeToCompleted.observe(now.Sub(m.connectStart).Seconds())
+			m.connectStart = time.Time{}
+		}
+	case contains(connectingStates, state):
+		if m.connectStart.IsZero() {
+			m.connectStart = now
+		}
+	case contains(idleStates, state):
+		m.connectStart = time.Time{}
+	}
+}
+
+func (m *interfaceMetrics) onEAP(event wpaSuppDBusLib.EAPEvent) {
+	switch event.Status {
+	case "method":
+		m.eapMethod = event.Parameter
+	case "completion":
+		method := m.eapMethod
+		if method == "" {
+			method = "unknown"
+		}
+		m.eapResults[eapResultKey{method: method, result: event.Parameter}]++
+		m.eapMethod = ""
+	}
+}
+
+// interfaceSnapshot is a copy of an interface's metrics plus the values read at scrape time
+type interfaceSnapshot struct {
+	ifPath          dbus.ObjectPath
+	ifname          string
+	state           string
+	stateEntries    map[string]uint64
+	eapResults      map[eapResultKey]uint64
+	timeToCompleted *histogram
+	scans           map[bool]uint64
+	linkQuality     *wpaSuppDBusLib.LinkQuality
+	bssCount        int
+	bssCountValid   bool
+}
+
+func (e *Exporter) snapshot() []interfaceSnapshot {
+	e.mutex.Lock()
+	snapshots := make([]interfaceSnapshot, 0, len(e.interfaces))
+	for ifPath, metrics := range e.interfaces {
+		snapshot := interfaceSnapshot{
+			ifPath:          ifPath,
+			ifname:          metrics.ifname,
+			state:           metrics.state,
+			stateEntries:    make(map[string]uint64, len(metrics.stateEntries)),
+			eapResults:      make(map[eapResultKey]uint64, len(metrics.eapResults)),
+			timeToCompleted: metrics.timeToCompleted.clone(),
+			scans:           map[bool]uint64{true: metrics.scans[true], false: metrics.scans[false]},
+		}
+		for state, count := range metrics.stateEntries {
+			snapshot.stateEntries[state] = count
+		}
+		for key, count := range metrics.eapResults {
+			snapshot.eapResults[key] = count
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	e.mutex.Unlock()
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ifname < snapshots[j].ifname
+	})
+	for i := range snapshots {
+		if snapshots[i].state == "completed" {
+			if linkQuality, err := e.source.SignalPoll(snapshots[i].ifPath); err == nil {
+				snapshots[i].linkQuality = linkQuality
+			}
+		}
+		if bsss, err := e.source.GetBSSs(snapshots[i].ifPath); err == nil {
+			snapshots[i].bssCount = len(bsss)
+			snapshots[i].bssCountValid = true
+		}
+	}
+	return snapshots
+}
+
+func (e *Exporter) collect() string {
+	w := &textWriter{}
+	up := 0.0
+	if running, err := e.source.SupplicantRunning(); err == nil && running {
+		up = 1
+	}
+	w.header("wpa_supplicant_up", "Whether wpa_supplicant is registered on the system bus.", "gauge")
+	w.sample("wpa_supplicant_up", nil, up)
+
+	snapshots := e.snapshot()
+
+	w.header("wpa_supplicant_interface_state", "Current supplicant state of the interface, 1 for the active state.", "gauge")
+	for _, s := range snapshots {
+		for _, state := range supplicantStates {
+			value := 0.0
+			if s.state == state {
+				value = 1
+			}
+			w.sample("wpa_supplicant_interface_state", []label{{"interface", s.ifname}, {"state", state}}, value)
+		}
+	}
+
+	w.header("wpa_supplicant_interface_state_transitions_total", "Number of transitions into each supplicant state.", "counter")
+	for _, s := range snapshots {
+		for _, state := range sortedKeys(s.stateEntries) {
+			w.sample("wpa_supplicant_interface_state_transitions_total", []label{{"interface", s.ifname}, {"state", state}}, float64(s.stateEntries[state]))
+		}
+	}
+
+	w.header("wpa_supplicant_eap_completions_total", "Number of finished EAP authentications by method and result.", "counter")
+	for _, s := range snapshots {
+		keys := make([]eapResultKey, 0, len(s.eapResults))
+		for key := range s.eapResults {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].method != keys[j].method {
+				return keys[i].method < keys[j].method
+			}
+			return keys[i].result < keys[j].result
+		})
+		for _, key := range keys {
+			w.sample("wpa_supplicant_eap_completions_total", []label{{"interface", s.ifname}, {"method", key.method}, {"result", key.result}}, float64(s.eapResults[key]))
+		}
+	}
+
+	w.header("wpa_supplicant_time_to_completed_seconds", "Time from the start of a connection attempt until the completed state.", "histogram")
+	for _, s := range snapshots {
+		w.histogram("wpa_supplicant_time_to_completed_seconds", []label{{"interface", s.ifname}}, s.timeToCompleted)
+	}
+
+	w.header("wpa_supplicant_scans_total", "Number of finished scans by result.", "counter")
+	for _, s := range snapshots {
+		w.sample("wpa_supplicant_scans_total", []label{{"interface", s.ifname}, {"result", "success"}}, float64(s.scans[true]))
+		w.sample("wpa_supplicant_scans_total", []label{{"interface", s.ifname}, {"result", "failure"}}, float64(s.scans[false]))
+	}
+
+	w.header("wpa_supplicant_bss_count", "Number of BSSs known to the interface.", "gauge")
+	for _, s := range snapshots {
+		if s.bssCountValid {
+			w.sample("wpa_supplicant_bss_count", []label{{"interface", s.ifname}}, float64(s.bssCount))
+		}
+	}
+
+	w.header("wpa_supplicant_rssi_dbm", "Signal strength of the current connection.", "gauge")
+	for _, s := range snapshots {
+		if s.linkQuality != nil {
+			w.sample("wpa_supplicant_rssi_dbm", []label{{"interface", s.ifname}}, float64(s.linkQuality.RSSI))
+		}
+	}
+
+	w.header("wpa_supplicant_link_speed_mbps", "Link speed of the current connection.", "gauge")
+	for _, s := range snapshots {
+		if s.linkQuality != nil {
+			w.sample("wpa_supplicant_link_speed_mbps", []label{{"interface", s.ifname}}, float64(s.linkQuality.LinkSpeed))
+		}
+	}
+	return w.String()
+}
+
+func sortedKeys(values map[string]uint64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// stdLogger logs through the standard log package
+type stdLogger struct{}
+
+func (stdLogger) Info(args ...interface{}) {
+	log.Print(append([]interface{}{"[INFO] "}, args...)...)
+}
+
+func (stdLogger) Warn(args ...interface{}) {
+	log.Print(append([]interface{}{"[WARN] "}, args...)...)
+}
+
+func (stdLogger) Error(args ...interface{}) {
+	log.Print(append([]interface{}{"[ERROR] "}, args...)...)
+}
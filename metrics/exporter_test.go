@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	wpaSuppDBusLib "git.dev.zgrp.net/litecom/libs/wpaSupplicantDbusLib"
+)
+
+var testIfPath = dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/0")
+
+type fakeSource struct {
+	stateChan chan string
+	eapChan   chan wpaSuppDBusLib.EAPEvent
+	scanChan  chan bool
+}
+
+func (f *fakeSource) SupplicantRunning() (bool, error) {
+	return true, nil
+}
+
+func (f *fakeSource) TrackedInterfaces() map[dbus.ObjectPath]string {
+	return map[dbus.ObjectPath]string{testIfPath: "eth0"}
+}
+
+func (f *fakeSource) GetState(wpaInterfaceName dbus.ObjectPath) (string, error) {
+	return "disconnected", nil
+}
+
+func (f *fakeSource) SignalPoll(wpaInterfaceName dbus.ObjectPath) (*wpaSuppDBusLib.LinkQuality, error) {
+	return &wpaSuppDBusLib.LinkQuality{RSSI: -58, LinkSpeed: 866}, nil
+}
+
+func (f *fakeSource) GetBSSs(wpaInterfaceName dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	return []dbus.ObjectPath{"/bss/0", "/bss/1", "/bss/2"}, nil
+}
+
+func (f *fakeSource) SubscribeStateChanges(wpaInterfaceName dbus.ObjectPath, stateChan chan string) (func(), error) {
+	f.stateChan = stateChan
+	return func() {}, nil
+}
+
+func (f *fakeSource) SubscribeEAPEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan wpaSuppDBusLib.EAPEvent) (func(), error) {
+	f.eapChan = eventChan
+	return func() {}, nil
+}
+
+func (f *fakeSource) SubscribeScanDone(wpaInterfaceName dbus.ObjectPath, resultChan chan bool) (func(), error) {
+	f.scanChan = resultChan
+	return func() {}, nil
+}
+
+func TestExporterServesCollectedMetrics(t *testing.T) {
+	source := &fakeSource{}
+	exporter := NewExporter(source, 1, 5)
+	exporter.Sync()
+
+	source.scanChan <- true
+	source.stateChan <- "authenticating"
+	source.eapChan <- wpaSuppDBusLib.EAPEvent{Status: "method", Parameter: "TLS"}
+	source.eapChan <- wpaSuppDBusLib.EAPEvent{Status: "completion", Parameter: "success"}
+	source.stateChan <- "completed"
+
+	expected := []string{
+		"wpa_supplicant_up 1",
+		`wpa_supplicant_interface_state{interface="eth0",state="completed"} 1`,
+		`wpa_supplicant_interface_state{interface="eth0",state="disconnected"} 0`,
+		`wpa_supplicant_interface_state_transitions_total{interface="eth0",state="authenticating"} 1`,
+		`wpa_supplicant_eap_completions_total{interface="eth0",method="TLS",result="success"} 1`,
+		`wpa_supplicant_time_to_completed_seconds_bucket{interface="eth0",le="+Inf"} 1`,
+		`wpa_supplicant_time_to_completed_seconds_count{interface="eth0"} 1`,
+		`wpa_supplicant_scans_total{interface="eth0",result="success"} 1`,
+		`wpa_supplicant_bss_count{interface="eth0"} 3`,
+		`wpa_supplicant_rssi_dbm{interface="eth0"} -58`,
+		`wpa_supplicant_link_speed_mbps{interface="eth0"} 866`,
+		"# TYPE wpa_supplicant_time_to_completed_seconds histogram",
+	}
+	var body string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		recorder := httptest.NewRecorder()
+		exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		body = recorder.Body.String()
+		if strings.Contains(body, `wpa_supplicant_time_to_completed_seconds_count{interface="eth0"} 1`) {
+			break
+		}
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, body)
+		}
+	}
+}
+
+type failingSource struct {
+	fakeSource
+}
+
+func (f *failingSource) SubscribeEAPEvents(wpaInterfaceName dbus.ObjectPath, eventChan chan wpaSuppDBusLib.EAPEvent) (func(), error) {
+	return nil, errors.New("subscription failed")
+}
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Info(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *recordingLogger) Warn(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *recordingLogger) Error(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func TestExporterReportsFailures(t *testing.T) {
+	logger := &recordingLogger{}
+	exporter := NewExporter(&failingSource{}).WithLogger(logger)
+	exporter.Sync()
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "eth0") {
+		t.Errorf("failed interface not logged: %q", logger.messages)
+	}
+	if err := exporter.Run(context.Background(), 0); err == nil {
+		t.Errorf("zero sync interval must be rejected")
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	h := newHistogram([]float64{5, 1})
+	h.observe(0.5)
+	h.observe(3)
+	h.observe(30)
+	w := &textWriter{}
+	w.histogram("h", []label{{"interface", "wlan\"0"}}, h)
+	expected := "h_bucket{interface=\"wlan\\\"0\",le=\"1\"} 1\n" +
+		"h_bucket{interface=\"wlan\\\"0\",le=\"5\"} 2\n" +
+		"h_bucket{interface=\"wlan\\\"0\",le=\"+Inf\"} 3\n" +
+		"h_sum{interface=\"wlan\\\"0\"} 33.5\n" +
+		"h_count{interface=\"wlan\\\"0\"} 3\n"
+	if w.String() != expected {
+		t.Errorf("unexpected histogram output\n%s", w.String())
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type label struct {
+	name  string
+	value string
+}
+
+// textWriter renders metric families in the Prometheus text exposition format (version 0.0.4).
+// Every family must be written in one go: header first, then all of its samples.
+type textWriter struct {
+	builder strings.Builder
+}
+
+func (w *textWriter) header(name, help, metricType string) {
+	w.builder.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.builder.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+func (w *textWriter) sample(name string, labels []label, value float64) {
+	w.builder.WriteString(name)
+	if len(labels) > 0 {
+		w.builder.WriteString("{")
+		for i, l := range labels {
+			if i > 0 {
+				w.builder.WriteString(",")
+			}
+			w.builder.WriteString(l.name + "=\"" + escapeLabelValue(l.value) + "\"")
+		}
+		w.builder.WriteString("}")
+	}
+	w.builder.WriteString(" " + formatValue(value) + "\n")
+}
+
+func (w *textWriter) histogram(name string, labels []label, h *histogram) {
+	for i, upperBound := range h.upperBounds {
+		w.sample(name+"_bucket", append(labels, label{"le", formatValue(upperBound)}), float64(h.counts[i]))
+	}
+	w.sample(name+"_bucket", append(labels, label{"le", "+Inf"}), float64(h.count))
+	w.sample(name+"_sum", labels, h.sum)
+	w.sample(name+"_count", labels, float64(h.count))
+}
+
+func (w *textWriter) String() string {
+	return w.builder.String()
+}
+
+// histogram keeps cumulative bucket counts like a Prometheus histogram
+type histogram struct {
+	upperBounds []float64
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func newHistogram(upperBounds []float64) *histogram {
+	bounds := make([]float64, len(upperBounds))
+	copy(bounds, upperBounds)
+	sort.Float64s(bounds)
+	return &histogram{
+		upperBounds: bounds,
+		counts:      make([]uint64, len(bounds)),
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, upperBound := range h.upperBounds {
+		if value <= upperBound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+func (h *histogram) clone() *histogram {
+	c := *h
+	c.upperBounds = append([]float64(nil), h.upperBounds...)
+	c.counts = append([]uint64(nil), h.counts...)
+	return &c
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value)
+}
@@ -0,0 +1,208 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+type ReconcileAction string
+
+const (
+	ReconcileAdd    ReconcileAction = "add"
+	ReconcileRemove ReconcileAction = "remove"
+	ReconcileUpdate ReconcileAction = "update"
+	// ReconcileReplace removes and re-adds a network whose live config has fields the desired
+	// network no longer sets
+	ReconcileReplace ReconcileAction = "replace"
+)
+
+// ReconcileOperation is a single change needed to bring an interface to its desired networks.
+// NetworkPath is empty for ReconcileAdd until the operation was applied and is replaced by the new
+// path for ReconcileReplace. Properties holds the fields an update sets, StaleFields the keys a
+// replace drops.
+type ReconcileOperation struct {
+	Action      ReconcileAction
+	NetworkPath dbus.ObjectPath
+	Network     *Network
+	Properties  map[string]interface{}
+	StaleFields []string
+}
+
+// ReconcilePlan lists the operations of a reconciliation in the order they are applied:
+// removals, updates and replacements, additions. Unchanged holds the networks that already match.
+type ReconcilePlan struct {
+	Operations []ReconcileOperation
+	Unchanged  []dbus.ObjectPath
+}
+
+// ReconcileOptions control ReconcileNetworks. With DryRun the plan is only computed. Secrets can
+// not be read back from the supplicant, UpdateSecrets writes them to every kept network so that a
+// changed psk or password is applied too.
+type ReconcileOptions struct {
+	DryRun        bool
+	UpdateSecrets bool
+}
+
+// liveNetwork is a network configured on a running interface
+type liveNetwork struct {
+	path       dbus.ObjectPath
+	properties map[string]string
+}
+
+// ReconcileNetworks compares the networks of wpaInterface with the ones configured on the running
+// interface and applies the minimal AddNetwork, RemoveNetwork and property changes. Networks are
+// matched by SSID, EAP identity and priority, then by SSID and identity alone. A network that has
+// string fields the desired network no longer sets is replaced, since those can not be cleared.
+func (wpaDbus *WpaSupplicantDbus) ReconcileNetworks(wpaInterfaceName dbus.ObjectPath, wpaInterface WPAInterface, options ReconcileOptions) (*ReconcilePlan, error) {
+	paths, err := wpaDbus.GetNetworks(wpaInterfaceName)
+	if err != nil {
+		return nil, err
+	}
+	live := make([]liveNetwork, 0, len(paths))
+	for _, networkPath := range paths {
+		properties, err := wpaDbus.GetNetworkProperties(networkPath)
+		if err != nil {
+			return nil, err
+		}
+		live = append(live, liveNetwork{path: networkPath, properties: properties})
+	}
+	for i := range wpaInterface.network {
+		if err := checkDBusSupported(wpaInterface.network[i].allConfigFields()); err != nil {
+			return nil, err
+		}
+	}
+	plan := planNetworkReconcile(wpaInterface.network, live, options.UpdateSecrets)
+	if options.DryRun {
+		return plan, nil
+	}
+	for i := range plan.Operations {
+		operation := &plan.Operations[i]
+		switch operation.Action {
+		case ReconcileRemove:
+			err = wpaDbus.RemoveNetwork(wpaInterfaceName, operation.NetworkPath)
+		case ReconcileUpdate:
+			err = wpaDbus.SetNetworkProperties(operation.NetworkPath, operation.Properties)
+		case ReconcileReplace:
+			err = wpaDbus.RemoveNetwork(wpaInterfaceName, operation.NetworkPath)
+			if err != nil {
+				break
+			}
+			fallthrough
+		case ReconcileAdd:
+			operation.NetworkPath, err = wpaDbus.AddNetwork(wpaInterfaceName, *operation.Network)
+			if err == nil {
+				err = wpaDbus.SetNetworkEnabled(operation.NetworkPath, true)
+			}
+		}
+		if err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+func planNetworkReconcile(desired []Network, live []liveNetwork, updateSecrets bool) *ReconcilePlan {
+	plan := &ReconcilePlan{Operations: make([]ReconcileOperation, 0), Unchanged: make([]dbus.ObjectPath, 0)}
+	matches := make([]int, len(desired))
+	used := make([]bool, len(live))
+	for i := range desired {
+		matches[i] = -1
+	}
+	matchPass := func(key func(fields map[string]string) string) {
+		for i := range desired {
+			if matches[i] != -1 {
+				continue
+			}
+			desiredKey := key(desiredFieldValues(desired[i]))
+			for j := range live {
+				if !used[j] && key(liveFieldValues(live[j], desired[i])) == desiredKey {
+					matches[i] = j
+					used[j] = true
+					break
+				}
+			}
+		}
+	}
+	matchPass(func(fields map[string]string) string {
+		return fields["ssid"] + "\x00" + fields["identity"] + "\x00" + fields["priority"]
+	})
+	matchPass(func(fields map[string]string) string {
+		return fields["ssid"] + "\x00" + fields["identity"]
+	})
+
+	for j := range live {
+		if !used[j] {
+			plan.Operations = append(plan.Operations, ReconcileOperation{Action: ReconcileRemove, NetworkPath: live[j].path})
+		}
+	}
+	additions := make([]ReconcileOperation, 0)
+	for i := range desired {
+		network := desired[i]
+		if matches[i] == -1 {
+			additions = append(additions, ReconcileOperation{Action: ReconcileAdd, Network: &network})
+			continue
+		}
+		stale := staleNetworkFields(network, live[matches[i]].properties)
+		if len(stale) > 0 {
+			plan.Operations = append(plan.Operations, ReconcileOperation{
+				Action:      ReconcileReplace,
+				NetworkPath: live[matches[i]].path,
+				Network:     &network,
+				StaleFields: stale,
+			})
+			continue
+		}
+		changed := changedNetworkFields(network, live[matches[i]], updateSecrets)
+		if len(changed) == 0 {
+			plan.Unchanged = append(plan.Unchanged, live[matches[i]].path)
+			continue
+		}
+		plan.Operations = append(plan.Operations, ReconcileOperation{
+			Action:      ReconcileUpdate,
+			NetworkPath: live[matches[i]].path,
+			Network:     &network,
+			Properties:  configFieldsToDBusArgs(changed),
+		})
+	}
+	plan.Operations = append(plan.Operations, additions...)
+	return plan
+}
+
+// changedNetworkFields returns the fields of network that differ from the live network. Secrets
+// are only returned with updateSecrets since their live value is unknown.
+func changedNetworkFields(network Network, live liveNetwork, updateSecrets bool) []configField {
+	changed := make([]configField, 0)
+	for _, field := range network.allConfigFields() {
+		if contains(secretConfigKeys, field.key) {
+			if updateSecrets {
+				changed = append(changed, field)
+			}
+			continue
+		}
+		value, ok := live.properties[field.key]
+		if !ok || !configValueMatches(field, value) {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+func desiredFieldValues(network Network) map[string]string {
+	values := map[string]string{"priority": "0"}
+	for _, field := range network.allConfigFields() {
+		values[field.key] = field.value
+	}
+	return values
+}
+
+// liveFieldValues decodes the live properties in the encoding of the matching fields of network
+func liveFieldValues(live liveNetwork, network Network) map[string]string {
+	fields := make(map[string]configField)
+	for _, field := range network.allConfigFields() {
+		fields[field.key] = field
+	}
+	values := map[string]string{"priority": "0"}
+	for key, value := range live.properties {
+		values[key] = liveConfigValue(fields[key], value)
+	}
+	return values
+}
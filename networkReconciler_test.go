@@ -0,0 +1,82 @@
+package wpaSuppDBusLib
+
+import (
+	"testing"
+)
+
+func TestPlanNetworkReconcile(t *testing.T) {
+	home, _ := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithPSK("very secret passphrase").WithPriority(5).Build()
+	office, _ := NewNetworkBuilder().WithSSID("office").WithKeyManagement(WpaPSK).WithPSK("another passphrase").WithPriority(2).Build()
+	guest, _ := NewNetworkBuilder().WithSSID("guest").WithKeyManagement(NONE).Build()
+	live := []liveNetwork{
+		{path: "/net/0", properties: map[string]string{"ssid": "\"home\"", "key_mgmt": "WPA-PSK", "priority": "5"}},
+		{path: "/net/1", properties: map[string]string{"ssid": "\"office\"", "key_mgmt": "WPA-PSK", "priority": "1"}},
+		{path: "/net/2", properties: map[string]string{"ssid": "\"old\"", "key_mgmt": "NONE", "priority": "0"}},
+	}
+
+	plan := planNetworkReconcile([]Network{*home, *office, *guest}, live, false)
+	if len(plan.Unchanged) != 1 || plan.Unchanged[0] != "/net/0" {
+		t.Errorf("unexpected unchanged networks %v", plan.Unchanged)
+	}
+	if len(plan.Operations) != 3 {
+		t.Fatalf("unexpected operations %v", plan.Operations)
+	}
+	remove, update, add := plan.Operations[0], plan.Operations[1], plan.Operations[2]
+	if remove.Action != ReconcileRemove || remove.NetworkPath != "/net/2" {
+		t.Errorf("unexpected remove %v", remove)
+	}
+	if update.Action != ReconcileUpdate || update.NetworkPath != "/net/1" || len(update.Properties) != 1 || update.Properties["priority"] != int32(2) {
+		t.Errorf("unexpected update %v", update)
+	}
+	if add.Action != ReconcileAdd || add.Network.ssid != "guest" {
+		t.Errorf("unexpected add %v", add)
+	}
+
+	plan = planNetworkReconcile([]Network{*home}, live[:1], true)
+	if len(plan.Operations) != 1 || plan.Operations[0].Properties["psk"] != "very secret passphrase" {
+		t.Errorf("secrets must be written with update secrets %v", plan.Operations)
+	}
+}
+
+func TestPlanNetworkReconcileListsAndStaleFields(t *testing.T) {
+	home, _ := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK, FtPSK).WithProto(WPAProto, WPA2Proto).WithPairWise(PairWiseCCMP, PairWiseTKIP).WithPSK("very secret passphrase").Build()
+	live := []liveNetwork{
+		{path: "/net/0", properties: map[string]string{"ssid": "\"home\"", "key_mgmt": "FT-PSK WPA-PSK", "proto": "RSN WPA", "pairwise": "TKIP CCMP"}},
+	}
+
+	plan := planNetworkReconcile([]Network{*home}, live, false)
+	if len(plan.Operations) != 0 || len(plan.Unchanged) != 1 {
+		t.Errorf("reordered lists must match %v", plan.Operations)
+	}
+	if !networksMatch([]Network{*home}, []map[string]string{live[0].properties}) {
+		t.Errorf("reordered lists must match")
+	}
+
+	live[0].properties["bgscan"] = "\"simple:30:-45:300\""
+	plan = planNetworkReconcile([]Network{*home}, live, false)
+	if len(plan.Operations) != 1 {
+		t.Fatalf("unexpected operations %v", plan.Operations)
+	}
+	replace := plan.Operations[0]
+	if replace.Action != ReconcileReplace || replace.NetworkPath != "/net/0" || len(replace.StaleFields) != 1 || replace.StaleFields[0] != "bgscan" {
+		t.Errorf("unexpected replace %v", replace)
+	}
+	if networksMatch([]Network{*home}, []map[string]string{live[0].properties}) {
+		t.Errorf("stale fields must not match")
+	}
+}
+
+func TestPlanNetworkReconcileHexStrings(t *testing.T) {
+	cafe, _ := NewNetworkBuilder().WithSSID("Café").WithKeyManagement(WpaPSK).WithPSK("very secret passphrase").Build()
+	live := []liveNetwork{
+		{path: "/net/0", properties: map[string]string{"ssid": "436166c3a9", "key_mgmt": "WPA-PSK"}},
+	}
+
+	plan := planNetworkReconcile([]Network{*cafe}, live, false)
+	if len(plan.Operations) != 0 || len(plan.Unchanged) != 1 {
+		t.Errorf("hex encoded ssid must match %v", plan.Operations)
+	}
+	if !networksMatch([]Network{*cafe}, []map[string]string{live[0].properties}) {
+		t.Errorf("hex encoded ssid must match")
+	}
+}
@@ -0,0 +1,73 @@
+package wpaSuppDBusLib
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+// PSKStorage selects how a psk passphrase is written to generated configs
+type PSKStorage int
+
+const (
+	// PSKStoragePassphrase writes the passphrase as it is
+	PSKStoragePassphrase PSKStorage = 0
+	// PSKStoragePMK writes the 256-bit key derived from passphrase and SSID instead of the passphrase
+	PSKStoragePMK PSKStorage = 1
+)
+
+var pskStorageSlice = []PSKStorage{PSKStoragePassphrase, PSKStoragePMK}
+
+// DerivePSK derives the 256-bit WPA pre-shared key from an 8 to 63 character ASCII passphrase and
+// the SSID with PBKDF2-HMAC-SHA1 and 4096 iterations, like wpa_passphrase(8). The key is returned
+// as 64 hex digits and can be used as psk instead of the passphrase.
+func DerivePSK(ssid, passphrase string) (string, error) {
+	if ssid == "" || len(ssid) > maxSSIDLength {
+		return "", errors.New("invalid value for ssid. must be 1 to 32 bytes")
+	}
+	if !isPassphrase(passphrase) {
+		return "", errors.New("invalid value for passphrase. must be 8 to 63 ascii characters")
+	}
+	return hex.EncodeToString(pbkdf2SHA1([]byte(passphrase), []byte(ssid), 4096, 32)), nil
+}
+
+// pbkdf2SHA1 implements PBKDF2 (RFC 8018) with HMAC-SHA1 as pseudorandom function
+func pbkdf2SHA1(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha1.New, password)
+	blocks := (keyLen + prf.Size() - 1) / prf.Size()
+	key := make([]byte, 0, blocks*prf.Size())
+	counter := make([]byte, 4)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter, uint32(block))
+		prf.Write(counter)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// isExternalName reports whether name can be used in an ext: reference of ext_password_backend
+func isExternalName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] <= ' ' || name[i] > '~' || name[i] == '"' || name[i] == '#' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package wpaSuppDBusLib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDerivePSK(t *testing.T) {
+	// test vectors of IEEE 802.11i-2004 annex H.4
+	vectors := []struct {
+		ssid, passphrase, psk string
+	}{
+		{"IEEE", "password", "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e"},
+		{"ThisIsASSID", "ThisIsAPassword", "0dc0d6eb90555ed6419756b9a15ec3e3209b63df707dd508d14581f8982721af"},
+	}
+	for _, vector := range vectors {
+		psk, err := DerivePSK(vector.ssid, vector.passphrase)
+		if err != nil || psk != vector.psk {
+			t.Errorf("DerivePSK(%q, %q) = %s, %v", vector.ssid, vector.passphrase, psk, err)
+		}
+	}
+	if _, err := DerivePSK("IEEE", "short"); err == nil {
+		t.Errorf("short passphrase must be rejected")
+	}
+}
+
+func TestNetworkPSKStorage(t *testing.T) {
+	network, err := NewNetworkBuilder().WithSSID("IEEE").WithKeyManagement(WpaPSK).WithPSK("password").WithPSKStorage(PSKStoragePMK).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := network.ToConfigString()
+	if !strings.Contains(config, "  psk=f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e\n") || strings.Contains(config, "password") {
+		t.Errorf("pmk must replace the passphrase: %s", config)
+	}
+	if _, err := NewNetworkBuilder().WithSSID("home").WithKeyManagement(SAE).WithPSK("password").WithPSKStorage(PSKStoragePMK).Build(); err == nil {
+		t.Errorf("sae with pmk storage must be rejected")
+	}
+
+	network, err = NewNetworkBuilder().WithSSID("home").WithKeyManagement(SAE).WithSAEPassword("a long sae password").WithSAEPasswordID("guest").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(network.ToConfigString(), "  sae_password=\"a long sae password\"\n  sae_password_id=\"guest\"\n") {
+		t.Errorf("unexpected sae config: %s", network.ToConfigString())
+	}
+	if _, err := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithPSK("password").WithSAEPasswordID("guest").Build(); err == nil {
+		t.Errorf("sae password id without sae must be rejected")
+	}
+
+	network, err = NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithExternalPSK("home-psk").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(network.ToConfigString(), "  psk=ext:home-psk\n") {
+		t.Errorf("unexpected external psk: %s", network.ToConfigString())
+	}
+	if err := checkDBusSupported(network.allConfigFields()); err == nil {
+		t.Errorf("ext: references must not be sent over D-Bus")
+	}
+	if _, err := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithExternalPSK("home psk").Build(); err == nil {
+		t.Errorf("external psk name with space must be rejected")
+	}
+}
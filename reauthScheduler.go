@@ -0,0 +1,97 @@
+package wpaSuppDBusLib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"time"
+)
+
+// ReauthScheduler periodically restarts the IEEE 802.1X session of an interface with an
+// EAPOL-Logoff/EAPOL-Start pair and, optionally, does so every time the link comes back up.
+// It is meant for DriverWired interfaces where switches drop the session when a port flaps.
+type ReauthScheduler struct {
+	wpaDbus  *WpaSupplicantDbus
+	ifPath   dbus.ObjectPath
+	ifname   string
+	interval time.Duration
+	onLinkUp bool
+}
+
+// NewReauthScheduler creates a scheduler for the given supplicant interface. An interval of zero
+// disables periodic re-authentication, onLinkUp enables re-authentication on link-up events.
+func NewReauthScheduler(wpaDbus *WpaSupplicantDbus, wpaInterfaceName dbus.ObjectPath, interval time.Duration, onLinkUp bool) (*ReauthScheduler, error) {
+	if interval < 0 {
+		return nil, errors.New("invalid value for reauth interval")
+	}
+	if interval == 0 && !onLinkUp {
+		return nil, errors.New("no reauth trigger configured. set an interval or enable link-up reauth")
+	}
+	wpaDbus.mutex.Lock()
+	ifConfig, known := wpaDbus.interfaceConfigs[string(wpaInterfaceName)]
+	wpaDbus.mutex.Unlock()
+	ifname := ifConfig.ifname
+	if !known {
+		err := readInterfaceProperty(wpaDbus, wpaInterfaceName, "Ifname", &ifname)
+		if err != nil {
+			return nil, err
+		}
+	}
+	scheduler := ReauthScheduler{
+		wpaDbus:  wpaDbus,
+		ifPath:   wpaInterfaceName,
+		ifname:   ifname,
+		interval: interval,
+		onLinkUp: onLinkUp,
+	}
+	return &scheduler, nil
+}
+
+// Run re-authenticates on the configured triggers until ctx is cancelled
+func (s *ReauthScheduler) Run(ctx context.Context) error {
+	var linkUpChan <-chan struct{}
+	if s.onLinkUp {
+		var err error
+		linkUpChan, err = watchLinkUp(ctx, s.ifname)
+		if err != nil {
+			return err
+		}
+	}
+	var tickChan <-chan time.Time
+	if s.interval > 0 {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		tickChan = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tickChan:
+			s.reauthenticate("reauth interval elapsed")
+		case _, ok := <-linkUpChan:
+			if !ok {
+				return errors.New("link monitor for " + s.ifname + " stopped")
+			}
+			s.reauthenticate("link up")
+		}
+	}
+}
+
+// Reauthenticate sends an EAPOL-Logoff followed by a fresh EAPOL-Start
+func (s *ReauthScheduler) Reauthenticate() error {
+	err := eapLogoff(s.wpaDbus, s.ifPath)
+	if err != nil {
+		return err
+	}
+	return eapLogon(s.wpaDbus, s.ifPath)
+}
+
+func (s *ReauthScheduler) reauthenticate(reason string) {
+	s.wpaDbus.logger.Info(fmt.Sprintf("re-authenticating %s: %s", s.ifname, reason))
+	err := s.Reauthenticate()
+	if err != nil {
+		s.wpaDbus.logger.Error(fmt.Sprintf("unable to re-authenticate %s: %v", s.ifname, err))
+	}
+}
@@ -0,0 +1,31 @@
+package wpaSuppDBusLib
+
+import (
+	"github.com/godbus/dbus/v5"
+	"testing"
+	"time"
+)
+
+func TestNewReauthScheduler(t *testing.T) {
+	ifPath := dbus.ObjectPath("/fi/w1/wpa_supplicant1/Interfaces/0")
+	wpaDbus := &WpaSupplicantDbus{
+		logger:           newDefaultLogger(),
+		interfaceConfigs: map[string]interfaceConfig{string(ifPath): {ifname: "eth0", driver: DriverWired}},
+	}
+	if _, err := NewReauthScheduler(wpaDbus, ifPath, -time.Second, true); err == nil {
+		t.Errorf("negative interval must be rejected")
+	}
+	if _, err := NewReauthScheduler(wpaDbus, ifPath, 0, false); err == nil {
+		t.Errorf("scheduler without trigger must be rejected")
+	}
+	scheduler, err := NewReauthScheduler(wpaDbus, ifPath, time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheduler.ifname != "eth0" || scheduler.interval != time.Hour || scheduler.onLinkUp {
+		t.Errorf("unexpected scheduler %+v", scheduler)
+	}
+	if _, err = NewReauthScheduler(wpaDbus, ifPath, 0, true); err != nil {
+		t.Errorf("link-up only scheduler rejected: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package wpaSuppDBusLib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces secrets in redacted config strings, documents and log messages
+const redactedValue = "REDACTED"
+
+var secretKeysPattern = strings.Join(secretConfigKeys, "|")
+
+// redactPatterns match secrets rendered as config lines (psk="...", password=P"..."), JSON members
+// ("psk":"...") and YAML or Go map entries (psk: ...). Quoted config values run to the last quote
+// of the line like wpa_supplicant reads them.
+var redactPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(^|[\s{\[,])(` + secretKeysPattern + `)=(P?"[^\n]*"|[^\s\],]*)`), `${1}${2}="` + redactedValue + `"`},
+	{regexp.MustCompile(`"(` + secretKeysPattern + `)":\s*"(?:[^"\\]|\\.)*"`), `"${1}":"` + redactedValue + `"`},
+	{regexp.MustCompile(`(^|[\s{\[,])(` + secretKeysPattern + `): ?([^\s\],]+)`), `${1}${2}: ` + redactedValue},
+}
+
+// RedactSecrets masks the values of password, psk, private_key_passwd, pin, sae_password, mka_cak
+// and the other secret keys in text. It understands config files, JSON and YAML, which covers the
+// output of ToConfigString and the document types.
+func RedactSecrets(text string) string {
+	for _, redact := range redactPatterns {
+		text = redact.pattern.ReplaceAllString(text, redact.replacement)
+	}
+	return text
+}
+
+// errRedactedSecret is returned when a redacted document is decoded, building it would silently
+// replace the real secret with the placeholder
+var errRedactedSecret = errors.New("document contains redacted secrets")
+
+func checkNotRedacted(secrets ...string) error {
+	for _, secret := range secrets {
+		if secret == redactedValue {
+			return errRedactedSecret
+		}
+	}
+	return nil
+}
+
+func redactString(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+func (doc InterfaceDocument) redacted() InterfaceDocument {
+	networks := make([]NetworkDocument, 0, len(doc.Networks))
+	for _, network := range doc.Networks {
+		networks = append(networks, network.redacted())
+	}
+	credentials := make([]CredentialDocument, 0, len(doc.Credentials))
+	for _, credential := range doc.Credentials {
+		credentials = append(credentials, credential.redacted())
+	}
+	doc.Networks, doc.Credentials = networks, credentials
+	return doc
+}
+
+func (doc NetworkDocument) redacted() NetworkDocument {
+	doc.PSK = redactString(doc.PSK)
+	doc.SAEPassword = redactString(doc.SAEPassword)
+	eap := make([]EAPDocument, 0, len(doc.EAP))
+	for _, method := range doc.EAP {
+		eap = append(eap, method.redacted())
+	}
+	doc.EAP = eap
+	if doc.Macsec != nil {
+		macsec := *doc.Macsec
+		macsec.MKACAK = redactString(macsec.MKACAK)
+		doc.Macsec = &macsec
+	}
+	return doc
+}
+
+func (doc EAPDocument) redacted() EAPDocument {
+	doc.Password = redactString(doc.Password)
+	doc.PasswordHash = redactString(doc.PasswordHash)
+	doc.PrivateKeyPassword = redactString(doc.PrivateKeyPassword)
+	return doc
+}
+
+func (doc CredentialDocument) redacted() CredentialDocument {
+	doc.Password = redactString(doc.Password)
+	doc.PrivateKeyPassword = redactString(doc.PrivateKeyPassword)
+	return doc
+}
+
+// Secrets returns every secret value configured on the interface, e.g. to seed a RedactingLogger
+func (wpa *WPAInterface) Secrets() []string {
+	doc := wpa.ToDocument()
+	secrets := make([]string, 0)
+	add := func(values ...string) {
+		for _, value := range values {
+			if value != "" {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	for _, network := range doc.Networks {
+		add(network.PSK, network.SAEPassword)
+		for _, method := range network.EAP {
+			add(method.Password, method.PasswordHash, method.PrivateKeyPassword)
+		}
+		if network.Macsec != nil {
+			add(network.Macsec.MKACAK)
+		}
+	}
+	for _, credential := range doc.Credentials {
+		add(credential.Password, credential.PrivateKeyPassword)
+	}
+	return secrets
+}
+
+// ToRedactedConfigString renders the config like ToConfigString with all secrets masked, it is
+// safe to log or to put into support bundles
+func (wpa *WPAInterface) ToRedactedConfigString() string {
+	return RedactSecrets(wpa.ToConfigString())
+}
+
+func (wpa WPAInterface) String() string {
+	return wpa.ToRedactedConfigString()
+}
+
+func (wpa WPAInterface) GoString() string {
+	return goStringDocument(wpa, wpa.ToDocument().redacted())
+}
+
+// ToRedactedConfigString renders the network block like ToConfigString with all secrets masked
+func (net *Network) ToRedactedConfigString() string {
+	return RedactSecrets(net.ToConfigString())
+}
+
+func (net Network) String() string {
+	return net.ToRedactedConfigString()
+}
+
+func (net Network) GoString() string {
+	return goStringDocument(net, net.ToDocument().redacted())
+}
+
+// ToRedactedConfigString renders the cred block like ToConfigString with all secrets masked
+func (c *Credential) ToRedactedConfigString() string {
+	return RedactSecrets(c.ToConfigString())
+}
+
+func (c Credential) String() string {
+	return c.ToRedactedConfigString()
+}
+
+func (c Credential) GoString() string {
+	return goStringDocument(c, c.ToDocument().redacted())
+}
+
+func (t *tlsMethod) ToRedactedConfigString() string {
+	return RedactSecrets(t.ToConfigString())
+}
+
+func (t *tlsMethod) String() string {
+	return t.ToRedactedConfigString()
+}
+
+func (t *tlsMethod) GoString() string {
+	return goStringDocument(t, t.toDocument().redacted())
+}
+
+func (t *tlsMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.toDocument().redacted())
+}
+
+func (p *peapMethod) ToRedactedConfigString() string {
+	return RedactSecrets(p.ToConfigString())
+}
+
+func (p *peapMethod) String() string {
+	return p.ToRedactedConfigString()
+}
+
+func (p *peapMethod) GoString() string {
+	return goStringDocument(p, p.toDocument().redacted())
+}
+
+func (p *peapMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.toDocument().redacted())
+}
+
+func (t *ttlsMethod) ToRedactedConfigString() string {
+	return RedactSecrets(t.ToConfigString())
+}
+
+func (t *ttlsMethod) String() string {
+	return t.ToRedactedConfigString()
+}
+
+func (t *ttlsMethod) GoString() string {
+	return goStringDocument(t, t.toDocument().redacted())
+}
+
+func (t *ttlsMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.toDocument().redacted())
+}
+
+func (m *md5EapMethod) ToRedactedConfigString() string {
+	return RedactSecrets(m.ToConfigString())
+}
+
+func (m *md5EapMethod) String() string {
+	return m.ToRedactedConfigString()
+}
+
+func (m *md5EapMethod) GoString() string {
+	return goStringDocument(m, m.toDocument().redacted())
+}
+
+func (m *md5EapMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.toDocument().redacted())
+}
+
+// goStringDocument formats the redacted document of value for %#v
+func goStringDocument(value interface{}, doc interface{}) string {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Sprintf("%T{%v}", value, err)
+	}
+	return fmt.Sprintf("%T(%s)", value, data)
+}
+
+// RedactingLogger wraps a Logger and scrubs secrets from every message before passing it on.
+// Config lines, JSON and YAML secrets are always masked, literal values registered with
+// AddSecrets are masked wherever they appear.
+type RedactingLogger struct {
+	logger  Logger
+	secrets []string
+	mutex   sync.RWMutex
+}
+
+func NewRedactingLogger(logger Logger, secrets ...string) *RedactingLogger {
+	redacting := RedactingLogger{logger: logger}
+	redacting.AddSecrets(secrets...)
+	return &redacting
+}
+
+// AddSecrets registers literal secret values, e.g. the result of WPAInterface.Secrets
+func (l *RedactingLogger) AddSecrets(secrets ...string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, secret := range secrets {
+		if secret != "" && secret != redactedValue && !contains(l.secrets, secret) {
+			l.secrets = append(l.secrets, secret)
+		}
+	}
+}
+
+func (l *RedactingLogger) redact(args []interface{}) string {
+	text := RedactSecrets(fmt.Sprint(args...))
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	for _, secret := range l.secrets {
+		text = strings.ReplaceAll(text, secret, redactedValue)
+	}
+	return text
+}
+
+func (l *RedactingLogger) Info(args ...interface{}) {
+	l.logger.Info(l.redact(args))
+}
+
+func (l *RedactingLogger) Warn(args ...interface{}) {
+	l.logger.Warn(l.redact(args))
+}
+
+func (l *RedactingLogger) Error(args ...interface{}) {
+	l.logger.Error(l.redact(args))
+}
@@ -0,0 +1,78 @@
+package wpaSuppDBusLib
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Info(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *recordingLogger) Warn(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *recordingLogger) Error(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func TestRedactedOutputs(t *testing.T) {
+	tlsBuilder := NewTLSBuilder()
+	eapTLS, _ := tlsBuilder.WithIdentity("user").WithCaCertPath("/ca.pem").WithClientCertPath("/client.pem").
+		WithPrivateKeyPath("/client.key").WithPrivateKeyPassword("key secret").Build()
+	wired, _ := NewNetworkBuilder().WithKeyManagement(IEEE8021X).WithEAPMethods(eapTLS).Build()
+	home, _ := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithPSK("psk secret").Build()
+	wpa, err := NewWpaInterfaceBuilder().WithNetwork(*wired, *home).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonData, _ := json.Marshal(wpa)
+	yamlData, _ := yaml.Marshal(wpa)
+	outputs := map[string]string{
+		"config":     wpa.ToRedactedConfigString(),
+		"string":     fmt.Sprint(wpa),
+		"gostring":   fmt.Sprintf("%#v", wpa),
+		"json":       string(jsonData),
+		"yaml":       string(yamlData),
+		"eap string": fmt.Sprint(eapTLS),
+	}
+	for name, output := range outputs {
+		if strings.Contains(output, "secret") {
+			t.Errorf("%s leaks a secret: %s", name, output)
+		}
+		if !strings.Contains(output, redactedValue) {
+			t.Errorf("%s is not redacted: %s", name, output)
+		}
+	}
+	if !strings.Contains(wpa.ToRedactedConfigString(), "private_key=\"/client.key\"") {
+		t.Errorf("non secret fields must be kept")
+	}
+
+	var decoded WPAInterface
+	if err := json.Unmarshal(jsonData, &decoded); err == nil {
+		t.Errorf("redacted documents must not decode")
+	}
+}
+
+func TestRedactingLogger(t *testing.T) {
+	recorder := &recordingLogger{}
+	logger := NewRedactingLogger(recorder, "hunter22")
+	logger.Info("network={\n  psk=\"psk secret\"\n}")
+	logger.Warn(`{"password":"pw \"secret\""}`)
+	logger.Error("login with hunter22 failed")
+
+	for _, message := range recorder.messages {
+		if strings.Contains(message, "secret") || strings.Contains(message, "hunter22") {
+			t.Errorf("message leaks a secret: %s", message)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package wpaSuppDBusLib
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SecretSource resolves the names of ext: references to the secret values, like the
+// ext_password_backend of wpa_supplicant does. It lets a config using external secrets be
+// previewed and validated without the secrets ever being written to it.
+type SecretSource interface {
+	LookupSecret(name string) (string, error)
+}
+
+// StaticSecretSource is a SecretSource backed by a map of names to secrets
+type StaticSecretSource map[string]string
+
+func (s StaticSecretSource) LookupSecret(name string) (string, error) {
+	secret, ok := s[name]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found", name)
+	}
+	return secret, nil
+}
+
+// NewFileSecretSource reads a password file of the file ext_password_backend: one name=password
+// per line, empty lines and lines starting with # are skipped
+func NewFileSecretSource(path string) (StaticSecretSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	source := make(StaticSecretSource)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		separator := strings.Index(text, "=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid line, expected name=password", path, line)
+		}
+		source[text[:separator]] = text[separator+1:]
+	}
+	return source, scanner.Err()
+}
+
+// ResolveSecrets returns a copy of the interface with every ext: reference replaced by the secret
+// source resolves it to. The copy is validated like a newly built interface, so it can be used to
+// check the secrets or, together with ToRedactedConfigString, to preview the effective config. The
+// interface itself is not changed.
+func (wpa *WPAInterface) ResolveSecrets(source SecretSource) (*WPAInterface, error) {
+	doc := wpa.ToDocument()
+	var errs MultiError
+	resolve := func(name *string, secret *string) {
+		if *name == "" {
+			return
+		}
+		value, err := source.LookupSecret(*name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to resolve ext:%s: %w", *name, err))
+			return
+		}
+		*name, *secret = "", value
+	}
+	for i := range doc.Networks {
+		network := &doc.Networks[i]
+		resolve(&network.ExternalPSK, &network.PSK)
+		for j := range network.EAP {
+			method := &network.EAP[j]
+			resolve(&method.ExternalPassword, &method.Password)
+		}
+	}
+	if err := errs.errorOrNil(); err != nil {
+		return nil, err
+	}
+	return doc.Build()
+}
+
+// usesExternalSecrets reports whether any of the fields is an ext: reference
+func usesExternalSecrets(fields []configField) bool {
+	for _, field := range fields {
+		if !field.quoted && strings.HasPrefix(field.value, "ext:") {
+			return true
+		}
+	}
+	return false
+}
+
+// countSet returns how many of values are not empty
+func countSet(values ...string) int {
+	count := 0
+	for _, value := range values {
+		if value != "" {
+			count++
+		}
+	}
+	return count
+}
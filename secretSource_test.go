@@ -0,0 +1,105 @@
+package wpaSuppDBusLib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExternalSecrets(t *testing.T) {
+	peapBuilder := NewPEAPBuilder()
+	peap, err := peapBuilder.WithIdentity("user").WithExternalPassword("corp").WithInnerAuthType(InnerAuthMsChapV2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsBuilder := NewTLSBuilder()
+	tls, err := tlsBuilder.WithIdentity("device").WithClientCertPath("/client.pem").WithPrivateKeyPath("/client.key").
+		WithPrivateKeyPassword("key password").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	corp, _ := NewNetworkBuilder().WithSSID("corp").WithKeyManagement(WpaEAP).WithEAPMethods(peap).Build()
+	wired, _ := NewNetworkBuilder().WithKeyManagement(IEEE8021X).WithEAPMethods(tls).Build()
+	home, _ := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithExternalPSK("home").Build()
+
+	if _, err := NewWpaInterfaceBuilder().WithNetwork(*corp, *wired, *home).Build(); err == nil {
+		t.Errorf("ext: references without backend must be rejected")
+	}
+	wpa, err := NewWpaInterfaceBuilder().WithExtPasswordBackend(ExtPasswordBackendFile, "/etc/wpa_supplicant/passwords").
+		WithNetwork(*corp, *wired, *home).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := wpa.ToConfigString()
+	for _, line := range []string{"ext_password_backend=file:/etc/wpa_supplicant/passwords\n", "  password=ext:corp\n",
+		"  psk=ext:home\n"} {
+		if !strings.Contains(config, line) {
+			t.Errorf("%q missing from config:\n%s", line, config)
+		}
+	}
+
+	passwords := filepath.Join(t.TempDir(), "passwords")
+	content := "# wpa_supplicant passwords\ncorp=corp password\nhome=home passphrase\n"
+	if err := os.WriteFile(passwords, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewFileSecretSource(passwords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := wpa.ResolveSecrets(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = resolved.ToConfigString()
+	for _, line := range []string{"  password=\"corp password\"\n", "  private_key_passwd=\"key password\"\n", "  psk=\"home passphrase\"\n"} {
+		if !strings.Contains(config, line) {
+			t.Errorf("%q missing from resolved config:\n%s", line, config)
+		}
+	}
+	if strings.Contains(wpa.ToConfigString(), "corp password") {
+		t.Errorf("resolving must not change the interface")
+	}
+
+	if _, err := wpa.ResolveSecrets(StaticSecretSource{"corp": "corp password"}); err == nil {
+		t.Errorf("missing secrets must be reported")
+	}
+	if _, err := wpa.ResolveSecrets(StaticSecretSource{"corp": "pw", "home": "short"}); err == nil {
+		t.Errorf("resolved secrets must be validated")
+	}
+}
+
+func TestExternalSecretKeys(t *testing.T) {
+	peapBuilder := NewPEAPBuilder()
+	peap, _ := peapBuilder.WithIdentity("user").WithExternalPassword("peap").WithInnerAuthType(InnerAuthMsChapV2).Build()
+	ttlsBuilder := NewTTLSBuilder()
+	ttls, _ := ttlsBuilder.WithIdentity("user").WithExternalPassword("ttls").WithInnerAuthType(InnerAuthMsChapV2).Build()
+	md5Builder := NewMd5EApBuilder()
+	md5, _ := md5Builder.WithUsername("user").WithExternalPassword("md5").Build()
+	home, _ := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithExternalPSK("home").Build()
+	networks := []*Network{home}
+	for _, method := range []eapMethod{peap, ttls, md5} {
+		if method == nil {
+			t.Fatal("unable to build eap method with external password")
+		}
+		network, _ := NewNetworkBuilder().WithSSID("corp").WithKeyManagement(WpaEAP).WithEAPMethods(method).Build()
+		networks = append(networks, network)
+	}
+
+	references := 0
+	for _, network := range networks {
+		for _, field := range network.allConfigFields() {
+			if field.quoted || !strings.HasPrefix(field.value, "ext:") {
+				continue
+			}
+			references++
+			if field.key != "psk" && field.key != "password" {
+				t.Errorf("wpa_supplicant does not resolve ext: references of %s", field.key)
+			}
+		}
+	}
+	if references != len(networks) {
+		t.Errorf("expected %d ext: references, got %d", len(networks), references)
+	}
+}
@@ -0,0 +1,380 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type CredentialEAP string
+type OCSP int8
+
+const (
+	CredentialEAPTTLS     CredentialEAP = "TTLS"
+	CredentialEAPTLS      CredentialEAP = "TLS"
+	CredentialEAPPEAP     CredentialEAP = "PEAP"
+	CredentialEAPSIM      CredentialEAP = "SIM"
+	CredentialEAPAKA      CredentialEAP = "AKA"
+	CredentialEAPAKAPrime CredentialEAP = "AKA'"
+	OCSPOff               OCSP          = 0
+	OCSPRequest           OCSP          = 1
+	OCSPRequire           OCSP          = 2
+	OCSPRequireAll        OCSP          = 3
+)
+
+var credentialEAPSlice = []CredentialEAP{CredentialEAPTTLS, CredentialEAPTLS, CredentialEAPPEAP, CredentialEAPSIM, CredentialEAPAKA, CredentialEAPAKAPrime}
+var credentialSIMEAPSlice = []CredentialEAP{CredentialEAPSIM, CredentialEAPAKA, CredentialEAPAKAPrime}
+var ocspSlice = []OCSP{OCSPOff, OCSPRequest, OCSPRequire, OCSPRequireAll}
+
+// Credential is a Hotspot 2.0 / Interworking credential, rendered as a cred={} block.
+// The supplicant matches credentials against the ANQP information of access points
+// and builds the network block on its own during interworking selection.
+type Credential struct {
+	realm                     string
+	username                  string
+	password                  string
+	caCertPath                string
+	clientCert                string
+	privateKey                string
+	privateKeyPassword        string
+	imsi                      string
+	milenage                  string
+	domain                    []string
+	roamingConsortium         string
+	requiredRoamingConsortium string
+	eap                       CredentialEAP
+	innerAuth                 innerAuthType
+	priority                  uint
+	provisioningSP            string
+	spPriority                int16
+	ocsp                      OCSP
+}
+
+func (c *Credential) configFields() []configField {
+	fields := make([]configField, 0)
+	if c.realm != "" {
+		fields = append(fields, quotedField("realm", c.realm))
+	}
+	if c.username != "" {
+		fields = append(fields, quotedField("username", c.username))
+	}
+	if c.password != "" {
+		fields = append(fields, quotedField("password", c.password))
+	}
+	if c.caCertPath != "" {
+		fields = append(fields, quotedField("ca_cert", c.caCertPath))
+	}
+	if c.clientCert != "" {
+		fields = append(fields, quotedField("client_cert", c.clientCert))
+	}
+	if c.privateKey != "" {
+		fields = append(fields, quotedField("private_key", c.privateKey))
+	}
+	if c.privateKeyPassword != "" {
+		fields = append(fields, quotedField("private_key_passwd", c.privateKeyPassword))
+	}
+	if c.imsi != "" {
+		fields = append(fields, quotedField("imsi", c.imsi))
+	}
+	if c.milenage != "" {
+		fields = append(fields, quotedField("milenage", c.milenage))
+	}
+	for i := 0; i < len(c.domain); i++ {
+		fields = append(fields, quotedField("domain", c.domain[i]))
+	}
+	if c.roamingConsortium != "" {
+		fields = append(fields, rawField("roaming_consortium", c.roamingConsortium))
+	}
+	if c.requiredRoamingConsortium != "" {
+		fields = append(fields, rawField("required_roaming_consortium", c.requiredRoamingConsortium))
+	}
+	if c.eap != "" {
+		fields = append(fields, rawField("eap", c.eap))
+	}
+	if c.innerAuth != "" {
+		fields = append(fields, quotedField("phase2", fmt.Sprintf("auth=%s", c.innerAuth)))
+	}
+	if c.priority != 0 {
+		fields = append(fields, intField("priority", c.priority))
+	}
+	if c.provisioningSP != "" {
+		fields = append(fields, quotedField("provisioning_sp", c.provisioningSP))
+	}
+	if c.spPriority != -1 {
+		fields = append(fields, intField("sp_priority", c.spPriority))
+	}
+	if c.ocsp != -1 {
+		fields = append(fields, intField("ocsp", c.ocsp))
+	}
+	return fields
+}
+
+func (c *Credential) ToConfigString() string {
+	builder := strings.Builder{}
+	builder.WriteString("cred={\n")
+	builder.WriteString(renderConfigFields("  ", c.configFields()))
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// toDBusArgs returns the AddCred arguments. A D-Bus dictionary cannot repeat a key so only
+// the first domain is sent; use the config file for credentials with several domains.
+func (c *Credential) toDBusArgs() map[string]interface{} {
+	fields := c.configFields()
+	args := configFieldsToDBusArgs(fields)
+	if len(c.domain) > 0 {
+		args["domain"] = c.domain[0]
+	}
+	return args
+}
+
+type credentialBuilder interface {
+	WithRealm(realm string) credentialBuilder
+	WithUsername(username string) credentialBuilder
+	WithPassword(password string) credentialBuilder
+	WithCaCertPath(caCertPath string) credentialBuilder
+	WithClientCertPath(clientCertPath string) credentialBuilder
+	WithPrivateKeyPath(privateKeyPath string) credentialBuilder
+	WithPrivateKeyPassword(password string) credentialBuilder
+	WithIMSI(imsi string) credentialBuilder
+	WithMilenage(milenage string) credentialBuilder
+	WithDomain(domain ...string) credentialBuilder
+	WithRoamingConsortium(oi string) credentialBuilder
+	WithRequiredRoamingConsortium(oi string) credentialBuilder
+	WithEAP(eap CredentialEAP) credentialBuilder
+	WithInnerAuthType(innerAuthType innerAuthType) credentialBuilder
+	WithPriority(prio uint) credentialBuilder
+	WithProvisioningSP(sp string) credentialBuilder
+	WithSPPriority(prio uint8) credentialBuilder
+	WithOCSP(ocsp OCSP) credentialBuilder
+	Build() (*Credential, error)
+}
+
+type CredentialBuilder struct {
+	realm                     string
+	username                  string
+	password                  string
+	caCertPath                string
+	clientCert                string
+	privateKey                string
+	privateKeyPassword        string
+	imsi                      string
+	milenage                  string
+	domain                    []string
+	roamingConsortium         string
+	requiredRoamingConsortium string
+	eap                       CredentialEAP
+	innerAuth                 innerAuthType
+	priority                  uint
+	provisioningSP            string
+	spPriority                int16
+	ocsp                      OCSP
+}
+
+func NewCredentialBuilder() credentialBuilder {
+	builder := CredentialBuilder{
+		spPriority: -1,
+		ocsp:       -1,
+	}
+	return &builder
+}
+
+// WithRealm Home Realm for Interworking, used to match the NAI Realm list of access points
+// and, for username/password and certificate credentials, as the realm of the identity
+func (b *CredentialBuilder) WithRealm(realm string) credentialBuilder {
+	b.realm = realm
+	return b
+}
+
+// WithUsername Username for Interworking network selection
+func (b *CredentialBuilder) WithUsername(username string) credentialBuilder {
+	b.username = username
+	return b
+}
+
+// WithPassword Password for Interworking network selection
+func (b *CredentialBuilder) WithPassword(password string) credentialBuilder {
+	b.password = password
+	return b
+}
+
+// WithCaCertPath CA certificate for Interworking network selection
+func (b *CredentialBuilder) WithCaCertPath(caCertPath string) credentialBuilder {
+	b.caCertPath = caCertPath
+	return b
+}
+
+// WithClientCertPath File path to client certificate file (PEM/DER), used for certificate credentials
+func (b *CredentialBuilder) WithClientCertPath(clientCertPath string) credentialBuilder {
+	b.clientCert = clientCertPath
+	return b
+}
+
+// WithPrivateKeyPath File path to client private key file (PEM/DER/PFX)
+func (b *CredentialBuilder) WithPrivateKeyPath(privateKeyPath string) credentialBuilder {
+	b.privateKey = privateKeyPath
+	return b
+}
+
+// WithPrivateKeyPassword Password for the private key file
+func (b *CredentialBuilder) WithPrivateKeyPassword(password string) credentialBuilder {
+	b.privateKeyPassword = password
+	return b
+}
+
+// WithIMSI IMSI in <MCC> | <MNC> | '-' | <MSIN> format, used for SIM/USIM credentials
+func (b *CredentialBuilder) WithIMSI(imsi string) credentialBuilder {
+	b.imsi = imsi
+	return b
+}
+
+// WithMilenage Milenage parameters for SIM/USIM simulator in <Ki>:<OPc>:<SQN> format
+func (b *CredentialBuilder) WithMilenage(milenage string) credentialBuilder {
+	b.milenage = milenage
+	return b
+}
+
+// WithDomain Home service provider FQDN(s). Used to determine whether the access point is
+// operated by the home operator or a roaming partner.
+func (b *CredentialBuilder) WithDomain(domain ...string) credentialBuilder {
+	b.domain = make([]string, 0)
+	b.domain = append(b.domain, domain...)
+	return b
+}
+
+// WithRoamingConsortium Roaming Consortium OI, given as 3 to 15 bytes in hex.
+// Credentials with an OI are also matched against the Roaming Consortium of access points.
+func (b *CredentialBuilder) WithRoamingConsortium(oi string) credentialBuilder {
+	b.roamingConsortium = oi
+	return b
+}
+
+// WithRequiredRoamingConsortium Required Roaming Consortium OI, given as 3 to 15 bytes in hex.
+// Only access points advertising this OI are considered for the credential.
+func (b *CredentialBuilder) WithRequiredRoamingConsortium(oi string) credentialBuilder {
+	b.requiredRoamingConsortium = oi
+	return b
+}
+
+// WithEAP Pre-configured EAP method. If not set the method is selected from the NAI Realm list.
+func (b *CredentialBuilder) WithEAP(eap CredentialEAP) credentialBuilder {
+	b.eap = eap
+	return b
+}
+
+// WithInnerAuthType Pre-configured phase 2 (inner authentication) parameters
+func (b *CredentialBuilder) WithInnerAuthType(innerAuthType innerAuthType) credentialBuilder {
+	b.innerAuth = innerAuthType
+	return b
+}
+
+// WithPriority Priority group. Higher values are preferred when several networks match.
+func (b *CredentialBuilder) WithPriority(prio uint) credentialBuilder {
+	b.priority = prio
+	return b
+}
+
+// WithProvisioningSP FQDN of the service provider that provisioned the credential
+func (b *CredentialBuilder) WithProvisioningSP(sp string) credentialBuilder {
+	b.provisioningSP = sp
+	return b
+}
+
+// WithSPPriority Credential priority within a provisioning SP, 0 being the highest priority (default 128)
+func (b *CredentialBuilder) WithSPPriority(prio uint8) credentialBuilder {
+	b.spPriority = int16(prio)
+	return b
+}
+
+// WithOCSP Whether to use/require OCSP to check the server certificate;
+// 0 (do not use, default); 1 (try OCSP stapling); 2 (require valid OCSP stapling response);
+// 3 (require valid OCSP stapling response for all not-trusted certificates in the chain).
+func (b *CredentialBuilder) WithOCSP(ocsp OCSP) credentialBuilder {
+	b.ocsp = ocsp
+	return b
+}
+
+func (b *CredentialBuilder) Build() (*Credential, error) {
+	err := b.validate()
+	if err != nil {
+		return nil, err
+	}
+	cred := Credential{
+		realm:                     b.realm,
+		username:                  b.username,
+		password:                  b.password,
+		caCertPath:                b.caCertPath,
+		clientCert:                b.clientCert,
+		privateKey:                b.privateKey,
+		privateKeyPassword:        b.privateKeyPassword,
+		imsi:                      b.imsi,
+		milenage:                  b.milenage,
+		domain:                    b.domain,
+		roamingConsortium:         b.roamingConsortium,
+		requiredRoamingConsortium: b.requiredRoamingConsortium,
+		eap:                       b.eap,
+		innerAuth:                 b.innerAuth,
+		priority:                  b.priority,
+		provisioningSP:            b.provisioningSP,
+		spPriority:                b.spPriority,
+		ocsp:                      b.ocsp,
+	}
+	return &cred, nil
+}
+
+func (b *CredentialBuilder) validate() error {
+	passwordCred := b.username != "" || b.password != ""
+	certCred := b.clientCert != "" || b.privateKey != ""
+	simCred := b.imsi != ""
+	if !passwordCred && !certCred && !simCred {
+		return errors.New("no credential specified. set username/password, client certificate or imsi")
+	}
+	if (passwordCred && certCred) || (passwordCred && simCred) || (certCred && simCred) {
+		return errors.New("only one credential type (username/password, client certificate or imsi) can be used")
+	}
+	if passwordCred && (b.username == "" || b.password == "") {
+		return errors.New("username and password must be specified together")
+	}
+	if certCred && (b.clientCert == "" || b.privateKey == "") {
+		return errors.New("client certificate and private key must be specified together")
+	}
+	if (passwordCred || certCred) && b.realm == "" {
+		return errors.New("realm is required for username/password and certificate credentials")
+	}
+	if b.milenage != "" && !simCred {
+		return errors.New("milenage requires imsi")
+	}
+	if b.eap != "" && !contains(credentialEAPSlice, b.eap) {
+		return errors.New("invalid value for eap")
+	}
+	if simCred && b.eap != "" && !contains(credentialSIMEAPSlice, b.eap) {
+		return errors.New("imsi credentials require eap SIM, AKA or AKA'")
+	}
+	if b.innerAuth != "" {
+		if !passwordCred {
+			return errors.New("inner auth requires username/password credentials")
+		}
+		if !contains(allowedTTLSInnerAuthTypes, b.innerAuth) {
+			return errors.New("invalid inner auth (wrong value)")
+		}
+	}
+	if b.roamingConsortium != "" && !isRoamingConsortiumOI(b.roamingConsortium) {
+		return errors.New("invalid value for roaming consortium. must be 3 to 15 bytes in hex")
+	}
+	if b.requiredRoamingConsortium != "" && !isRoamingConsortiumOI(b.requiredRoamingConsortium) {
+		return errors.New("invalid value for required roaming consortium. must be 3 to 15 bytes in hex")
+	}
+	for i := 0; i < len(b.domain); i++ {
+		if b.domain[i] == "" {
+			return errors.New("invalid value for domain (empty)")
+		}
+	}
+	if b.ocsp != -1 && !contains(ocspSlice, b.ocsp) {
+		return errors.New("invalid value for ocsp")
+	}
+	return nil
+}
+
+func isRoamingConsortiumOI(oi string) bool {
+	return isHexString(oi) && len(oi) >= 6 && len(oi) <= 30
+}
@@ -9,10 +9,16 @@ import (
 type EapolVersion uint8
 type ApScan uint8
 type FastReauth byte
+type Interworking byte
+type HS20 byte
+type AutoInterworking byte
+type UserMPM byte
+type OKC byte
 
 const (
 	EapolV1       EapolVersion = 1
 	EapolV2       EapolVersion = 2
+	EapolV3       EapolVersion = 3
 	ApScanOff     ApScan       = 0
 	ApScanOn      ApScan       = 1
 	ApScanOnV2    ApScan       = 2
@@ -20,23 +26,59 @@ const (
 	FastReauthOff FastReauth   = 0
 )
 
-var eapolVersionSlice = []EapolVersion{EapolV1, EapolV2}
+const (
+	InterworkingOff     Interworking     = 0
+	InterworkingOn      Interworking     = 1
+	HS20Off             HS20             = 0
+	HS20On              HS20             = 1
+	AutoInterworkingOff AutoInterworking = 0
+	AutoInterworkingOn  AutoInterworking = 1
+	UserMPMOff          UserMPM          = 0
+	UserMPMOn           UserMPM          = 1
+	OKCOff              OKC              = 0
+	OKCOn               OKC              = 1
+)
+
+var eapolVersionSlice = []EapolVersion{EapolV1, EapolV2, EapolV3}
 var apScanSlice = []ApScan{ApScanOn, ApScanOnV2, ApScanOff}
 var fastReauthSlice = []FastReauth{FastReauthOn, FastReauthOff}
+var interworkingSlice = []Interworking{InterworkingOff, InterworkingOn}
+var hs20Slice = []HS20{HS20Off, HS20On}
+var autoInterworkingSlice = []AutoInterworking{AutoInterworkingOff, AutoInterworkingOn}
+var userMPMSlice = []UserMPM{UserMPMOff, UserMPMOn}
+var okcSlice = []OKC{OKCOff, OKCOn}
 
 var defaultCtrInterface = "/var/run/wpa_supplicant"
 var defaultCtrInterfaceGroup = ""
 var defaultEapolVersion = EapolV1
 var defaultApScan = ApScanOn
 var defaultFastReauth = FastReauthOn
+var defaultDot11RSNAConfigPMKLifetime uint32 = 43200
+var defaultInterworking = InterworkingOff
+var defaultHS20 = HS20Off
+var defaultAutoInterworking = AutoInterworkingOff
+var defaultUserMPM = UserMPMOn
+var defaultMaxPeerLinks uint8 = 99
+var defaultMeshMaxInactivity uint32 = 300
+var defaultOKC = OKCOff
 
 type WPAInterface struct {
-	ctrlInterface      string       `json:"ctrl_interface"`
-	ctrlInterfaceGroup string       `json:"ctrl_interface_group,omitempty"`
-	eapolVersion       EapolVersion `json:"eapol_version,omitempty"`
-	apScan             ApScan       `json:"ap_scan,omitempty"`
-	fastReauth         FastReauth   `json:"fast_reauth,omitempty"`
-	network            []Network    `json:"network"`
+	ctrlInterface      string
+	ctrlInterfaceGroup string
+	eapolVersion       EapolVersion
+	apScan             ApScan
+	fastReauth         FastReauth
+	network            []Network
+	pmkLifetime        uint32
+	interworking       Interworking
+	hs20               HS20
+	autoInterworking   AutoInterworking
+	credential         []Credential
+	userMPM            UserMPM
+	maxPeerLinks       uint8
+	meshMaxInactivity  uint32
+	okc                OKC
+	globals            interfaceGlobals
 }
 
 func (wpa *WPAInterface) ToConfigString() string {
@@ -54,11 +96,39 @@ func (wpa *WPAInterface) ToConfigString() string {
 	if wpa.fastReauth != defaultFastReauth {
 		builder.WriteString(fmt.Sprintf("fast_reauth=%d\n", wpa.fastReauth))
 	}
+	if wpa.pmkLifetime != defaultDot11RSNAConfigPMKLifetime {
+		builder.WriteString(fmt.Sprintf("dot11RSNAConfigPMKLifetime=%d\n", wpa.pmkLifetime))
+	}
+	builder.WriteString(renderConfigFields("", wpa.globals.configFields()))
+	if wpa.interworking != defaultInterworking {
+		builder.WriteString(fmt.Sprintf("interworking=%d\n", wpa.interworking))
+	}
+	if wpa.hs20 != defaultHS20 {
+		builder.WriteString(fmt.Sprintf("hs20=%d\n", wpa.hs20))
+	}
+	if wpa.autoInterworking != defaultAutoInterworking {
+		builder.WriteString(fmt.Sprintf("auto_interworking=%d\n", wpa.autoInterworking))
+	}
+	if wpa.userMPM != defaultUserMPM {
+		builder.WriteString(fmt.Sprintf("user_mpm=%d\n", wpa.userMPM))
+	}
+	if wpa.maxPeerLinks != defaultMaxPeerLinks {
+		builder.WriteString(fmt.Sprintf("max_peer_links=%d\n", wpa.maxPeerLinks))
+	}
+	if wpa.meshMaxInactivity != defaultMeshMaxInactivity {
+		builder.WriteString(fmt.Sprintf("mesh_max_inactivity=%d\n", wpa.meshMaxInactivity))
+	}
+	if wpa.okc != defaultOKC {
+		builder.WriteString(fmt.Sprintf("okc=%d\n", wpa.okc))
+	}
 	if wpa.network != nil && len(wpa.network) > 0 {
 		for i := 0; i < len(wpa.network); i++ {
 			builder.WriteString(wpa.network[i].ToConfigString())
 		}
 	}
+	for i := 0; i < len(wpa.credential); i++ {
+		builder.WriteString(wpa.credential[i].ToConfigString())
+	}
 	return builder.String()
 }
 
@@ -69,16 +139,58 @@ type wpaInterfaceBuilder interface {
 	WithApScan(scan ApScan) wpaInterfaceBuilder
 	WithFastReauth(reauth FastReauth) wpaInterfaceBuilder
 	WithNetwork(net ...Network) wpaInterfaceBuilder
+	WithDot11RSNAConfigPMKLifetime(seconds uint32) wpaInterfaceBuilder
+	WithInterworking(interworking Interworking) wpaInterfaceBuilder
+	WithHS20(hs20 HS20) wpaInterfaceBuilder
+	WithAutoInterworking(autoInterworking AutoInterworking) wpaInterfaceBuilder
+	WithCredential(cred ...Credential) wpaInterfaceBuilder
+	WithUserMPM(userMPM UserMPM) wpaInterfaceBuilder
+	WithMaxPeerLinks(links uint8) wpaInterfaceBuilder
+	WithMeshMaxInactivity(seconds uint32) wpaInterfaceBuilder
+	WithOKC(okc OKC) wpaInterfaceBuilder
+	WithCountry(country string) wpaInterfaceBuilder
+	WithUpdateConfig(updateConfig UpdateConfig) wpaInterfaceBuilder
+	WithUUID(uuid string) wpaInterfaceBuilder
+	WithDeviceName(name string) wpaInterfaceBuilder
+	WithManufacturer(manufacturer string) wpaInterfaceBuilder
+	WithConfigMethods(methods ...WPSConfigMethod) wpaInterfaceBuilder
+	WithP2PDisabled(disabled P2PDisabled) wpaInterfaceBuilder
+	WithBSSExpiration(age, scanCount uint32) wpaInterfaceBuilder
+	WithAutoscanPeriodic(interval uint32) wpaInterfaceBuilder
+	WithAutoscanExponential(base, limit uint32) wpaInterfaceBuilder
+	WithPassiveScan(passiveScan PassiveScan) wpaInterfaceBuilder
+	WithFilterSSIDs(filterSSIDs FilterSSIDs) wpaInterfaceBuilder
+	WithMacAddr(policy MacAddrPolicy) wpaInterfaceBuilder
+	WithPreassocMacAddr(policy MacAddrPolicy) wpaInterfaceBuilder
+	WithRandAddrLifetime(seconds uint32) wpaInterfaceBuilder
+	WithDot11RSNAConfigPMKReauthThreshold(percent uint32) wpaInterfaceBuilder
+	WithDot11RSNAConfigSATimeout(seconds uint32) wpaInterfaceBuilder
+	WithDriverParam(param string) wpaInterfaceBuilder
+	WithPMF(pmf PMF) wpaInterfaceBuilder
+	WithSAEGroups(groups ...SAEGroup) wpaInterfaceBuilder
+	WithExternalSIM(externalSIM ExternalSIM) wpaInterfaceBuilder
+	WithWowlanTriggers(triggers ...WowlanTrigger) wpaInterfaceBuilder
+	WithExtPasswordBackend(backend ExtPasswordBackend, parameters string) wpaInterfaceBuilder
 	Build() (*WPAInterface, error)
 }
 
 type WpaInterfaceBuilder struct {
-	ctrlInterface      string       `json:"ctrl_interface"`
-	ctrlInterfaceGroup string       `json:"ctrl_interface_group,omitempty"`
-	eapolVersion       EapolVersion `json:"eapol_version,omitempty"`
-	apScan             ApScan       `json:"ap_scan,omitempty"`
-	fastReauth         FastReauth   `json:"fast_reauth,omitempty"`
-	network            []Network    `json:"network"`
+	ctrlInterface      string
+	ctrlInterfaceGroup string
+	eapolVersion       EapolVersion
+	apScan             ApScan
+	fastReauth         FastReauth
+	network            []Network
+	pmkLifetime        uint32
+	interworking       Interworking
+	hs20               HS20
+	autoInterworking   AutoInterworking
+	credential         []Credential
+	userMPM            UserMPM
+	maxPeerLinks       uint8
+	meshMaxInactivity  uint32
+	okc                OKC
+	globals            interfaceGlobals
 }
 
 func NewWpaInterfaceBuilder() wpaInterfaceBuilder {
@@ -88,6 +200,15 @@ func NewWpaInterfaceBuilder() wpaInterfaceBuilder {
 		eapolVersion:       defaultEapolVersion,
 		apScan:             defaultApScan,
 		fastReauth:         defaultFastReauth,
+		pmkLifetime:        defaultDot11RSNAConfigPMKLifetime,
+		interworking:       defaultInterworking,
+		hs20:               defaultHS20,
+		autoInterworking:   defaultAutoInterworking,
+		userMPM:            defaultUserMPM,
+		maxPeerLinks:       defaultMaxPeerLinks,
+		meshMaxInactivity:  defaultMeshMaxInactivity,
+		okc:                defaultOKC,
+		globals:            newInterfaceGlobals(),
 	}
 	return &builder
 }
@@ -123,6 +244,66 @@ func (w *WpaInterfaceBuilder) WithNetwork(net ...Network) wpaInterfaceBuilder {
 	return w
 }
 
+// WithDot11RSNAConfigPMKLifetime sets the maximum lifetime of a PMK in seconds (default 43200).
+// When it expires the supplicant re-authenticates, on wired links this sends a new EAPOL-Start.
+func (w *WpaInterfaceBuilder) WithDot11RSNAConfigPMKLifetime(seconds uint32) wpaInterfaceBuilder {
+	w.pmkLifetime = seconds
+	return w
+}
+
+// WithInterworking enables Interworking (IEEE 802.11u) support. Required to use credentials.
+func (w *WpaInterfaceBuilder) WithInterworking(interworking Interworking) wpaInterfaceBuilder {
+	w.interworking = interworking
+	return w
+}
+
+// WithHS20 enables Hotspot 2.0 support. Requires interworking.
+func (w *WpaInterfaceBuilder) WithHS20(hs20 HS20) wpaInterfaceBuilder {
+	w.hs20 = hs20
+	return w
+}
+
+// WithAutoInterworking enables automatic network selection through interworking when
+// no enabled network block matches. Requires interworking.
+func (w *WpaInterfaceBuilder) WithAutoInterworking(autoInterworking AutoInterworking) wpaInterfaceBuilder {
+	w.autoInterworking = autoInterworking
+	return w
+}
+
+// WithCredential sets the Hotspot 2.0 / Interworking credentials, rendered as cred blocks
+func (w *WpaInterfaceBuilder) WithCredential(cred ...Credential) wpaInterfaceBuilder {
+	w.credential = make([]Credential, 0)
+	w.credential = append(w.credential, cred...)
+	return w
+}
+
+// WithUserMPM selects whether mesh peering is handled by the supplicant (1, default) or by the kernel (0)
+func (w *WpaInterfaceBuilder) WithUserMPM(userMPM UserMPM) wpaInterfaceBuilder {
+	w.userMPM = userMPM
+	return w
+}
+
+// WithMaxPeerLinks sets the maximum number of mesh peer links (default 99)
+func (w *WpaInterfaceBuilder) WithMaxPeerLinks(links uint8) wpaInterfaceBuilder {
+	w.maxPeerLinks = links
+	return w
+}
+
+// WithMeshMaxInactivity sets the time in seconds after which an inactive mesh peer is
+// removed (default 300)
+func (w *WpaInterfaceBuilder) WithMeshMaxInactivity(seconds uint32) wpaInterfaceBuilder {
+	w.meshMaxInactivity = seconds
+	return w
+}
+
+// WithOKC enables opportunistic key caching for every network of the interface, which lets
+// WPA-EAP roams between access points of the same ESS skip the full EAP exchange. Networks can
+// override it with proactive_key_caching.
+func (w *WpaInterfaceBuilder) WithOKC(okc OKC) wpaInterfaceBuilder {
+	w.okc = okc
+	return w
+}
+
 func (w WpaInterfaceBuilder) Build() (*WPAInterface, error) {
 	err := w.validate()
 	if err != nil {
@@ -135,6 +316,16 @@ func (w WpaInterfaceBuilder) Build() (*WPAInterface, error) {
 		apScan:             w.apScan,
 		fastReauth:         w.fastReauth,
 		network:            w.network,
+		pmkLifetime:        w.pmkLifetime,
+		interworking:       w.interworking,
+		hs20:               w.hs20,
+		autoInterworking:   w.autoInterworking,
+		credential:         w.credential,
+		userMPM:            w.userMPM,
+		maxPeerLinks:       w.maxPeerLinks,
+		meshMaxInactivity:  w.meshMaxInactivity,
+		okc:                w.okc,
+		globals:            w.globals,
 	}
 	return &wpaIf, err
 }
@@ -152,8 +343,40 @@ func (w WpaInterfaceBuilder) validate() error {
 	if !contains(fastReauthSlice, w.fastReauth) {
 		return errors.New("invalid value for fast reauth")
 	}
-	if w.network == nil || len(w.network) == 0 {
-		return errors.New("no networks configured. at least one network must be provided")
+	if w.pmkLifetime == 0 {
+		return errors.New("invalid value for dot11RSNAConfigPMKLifetime")
+	}
+	if !contains(interworkingSlice, w.interworking) {
+		return errors.New("invalid value for interworking")
+	}
+	if !contains(hs20Slice, w.hs20) {
+		return errors.New("invalid value for hs20")
+	}
+	if !contains(autoInterworkingSlice, w.autoInterworking) {
+		return errors.New("invalid value for auto interworking")
+	}
+	if !contains(userMPMSlice, w.userMPM) {
+		return errors.New("invalid value for user mpm")
+	}
+	if !contains(okcSlice, w.okc) {
+		return errors.New("invalid value for okc")
+	}
+	err := w.globals.validate()
+	if err != nil {
+		return err
+	}
+	if w.interworking != InterworkingOn && (w.hs20 == HS20On || w.autoInterworking == AutoInterworkingOn || len(w.credential) > 0) {
+		return errors.New("hs20, auto interworking and credentials require interworking")
+	}
+	if len(w.network) == 0 && len(w.credential) == 0 {
+		return errors.New("no networks configured. at least one network or credential must be provided")
+	}
+	if w.globals.extPasswordBackend == "" {
+		for i := range w.network {
+			if usesExternalSecrets(w.network[i].allConfigFields()) {
+				return errors.New("ext: references require an ext password backend")
+			}
+		}
 	}
 	return nil
 }
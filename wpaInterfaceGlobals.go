@@ -0,0 +1,476 @@
+package wpaSuppDBusLib
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type UpdateConfig byte
+type P2PDisabled byte
+type PassiveScan byte
+type FilterSSIDs byte
+type ExternalSIM byte
+type MacAddrPolicy byte
+type PMF byte
+type SAEGroup uint16
+type WPSConfigMethod string
+type WowlanTrigger string
+type ExtPasswordBackend string
+
+const (
+	UpdateConfigOff          UpdateConfig    = 0
+	UpdateConfigOn           UpdateConfig    = 1
+	P2PDisabledOff           P2PDisabled     = 0
+	P2PDisabledOn            P2PDisabled     = 1
+	PassiveScanOff           PassiveScan     = 0
+	PassiveScanOn            PassiveScan     = 1
+	FilterSSIDsOff           FilterSSIDs     = 0
+	FilterSSIDsOn            FilterSSIDs     = 1
+	ExternalSIMOff           ExternalSIM     = 0
+	ExternalSIMOn            ExternalSIM     = 1
+	MacAddrPermanent         MacAddrPolicy   = 0
+	MacAddrRandom            MacAddrPolicy   = 1
+	MacAddrRandomKeepOUI     MacAddrPolicy   = 2
+	PMFDisabled              PMF             = 0
+	PMFOptional              PMF             = 1
+	PMFRequired              PMF             = 2
+	SAEGroup19               SAEGroup        = 19
+	SAEGroup20               SAEGroup        = 20
+	SAEGroup21               SAEGroup        = 21
+	SAEGroup25               SAEGroup        = 25
+	SAEGroup26               SAEGroup        = 26
+	SAEGroup28               SAEGroup        = 28
+	SAEGroup29               SAEGroup        = 29
+	SAEGroup30               SAEGroup        = 30
+	WowlanAny                WowlanTrigger   = "any"
+	WowlanDisconnect         WowlanTrigger   = "disconnect"
+	WowlanMagicPkt           WowlanTrigger   = "magic_pkt"
+	WowlanGTKRekeyFailure    WowlanTrigger   = "gtk_rekey_failure"
+	WowlanEAPIdentityReq     WowlanTrigger   = "eap_identity_req"
+	WowlanFourWayHandshake   WowlanTrigger   = "four_way_handshake"
+	WowlanRFKillRelease      WowlanTrigger   = "rfkill_release"
+	WPSConfigUSBA            WPSConfigMethod = "usba"
+	WPSConfigEthernet        WPSConfigMethod = "ethernet"
+	WPSConfigLabel           WPSConfigMethod = "label"
+	WPSConfigDisplay         WPSConfigMethod = "display"
+	WPSConfigExtNFCToken     WPSConfigMethod = "ext_nfc_token"
+	WPSConfigIntNFCToken     WPSConfigMethod = "int_nfc_token"
+	WPSConfigNFCInterface    WPSConfigMethod = "nfc_interface"
+	WPSConfigPushButton      WPSConfigMethod = "push_button"
+	WPSConfigKeypad          WPSConfigMethod = "keypad"
+	WPSConfigVirtualDisplay  WPSConfigMethod = "virtual_display"
+	WPSConfigPhysicalDisplay WPSConfigMethod = "physical_display"
+	WPSConfigVirtualPush     WPSConfigMethod = "virtual_push_button"
+	WPSConfigPhysicalPush    WPSConfigMethod = "physical_push_button"
+	WPSConfigP2PS            WPSConfigMethod = "p2ps"
+	// ExtPasswordBackendTest takes the passwords from its parameters, e.g. pw1=secret|pw2=other
+	ExtPasswordBackendTest ExtPasswordBackend = "test"
+	// ExtPasswordBackendFile reads name=password lines from the file given as parameter
+	ExtPasswordBackendFile ExtPasswordBackend = "file"
+)
+
+var updateConfigSlice = []UpdateConfig{UpdateConfigOff, UpdateConfigOn}
+var p2pDisabledSlice = []P2PDisabled{P2PDisabledOff, P2PDisabledOn}
+var passiveScanSlice = []PassiveScan{PassiveScanOff, PassiveScanOn}
+var filterSSIDsSlice = []FilterSSIDs{FilterSSIDsOff, FilterSSIDsOn}
+var externalSIMSlice = []ExternalSIM{ExternalSIMOff, ExternalSIMOn}
+var macAddrPolicySlice = []MacAddrPolicy{MacAddrPermanent, MacAddrRandom, MacAddrRandomKeepOUI}
+var pmfSlice = []PMF{PMFDisabled, PMFOptional, PMFRequired}
+var saeGroupSlice = []SAEGroup{SAEGroup19, SAEGroup20, SAEGroup21, SAEGroup25, SAEGroup26, SAEGroup28, SAEGroup29, SAEGroup30}
+var wowlanTriggerSlice = []WowlanTrigger{WowlanAny, WowlanDisconnect, WowlanMagicPkt, WowlanGTKRekeyFailure,
+	WowlanEAPIdentityReq, WowlanFourWayHandshake, WowlanRFKillRelease}
+var extPasswordBackendSlice = []ExtPasswordBackend{ExtPasswordBackendTest, ExtPasswordBackendFile}
+var wpsConfigMethodSlice = []WPSConfigMethod{WPSConfigUSBA, WPSConfigEthernet, WPSConfigLabel, WPSConfigDisplay,
+	WPSConfigExtNFCToken, WPSConfigIntNFCToken, WPSConfigNFCInterface, WPSConfigPushButton, WPSConfigKeypad,
+	WPSConfigVirtualDisplay, WPSConfigPhysicalDisplay, WPSConfigVirtualPush, WPSConfigPhysicalPush, WPSConfigP2PS}
+
+var defaultUpdateConfig = UpdateConfigOff
+var defaultP2PDisabled = P2PDisabledOff
+var defaultBSSExpirationAge uint32 = 180
+var defaultBSSExpirationScanCount uint32 = 2
+var defaultPassiveScan = PassiveScanOff
+var defaultFilterSSIDs = FilterSSIDsOff
+var defaultMacAddr = MacAddrPermanent
+var defaultPreassocMacAddr = MacAddrPermanent
+var defaultRandAddrLifetime uint32 = 60
+var defaultDot11RSNAConfigPMKReauthThreshold uint32 = 70
+var defaultDot11RSNAConfigSATimeout uint32 = 60
+var defaultPMF = PMFDisabled
+var defaultExternalSIM = ExternalSIMOff
+
+var countryRegex = regexp.MustCompile(`^([A-Z]{2}|00)$`)
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// interfaceGlobals holds the less common global options of an interface config. Zero values of
+// strings and lists mean unset, the other options are only written when they differ from the
+// supplicant default.
+type interfaceGlobals struct {
+	country                string
+	updateConfig           UpdateConfig
+	uuid                   string
+	deviceName             string
+	manufacturer           string
+	configMethods          []WPSConfigMethod
+	p2pDisabled            P2PDisabled
+	bssExpirationAge       uint32
+	bssExpirationScanCount uint32
+	autoscan               autoscanConfig
+	passiveScan            PassiveScan
+	filterSSIDs            FilterSSIDs
+	macAddr                MacAddrPolicy
+	preassocMacAddr        MacAddrPolicy
+	randAddrLifetime       uint32
+	pmkReauthThreshold     uint32
+	saTimeout              uint32
+	driverParam            string
+	pmf                    PMF
+	saeGroups              []SAEGroup
+	externalSIM            ExternalSIM
+	wowlanTriggers         []WowlanTrigger
+	extPasswordBackend     ExtPasswordBackend
+	extPasswordParameters  string
+}
+
+// autoscanConfig is the autoscan module used while disconnected, empty when autoscan is not set
+type autoscanConfig struct {
+	module   string
+	interval uint32
+	limit    uint32
+}
+
+func newInterfaceGlobals() interfaceGlobals {
+	return interfaceGlobals{
+		updateConfig:           defaultUpdateConfig,
+		p2pDisabled:            defaultP2PDisabled,
+		bssExpirationAge:       defaultBSSExpirationAge,
+		bssExpirationScanCount: defaultBSSExpirationScanCount,
+		passiveScan:            defaultPassiveScan,
+		filterSSIDs:            defaultFilterSSIDs,
+		macAddr:                defaultMacAddr,
+		preassocMacAddr:        defaultPreassocMacAddr,
+		randAddrLifetime:       defaultRandAddrLifetime,
+		pmkReauthThreshold:     defaultDot11RSNAConfigPMKReauthThreshold,
+		saTimeout:              defaultDot11RSNAConfigSATimeout,
+		pmf:                    defaultPMF,
+		externalSIM:            defaultExternalSIM,
+	}
+}
+
+func (g *interfaceGlobals) configFields() []configField {
+	fields := make([]configField, 0)
+	if g.country != "" {
+		fields = append(fields, rawField("country", g.country))
+	}
+	if g.updateConfig != defaultUpdateConfig {
+		fields = append(fields, rawField("update_config", g.updateConfig))
+	}
+	if g.uuid != "" {
+		fields = append(fields, rawField("uuid", g.uuid))
+	}
+	if g.deviceName != "" {
+		fields = append(fields, rawField("device_name", g.deviceName))
+	}
+	if g.manufacturer != "" {
+		fields = append(fields, rawField("manufacturer", g.manufacturer))
+	}
+	if len(g.configMethods) > 0 {
+		fields = append(fields, listField("config_methods", g.configMethods))
+	}
+	if g.p2pDisabled != defaultP2PDisabled {
+		fields = append(fields, rawField("p2p_disabled", g.p2pDisabled))
+	}
+	if g.bssExpirationAge != defaultBSSExpirationAge {
+		fields = append(fields, rawField("bss_expiration_age", g.bssExpirationAge))
+	}
+	if g.bssExpirationScanCount != defaultBSSExpirationScanCount {
+		fields = append(fields, rawField("bss_expiration_scan_count", g.bssExpirationScanCount))
+	}
+	if g.autoscan.module != "" {
+		fields = append(fields, rawField("autoscan", g.autoscan.toConfigValue()))
+	}
+	if g.passiveScan != defaultPassiveScan {
+		fields = append(fields, rawField("passive_scan", g.passiveScan))
+	}
+	if g.filterSSIDs != defaultFilterSSIDs {
+		fields = append(fields, rawField("filter_ssids", g.filterSSIDs))
+	}
+	if g.macAddr != defaultMacAddr {
+		fields = append(fields, rawField("mac_addr", g.macAddr))
+	}
+	if g.preassocMacAddr != defaultPreassocMacAddr {
+		fields = append(fields, rawField("preassoc_mac_addr", g.preassocMacAddr))
+	}
+	if g.randAddrLifetime != defaultRandAddrLifetime {
+		fields = append(fields, rawField("rand_addr_lifetime", g.randAddrLifetime))
+	}
+	if g.pmkReauthThreshold != defaultDot11RSNAConfigPMKReauthThreshold {
+		fields = append(fields, rawField("dot11RSNAConfigPMKReauthThreshold", g.pmkReauthThreshold))
+	}
+	if g.saTimeout != defaultDot11RSNAConfigSATimeout {
+		fields = append(fields, rawField("dot11RSNAConfigSATimeout", g.saTimeout))
+	}
+	if g.driverParam != "" {
+		fields = append(fields, rawField("driver_param", g.driverParam))
+	}
+	if g.pmf != defaultPMF {
+		fields = append(fields, rawField("pmf", g.pmf))
+	}
+	if len(g.saeGroups) > 0 {
+		fields = append(fields, listField("sae_groups", g.saeGroups))
+	}
+	if g.externalSIM != defaultExternalSIM {
+		fields = append(fields, rawField("external_sim", g.externalSIM))
+	}
+	if len(g.wowlanTriggers) > 0 {
+		fields = append(fields, listField("wowlan_triggers", g.wowlanTriggers))
+	}
+	if g.extPasswordBackend != "" {
+		fields = append(fields, rawField("ext_password_backend", g.extPasswordBackendValue()))
+	}
+	return fields
+}
+
+// extPasswordBackendValue renders the backend as <backend>[:<parameters>]
+func (g *interfaceGlobals) extPasswordBackendValue() string {
+	if g.extPasswordParameters == "" {
+		return string(g.extPasswordBackend)
+	}
+	return fmt.Sprintf("%s:%s", g.extPasswordBackend, g.extPasswordParameters)
+}
+
+func (a *autoscanConfig) toConfigValue() string {
+	if a.module == "periodic" {
+		return fmt.Sprintf("periodic:%d", a.interval)
+	}
+	return fmt.Sprintf("%s:%d:%d", a.module, a.interval, a.limit)
+}
+
+func (g *interfaceGlobals) validate() error {
+	if g.country != "" && !countryRegex.MatchString(g.country) {
+		return errors.New("invalid value for country. must be an ISO 3166-1 alpha-2 code")
+	}
+	if !contains(updateConfigSlice, g.updateConfig) {
+		return errors.New("invalid value for update config")
+	}
+	if g.uuid != "" && !uuidRegex.MatchString(g.uuid) {
+		return errors.New("invalid value for uuid")
+	}
+	if len(g.deviceName) > 32 || strings.ContainsAny(g.deviceName, "\n") {
+		return errors.New("invalid value for device name. must be at most 32 characters")
+	}
+	if len(g.manufacturer) > 64 || strings.ContainsAny(g.manufacturer, "\n") {
+		return errors.New("invalid value for manufacturer. must be at most 64 characters")
+	}
+	if !contains(wpsConfigMethodSlice, g.configMethods) {
+		return errors.New("invalid value for config methods")
+	}
+	if !contains(p2pDisabledSlice, g.p2pDisabled) {
+		return errors.New("invalid value for p2p disabled")
+	}
+	if g.bssExpirationAge < 10 {
+		return errors.New("invalid value for bss expiration age. must be at least 10 seconds")
+	}
+	if g.bssExpirationScanCount == 0 {
+		return errors.New("invalid value for bss expiration scan count. must be at least 1")
+	}
+	if g.autoscan.module != "" && (g.autoscan.interval == 0 || (g.autoscan.module == "exponential" && g.autoscan.limit < g.autoscan.interval)) {
+		return errors.New("invalid value for autoscan")
+	}
+	if !contains(passiveScanSlice, g.passiveScan) {
+		return errors.New("invalid value for passive scan")
+	}
+	if !contains(filterSSIDsSlice, g.filterSSIDs) {
+		return errors.New("invalid value for filter ssids")
+	}
+	if !contains(macAddrPolicySlice, g.macAddr) {
+		return errors.New("invalid value for mac addr")
+	}
+	if !contains(macAddrPolicySlice, g.preassocMacAddr) {
+		return errors.New("invalid value for preassoc mac addr")
+	}
+	if g.randAddrLifetime == 0 {
+		return errors.New("invalid value for rand addr lifetime")
+	}
+	if g.pmkReauthThreshold == 0 || g.pmkReauthThreshold > 100 {
+		return errors.New("invalid value for dot11RSNAConfigPMKReauthThreshold. must be between 1 and 100")
+	}
+	if g.saTimeout == 0 {
+		return errors.New("invalid value for dot11RSNAConfigSATimeout")
+	}
+	if strings.ContainsAny(g.driverParam, "\n") {
+		return errors.New("invalid value for driver param")
+	}
+	if !contains(pmfSlice, g.pmf) {
+		return errors.New("invalid value for pmf")
+	}
+	if !contains(saeGroupSlice, g.saeGroups) {
+		return errors.New("invalid value for sae groups")
+	}
+	if !contains(externalSIMSlice, g.externalSIM) {
+		return errors.New("invalid value for external sim")
+	}
+	if !contains(wowlanTriggerSlice, g.wowlanTriggers) {
+		return errors.New("invalid value for wowlan triggers")
+	}
+	if contains(g.wowlanTriggers, WowlanAny) && len(g.wowlanTriggers) > 1 {
+		return errors.New("wowlan trigger any can not be combined with other triggers")
+	}
+	if g.extPasswordBackend != "" && !contains(extPasswordBackendSlice, g.extPasswordBackend) {
+		return errors.New("invalid value for ext password backend")
+	}
+	if strings.ContainsAny(g.extPasswordParameters, "\n\r") || (g.extPasswordBackend == ExtPasswordBackendFile && g.extPasswordParameters == "") {
+		return errors.New("invalid value for ext password backend parameters")
+	}
+	return nil
+}
+
+// WithCountry sets the ISO 3166-1 alpha-2 country code used for the regulatory domain
+func (w *WpaInterfaceBuilder) WithCountry(country string) wpaInterfaceBuilder {
+	w.globals.country = country
+	return w
+}
+
+// WithUpdateConfig allows the supplicant to write changes made at runtime back to the config file
+func (w *WpaInterfaceBuilder) WithUpdateConfig(updateConfig UpdateConfig) wpaInterfaceBuilder {
+	w.globals.updateConfig = updateConfig
+	return w
+}
+
+// WithUUID sets the WPS UUID of the device, by default it is derived from the MAC address
+func (w *WpaInterfaceBuilder) WithUUID(uuid string) wpaInterfaceBuilder {
+	w.globals.uuid = uuid
+	return w
+}
+
+// WithDeviceName sets the user friendly WPS/P2P device name, at most 32 characters
+func (w *WpaInterfaceBuilder) WithDeviceName(name string) wpaInterfaceBuilder {
+	w.globals.deviceName = name
+	return w
+}
+
+// WithManufacturer sets the WPS manufacturer, at most 64 characters
+func (w *WpaInterfaceBuilder) WithManufacturer(manufacturer string) wpaInterfaceBuilder {
+	w.globals.manufacturer = manufacturer
+	return w
+}
+
+// WithConfigMethods sets the WPS config methods the device supports
+func (w *WpaInterfaceBuilder) WithConfigMethods(methods ...WPSConfigMethod) wpaInterfaceBuilder {
+	w.globals.configMethods = make([]WPSConfigMethod, 0)
+	w.globals.configMethods = append(w.globals.configMethods, methods...)
+	return w
+}
+
+// WithP2PDisabled disables P2P functionality on the interface
+func (w *WpaInterfaceBuilder) WithP2PDisabled(disabled P2PDisabled) wpaInterfaceBuilder {
+	w.globals.p2pDisabled = disabled
+	return w
+}
+
+// WithBSSExpiration sets how long (seconds, default 180, minimum 10) and for how many scans
+// (default 2) a BSS that is no longer seen is kept in the BSS table
+func (w *WpaInterfaceBuilder) WithBSSExpiration(age, scanCount uint32) wpaInterfaceBuilder {
+	w.globals.bssExpirationAge = age
+	w.globals.bssExpirationScanCount = scanCount
+	return w
+}
+
+// WithAutoscanPeriodic scans every interval seconds while disconnected
+func (w *WpaInterfaceBuilder) WithAutoscanPeriodic(interval uint32) wpaInterfaceBuilder {
+	w.globals.autoscan = autoscanConfig{module: "periodic", interval: interval}
+	return w
+}
+
+// WithAutoscanExponential scans while disconnected with an interval starting at base seconds
+// that is multiplied by base after every scan until it reaches limit seconds
+func (w *WpaInterfaceBuilder) WithAutoscanExponential(base, limit uint32) wpaInterfaceBuilder {
+	w.globals.autoscan = autoscanConfig{module: "exponential", interval: base, limit: limit}
+	return w
+}
+
+// WithPassiveScan makes the supplicant only use passive scans
+func (w *WpaInterfaceBuilder) WithPassiveScan(passiveScan PassiveScan) wpaInterfaceBuilder {
+	w.globals.passiveScan = passiveScan
+	return w
+}
+
+// WithFilterSSIDs only keeps BSSs of configured networks in the BSS table
+func (w *WpaInterfaceBuilder) WithFilterSSIDs(filterSSIDs FilterSSIDs) wpaInterfaceBuilder {
+	w.globals.filterSSIDs = filterSSIDs
+	return w
+}
+
+// WithMacAddr selects the MAC address used when connecting; 0 (permanent, default),
+// 1 (random per ESS connection) or 2 (random keeping the OUI)
+func (w *WpaInterfaceBuilder) WithMacAddr(policy MacAddrPolicy) wpaInterfaceBuilder {
+	w.globals.macAddr = policy
+	return w
+}
+
+// WithPreassocMacAddr selects the MAC address used for scanning while not connected, see WithMacAddr
+func (w *WpaInterfaceBuilder) WithPreassocMacAddr(policy MacAddrPolicy) wpaInterfaceBuilder {
+	w.globals.preassocMacAddr = policy
+	return w
+}
+
+// WithRandAddrLifetime sets how long in seconds a random MAC address is used (default 60)
+func (w *WpaInterfaceBuilder) WithRandAddrLifetime(seconds uint32) wpaInterfaceBuilder {
+	w.globals.randAddrLifetime = seconds
+	return w
+}
+
+// WithDot11RSNAConfigPMKReauthThreshold sets the percentage of the PMK lifetime after which
+// the supplicant re-authenticates (default 70)
+func (w *WpaInterfaceBuilder) WithDot11RSNAConfigPMKReauthThreshold(percent uint32) wpaInterfaceBuilder {
+	w.globals.pmkReauthThreshold = percent
+	return w
+}
+
+// WithDot11RSNAConfigSATimeout sets the maximum time in seconds a security association may take (default 60)
+func (w *WpaInterfaceBuilder) WithDot11RSNAConfigSATimeout(seconds uint32) wpaInterfaceBuilder {
+	w.globals.saTimeout = seconds
+	return w
+}
+
+// WithDriverParam passes driver specific parameters, e.g. use_p2p_group_interface=1
+func (w *WpaInterfaceBuilder) WithDriverParam(param string) wpaInterfaceBuilder {
+	w.globals.driverParam = param
+	return w
+}
+
+// WithPMF sets the default protected management frames policy; 0 (disabled, default),
+// 1 (optional) or 2 (required). Networks can override it with ieee80211w.
+func (w *WpaInterfaceBuilder) WithPMF(pmf PMF) wpaInterfaceBuilder {
+	w.globals.pmf = pmf
+	return w
+}
+
+// WithSAEGroups sets the preferred order of finite cyclic groups for SAE
+func (w *WpaInterfaceBuilder) WithSAEGroups(groups ...SAEGroup) wpaInterfaceBuilder {
+	w.globals.saeGroups = make([]SAEGroup, 0)
+	w.globals.saeGroups = append(w.globals.saeGroups, groups...)
+	return w
+}
+
+// WithExternalSIM delegates SIM/USIM operations of EAP-SIM/AKA to an external program
+func (w *WpaInterfaceBuilder) WithExternalSIM(externalSIM ExternalSIM) wpaInterfaceBuilder {
+	w.globals.externalSIM = externalSIM
+	return w
+}
+
+// WithExtPasswordBackend sets the backend wpa_supplicant resolves ext:<name> references of
+// passwords and psks with. The file backend takes the path of the password file as parameters.
+func (w *WpaInterfaceBuilder) WithExtPasswordBackend(backend ExtPasswordBackend, parameters string) wpaInterfaceBuilder {
+	w.globals.extPasswordBackend = backend
+	w.globals.extPasswordParameters = parameters
+	return w
+}
+
+// WithWowlanTriggers sets the wake on WLAN triggers configured when the system suspends
+func (w *WpaInterfaceBuilder) WithWowlanTriggers(triggers ...WowlanTrigger) wpaInterfaceBuilder {
+	w.globals.wowlanTriggers = make([]WowlanTrigger, 0)
+	w.globals.wowlanTriggers = append(w.globals.wowlanTriggers, triggers...)
+	return w
+}
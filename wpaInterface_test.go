@@ -26,3 +26,90 @@ func TestWpaInterfaceToConfTextSmallExample(t *testing.T) {
 		t.Errorf("config strings don't match")
 	}
 }
+
+func TestWpaInterfaceToConfTextWithCredential(t *testing.T) {
+	//expected text is modeled after the Hotspot 2.0 example of wpa_supplicant.conf
+	expectedConfText := "ctrl_interface=/run/wpa_supplicant\ninterworking=1\nhs20=1\ncred={\n  realm=\"example.com\"\n  username=\"user\"\n  password=\"password\"\n  ca_cert=\"/etc/wpa_supplicant/ca.pem\"\n  domain=\"example.com\"\n  roaming_consortium=223344\n  eap=TTLS\n  phase2=\"auth=MSCHAPV2\"\n  priority=1\n  sp_priority=10\n}\n"
+
+	credBuilder := NewCredentialBuilder()
+	cred, err := credBuilder.WithRealm("example.com").WithUsername("user").WithPassword("password").
+		WithCaCertPath("/etc/wpa_supplicant/ca.pem").WithDomain("example.com").WithRoamingConsortium("223344").
+		WithEAP(CredentialEAPTTLS).WithInnerAuthType(InnerAuthMsChapV2).WithPriority(1).WithSPPriority(10).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ifBuilder := NewWpaInterfaceBuilder()
+	x, err := ifBuilder.WithCtrlInterface("/run/wpa_supplicant").WithInterworking(InterworkingOn).WithHS20(HS20On).WithCredential(*cred).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	confStr := x.ToConfigString()
+
+	if !strings.EqualFold(expectedConfText, confStr) {
+		t.Errorf("config strings don't match")
+	}
+
+	args := cred.toDBusArgs()
+	if args["roaming_consortium"] != "223344" || args["priority"] != int32(1) || args["username"] != "user" {
+		t.Errorf("unexpected AddCred arguments %v", args)
+	}
+
+	_, err = NewWpaInterfaceBuilder().WithCredential(*cred).Build()
+	if err == nil {
+		t.Errorf("credentials without interworking must be rejected")
+	}
+}
+
+func TestWpaInterfaceToConfTextWithGlobals(t *testing.T) {
+	expectedConfText := "ctrl_interface=/run/wpa_supplicant\ncountry=DE\nupdate_config=1\n" +
+		"uuid=12345678-9abc-def0-1234-56789abcdef0\ndevice_name=Gateway\nconfig_methods=display keypad\n" +
+		"p2p_disabled=1\nbss_expiration_age=60\nautoscan=exponential:3:300\nmac_addr=1\npreassoc_mac_addr=2\n" +
+		"dot11RSNAConfigPMKReauthThreshold=80\npmf=1\nsae_groups=19 20\nwowlan_triggers=disconnect magic_pkt\n" +
+		"network={\n  ssid=\"home\"\n  key_mgmt=NONE\n}\n"
+
+	network, err := NewNetworkBuilder().WithSSID("home").WithKeyManagement(NONE).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := NewWpaInterfaceBuilder().WithNetwork(*network).WithCtrlInterface("/run/wpa_supplicant").WithCountry("DE").
+		WithUpdateConfig(UpdateConfigOn).WithUUID("12345678-9abc-def0-1234-56789abcdef0").WithDeviceName("Gateway").
+		WithConfigMethods(WPSConfigDisplay, WPSConfigKeypad).WithP2PDisabled(P2PDisabledOn).WithBSSExpiration(60, 2).
+		WithAutoscanExponential(3, 300).WithMacAddr(MacAddrRandom).WithPreassocMacAddr(MacAddrRandomKeepOUI).
+		WithDot11RSNAConfigPMKReauthThreshold(80).WithPMF(PMFOptional).WithSAEGroups(SAEGroup19, SAEGroup20).
+		WithWowlanTriggers(WowlanDisconnect, WowlanMagicPkt).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	confStr := x.ToConfigString()
+
+	if expectedConfText != confStr {
+		t.Errorf("config strings don't match:\n%s", confStr)
+	}
+}
+
+func TestWpaInterfaceGlobalsValidation(t *testing.T) {
+	network, err := NewNetworkBuilder().WithSSID("home").WithKeyManagement(NONE).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWpaInterfaceBuilder().WithNetwork(*network).WithCountry("DE").Build(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := map[string]wpaInterfaceBuilder{
+		"country":          NewWpaInterfaceBuilder().WithCountry("de"),
+		"uuid":             NewWpaInterfaceBuilder().WithUUID("1234"),
+		"config methods":   NewWpaInterfaceBuilder().WithConfigMethods("pbc"),
+		"bss expiration":   NewWpaInterfaceBuilder().WithBSSExpiration(5, 2),
+		"autoscan":         NewWpaInterfaceBuilder().WithAutoscanExponential(300, 3),
+		"pmf":              NewWpaInterfaceBuilder().WithPMF(3),
+		"sae groups":       NewWpaInterfaceBuilder().WithSAEGroups(1),
+		"wowlan any":       NewWpaInterfaceBuilder().WithWowlanTriggers(WowlanAny, WowlanDisconnect),
+		"reauth threshold": NewWpaInterfaceBuilder().WithDot11RSNAConfigPMKReauthThreshold(101),
+	}
+	for name, builder := range invalid {
+		if _, err := builder.WithNetwork(*network).Build(); err == nil {
+			t.Errorf("invalid %s must be rejected", name)
+		}
+	}
+}
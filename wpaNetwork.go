@@ -1,6 +1,7 @@
 package wpaSuppDBusLib
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -14,18 +15,33 @@ type KeyManagement string
 type PairWise string
 type Group string
 type EapolFlag int8
+type MacsecPolicy int8
+type MacsecIntegOnly int8
+type MacsecReplayProtect int8
+type MacsecOffload int8
+type MeshFwding int8
+type HT40 int8
+type VHT int8
+type HE int8
+type WPSDisabled int8
+type ProactiveKeyCaching int8
 
 const (
 	ScanOn                ScanSSID      = 0
 	ScanOff               ScanSSID      = 1
 	ModeInfrastructure    Mode          = 0
 	ModeIBSS              Mode          = 1
+	ModeAP                Mode          = 2
+	ModeMesh              Mode          = 5
 	WPAProto              Proto         = "WPA"
 	WPA2Proto             Proto         = "RSN"
 	WpaEAP                KeyManagement = "WPA-EAP"
 	WpaPSK                KeyManagement = "WPA-PSK"
 	IEEE8021X             KeyManagement = "IEEE8021X"
 	NONE                  KeyManagement = "NONE"
+	SAE                   KeyManagement = "SAE"
+	FtEAP                 KeyManagement = "FT-EAP"
+	FtPSK                 KeyManagement = "FT-PSK"
 	AuthAlgOpen           AuthAlg       = "OPEN"
 	AuthAlgShared         AuthAlg       = "SHARED"
 	AuthAlgLeap           AuthAlg       = "LEAP"
@@ -42,29 +58,184 @@ const (
 	EapolDynamicBoth      EapolFlag     = 3
 )
 
+const (
+	MacsecPolicyShouldSecure MacsecPolicy        = 0
+	MacsecPolicyMustSecure   MacsecPolicy        = 1
+	MacsecIntegOnlyOff       MacsecIntegOnly     = 0
+	MacsecIntegOnlyOn        MacsecIntegOnly     = 1
+	MacsecReplayProtectOff   MacsecReplayProtect = 0
+	MacsecReplayProtectOn    MacsecReplayProtect = 1
+	MacsecOffloadOff         MacsecOffload       = 0
+	MacsecOffloadPHY         MacsecOffload       = 1
+	MacsecOffloadMAC         MacsecOffload       = 2
+)
+
+const (
+	MeshFwdingOff MeshFwding = 0
+	MeshFwdingOn  MeshFwding = 1
+)
+
+const (
+	HT40Off        HT40        = 0
+	HT40On         HT40        = 1
+	VHTOff         VHT         = 0
+	VHTOn          VHT         = 1
+	HEOff          HE          = 0
+	HEOn           HE          = 1
+	WPSDisabledOff WPSDisabled = 0
+	WPSDisabledOn  WPSDisabled = 1
+)
+
+const (
+	ProactiveKeyCachingOff ProactiveKeyCaching = 0
+	ProactiveKeyCachingOn  ProactiveKeyCaching = 1
+)
+
 var scanSlice = []ScanSSID{ScanOn, ScanOff}
-var modeSlice = []Mode{ModeInfrastructure, ModeIBSS}
+var modeSlice = []Mode{ModeInfrastructure, ModeIBSS, ModeAP, ModeMesh}
 var protoSlice = []Proto{WPAProto, WPA2Proto}
-var keyMngtSlice = []KeyManagement{WpaEAP, WpaPSK, IEEE8021X, NONE}
+var keyMngtSlice = []KeyManagement{WpaEAP, WpaPSK, IEEE8021X, NONE, SAE, FtEAP, FtPSK}
 var authAlgSlice = []AuthAlg{AuthAlgOpen, AuthAlgShared, AuthAlgLeap}
 var pairWiseSlice = []PairWise{PairWiseCCMP, PairWiseTKIP, PairWiseNone}
 var groupSlice = []Group{GroupCCMP, GroupTKIP, GroupWEP104, GroupWEP40}
 var eapFlagSlice = []EapolFlag{EapolOff, EapolDynamicUnicast, EapolDynamicBroadcast, EapolDynamicBoth}
+var eapKeyMngtSlice = []KeyManagement{WpaEAP, IEEE8021X, FtEAP}
+var macsecPolicySlice = []MacsecPolicy{MacsecPolicyShouldSecure, MacsecPolicyMustSecure}
+var macsecIntegOnlySlice = []MacsecIntegOnly{MacsecIntegOnlyOff, MacsecIntegOnlyOn}
+var macsecReplayProtectSlice = []MacsecReplayProtect{MacsecReplayProtectOff, MacsecReplayProtectOn}
+var macsecOffloadSlice = []MacsecOffload{MacsecOffloadOff, MacsecOffloadPHY, MacsecOffloadMAC}
+var meshFwdingSlice = []MeshFwding{MeshFwdingOff, MeshFwdingOn}
+var meshKeyMngtSlice = []KeyManagement{NONE, SAE}
+var ht40Slice = []HT40{HT40Off, HT40On}
+var vhtSlice = []VHT{VHTOff, VHTOn}
+var heSlice = []HE{HEOff, HEOn}
+var wpsDisabledSlice = []WPSDisabled{WPSDisabledOff, WPSDisabledOn}
+var proactiveKeyCachingSlice = []ProactiveKeyCaching{ProactiveKeyCachingOff, ProactiveKeyCachingOn}
+var apKeyMngtSlice = []KeyManagement{NONE, WpaPSK, SAE}
+
+var defaultMeshRSSIThreshold int16 = 1
 
 type Network struct {
-	ssid     string          `json:"ssid"`
-	scanSsid ScanSSID        `json:"scanSsid,omitempty"`
-	bssid    string          `json:"bssid,omitempty"`
-	priority uint            `json:"priority,omitempty"`
-	mode     Mode            `json:"mode,omitempty"`
-	proto    []Proto         `json:"proto,omitempty"`
-	keyMngnt []KeyManagement `json:"key_mgmt"`
-	authAlg  []AuthAlg       `json:"auth_Alg,omitempty"`
-	pairWise []PairWise      `json:"pairwise,omitempty"`
-	group    []Group         `json:"group,omitempty"`
-	psk      string          `json:"psk,omitempty"`
-	eaPol    EapolFlag       `json:"eapol_flags,omitempty"`
-	eap      []eapMethod
+	ssid     string
+	scanSsid ScanSSID
+	bssid    string
+	priority uint
+	mode     Mode
+	proto    []Proto
+	keyMngnt []KeyManagement
+	authAlg  []AuthAlg
+	pairWise []PairWise
+	group    []Group
+	psk      string
+	// pskExternal is the name of the psk in the ext_password_backend
+	pskExternal   string
+	saePassword   string
+	saePasswordID string
+	eaPol         EapolFlag
+	eap           []eapMethod
+	macsec        macsecConfig
+	frequency     uint32
+	mesh          meshConfig
+	ap            apConfig
+	bgscan        bgscanConfig
+	pkc           ProactiveKeyCaching
+}
+
+// macsecConfig holds the IEEE 802.1AE options of a network. Negative values mean unset.
+type macsecConfig struct {
+	policy        MacsecPolicy
+	integOnly     MacsecIntegOnly
+	replayProtect MacsecReplayProtect
+	replayWindow  int64
+	offload       MacsecOffload
+	port          int32
+	mkaCak        string
+	mkaCkn        string
+	mkaPriority   int16
+}
+
+func newMacsecConfig() macsecConfig {
+	return macsecConfig{
+		policy:        -1,
+		integOnly:     -1,
+		replayProtect: -1,
+		replayWindow:  -1,
+		offload:       -1,
+		port:          -1,
+		mkaPriority:   -1,
+	}
+}
+
+func (m *macsecConfig) isSet() bool {
+	return *m != newMacsecConfig()
+}
+
+// meshConfig holds the IEEE 802.11s options of a mesh point network. Negative values mean unset.
+type meshConfig struct {
+	fwding        MeshFwding
+	rssiThreshold int16
+	maxRetries    int16
+}
+
+func newMeshConfig() meshConfig {
+	return meshConfig{
+		fwding:        -1,
+		rssiThreshold: defaultMeshRSSIThreshold,
+		maxRetries:    -1,
+	}
+}
+
+func (m *meshConfig) isSet() bool {
+	return *m != newMeshConfig()
+}
+
+// apConfig holds the options of a network this device starts itself. The radio options apply to
+// AP and mesh mode, maxInactivity and wpsDisabled to AP mode only. Negative values mean unset.
+type apConfig struct {
+	ht40          HT40
+	vht           VHT
+	he            HE
+	beaconInt     int32
+	dtimPeriod    int16
+	maxInactivity int64
+	wpsDisabled   WPSDisabled
+}
+
+func newAPConfig() apConfig {
+	return apConfig{
+		ht40:          -1,
+		vht:           -1,
+		he:            -1,
+		beaconInt:     -1,
+		dtimPeriod:    -1,
+		maxInactivity: -1,
+		wpsDisabled:   -1,
+	}
+}
+
+func (a *apConfig) isSet() bool {
+	return *a != newAPConfig()
+}
+
+// bgscanConfig is the background scan module of a network, empty when bgscan is not set
+type bgscanConfig struct {
+	module          string
+	shortInterval   uint32
+	signalThreshold int16
+	longInterval    uint32
+	dbFile          string
+}
+
+func (g *bgscanConfig) toConfigValue() string {
+	value := fmt.Sprintf("%s:%d:%d:%d", g.module, g.shortInterval, g.signalThreshold, g.longInterval)
+	if g.dbFile != "" {
+		value += ":" + g.dbFile
+	}
+	return value
+}
+
+func (a *apConfig) isAPOnlySet() bool {
+	return a.maxInactivity != -1 || a.wpsDisabled != -1
 }
 
 type networkBuilder interface {
@@ -79,27 +250,63 @@ type networkBuilder interface {
 	WithPairWise(wise ...PairWise) networkBuilder
 	WithGroup(group ...Group) networkBuilder
 	WithPSK(psk string) networkBuilder
+	WithPSKStorage(storage PSKStorage) networkBuilder
+	WithExternalPSK(name string) networkBuilder
+	WithSAEPassword(password string) networkBuilder
+	WithSAEPasswordID(id string) networkBuilder
 	WithEapolFlag(flag EapolFlag) networkBuilder
 	WithEAPMethods(eapMethod ...eapMethod) networkBuilder
+	WithMacsecPolicy(policy MacsecPolicy) networkBuilder
+	WithMacsecIntegOnly(integOnly MacsecIntegOnly) networkBuilder
+	WithMacsecReplayProtect(replayProtect MacsecReplayProtect) networkBuilder
+	WithMacsecReplayWindow(window uint32) networkBuilder
+	WithMacsecPort(port uint16) networkBuilder
+	WithMacsecOffload(offload MacsecOffload) networkBuilder
+	WithMKAPSK(cak, ckn string) networkBuilder
+	WithMKAPriority(prio uint8) networkBuilder
+	WithFrequency(freq uint32) networkBuilder
+	WithMeshFwding(fwding MeshFwding) networkBuilder
+	WithMeshRSSIThreshold(threshold int16) networkBuilder
+	WithDot11MeshMaxRetries(retries uint8) networkBuilder
+	WithHT40(ht40 HT40) networkBuilder
+	WithVHT(vht VHT) networkBuilder
+	WithHE(he HE) networkBuilder
+	WithBeaconInt(interval uint16) networkBuilder
+	WithDTIMPeriod(period uint8) networkBuilder
+	WithAPMaxInactivity(seconds uint32) networkBuilder
+	WithWPSDisabled(disabled WPSDisabled) networkBuilder
+	WithBgscanSimple(shortInterval uint32, signalThreshold int16, longInterval uint32) networkBuilder
+	WithBgscanLearn(shortInterval uint32, signalThreshold int16, longInterval uint32, dbFile string) networkBuilder
+	WithProactiveKeyCaching(pkc ProactiveKeyCaching) networkBuilder
 	Build() (*Network, error)
 }
 
 type NetworkBuilder struct {
 	// Ssid Service Set IDentifier
-	ssid     string   `json:"ssid"`
-	scanSsid ScanSSID `json:"scanSsid,omitempty"`
+	ssid     string
+	scanSsid ScanSSID
 	// Bssid Basic Service Set IDentifier
-	bssid      string          `json:"bssid,omitempty"`
-	priority   uint            `json:"priority,omitempty"`
-	mode       Mode            `json:"mode,omitempty"`
-	proto      []Proto         `json:"proto,omitempty"`
-	keyMngnt   []KeyManagement `json:"key_mgmt"`
-	authAlg    []AuthAlg       `json:"auth_Alg,omitempty"`
-	pairWise   []PairWise      `json:"pairwise,omitempty"`
-	group      []Group         `json:"group,omitempty"`
-	psk        string          `json:"psk,omitempty"`
-	eaPol      EapolFlag       `json:"eapol_flags,omitempty"`
-	eapMethods []eapMethod
+	bssid         string
+	priority      uint
+	mode          Mode
+	proto         []Proto
+	keyMngnt      []KeyManagement
+	authAlg       []AuthAlg
+	pairWise      []PairWise
+	group         []Group
+	psk           string
+	pskStorage    PSKStorage
+	pskExternal   string
+	saePassword   string
+	saePasswordID string
+	eaPol         EapolFlag
+	eapMethods    []eapMethod
+	macsec        macsecConfig
+	frequency     uint32
+	mesh          meshConfig
+	ap            apConfig
+	bgscan        bgscanConfig
+	pkc           ProactiveKeyCaching
 }
 
 func NewNetworkBuilder() networkBuilder {
@@ -108,6 +315,10 @@ func NewNetworkBuilder() networkBuilder {
 		priority: 0,
 		mode:     -1,
 		eaPol:    -1,
+		macsec:   newMacsecConfig(),
+		mesh:     newMeshConfig(),
+		ap:       newAPConfig(),
+		pkc:      -1,
 	}
 	return &netBuilder
 }
@@ -144,8 +355,10 @@ func (b *NetworkBuilder) WithPriority(prio uint) networkBuilder {
 	return b
 }
 
-// WithMode IEEE 802.11 operation mode; either 0 (infrastructure, default) or 1 (IBSS).
+// WithMode IEEE 802.11 operation mode; either 0 (infrastructure, default), 1 (IBSS), 2 (AP) or 5 (mesh).
 // Note that IBSS (adhoc) mode can only be used with key_mgmt set to NONE (plaintext and static WEP).
+// Mesh mode requires a frequency and key_mgmt set to NONE (open mesh) or SAE (secure mesh).
+// AP mode requires a frequency and key_mgmt set to NONE, WPA-PSK or SAE.
 func (b *NetworkBuilder) WithMode(mode Mode) networkBuilder {
 	b.mode = mode
 	return b
@@ -162,7 +375,8 @@ func (b *NetworkBuilder) WithProto(proto ...Proto) networkBuilder {
 // WithKeyManagement List of acceptable key management protocols; one or more of: WPA-PSK (WPA pre-shared key),
 // WPA-EAP (WPA using EAP authentication),
 // IEEE8021X (IEEE 802.1x using EAP authentication and, optionally, dynamically generated WEP keys),
-// NONE (plaintext or static WEP keys). If not set this defaults to “WPA-PSK WPA-EAP”.
+// NONE (plaintext or static WEP keys), SAE (simultaneous authentication of equals, WPA3 personal),
+// FT-PSK and FT-EAP (IEEE 802.11r fast BSS transition with a pre-shared key or EAP). If not set this defaults to “WPA-PSK WPA-EAP”.
 func (b *NetworkBuilder) WithKeyManagement(keyMng ...KeyManagement) networkBuilder {
 	b.keyMngnt = make([]KeyManagement, 0)
 	b.keyMngnt = append(b.keyMngnt, keyMng...)
@@ -210,6 +424,35 @@ func (b *NetworkBuilder) WithPSK(psk string) networkBuilder {
 	return b
 }
 
+// WithPSKStorage selects how a passphrase set with WithPSK is written. PSKStoragePMK derives the
+// 64 hex digit key from passphrase and SSID when the network is built, the passphrase itself is
+// not kept. SAE needs the passphrase, use WithSAEPassword for SAE networks stored as PMK.
+func (b *NetworkBuilder) WithPSKStorage(storage PSKStorage) networkBuilder {
+	b.pskStorage = storage
+	return b
+}
+
+// WithExternalPSK references the psk by name in the ext_password_backend of the interface instead
+// of writing it to the config (psk=ext:<name>). wpa_supplicant only resolves ext: references from
+// config files.
+func (b *NetworkBuilder) WithExternalPSK(name string) networkBuilder {
+	b.pskExternal = name
+	return b
+}
+
+// WithSAEPassword SAE password, used instead of the psk passphrase for SAE. Unlike the psk it has
+// no length limit.
+func (b *NetworkBuilder) WithSAEPassword(password string) networkBuilder {
+	b.saePassword = password
+	return b
+}
+
+// WithSAEPasswordID SAE password identifier, selects one of several passwords configured on the AP
+func (b *NetworkBuilder) WithSAEPasswordID(id string) networkBuilder {
+	b.saePasswordID = id
+	return b
+}
+
 // WithEapolFlag Dynamic WEP key usage for non-WPA mode, specified as a bit field. Bit 0 (1) forces dynamically
 // generated unicast WEP keys to be used. Bit 1 (2) forces dynamically generated broadcast WEP keys to be used.
 // By default this is set to 3 (use both)
@@ -233,35 +476,206 @@ func (b *NetworkBuilder) WithEAPMethods(eapMethods ...eapMethod) networkBuilder
 	return b
 }
 
+// WithMacsecPolicy IEEE 802.1X/MACsec transmit mode;
+// 0 (Should secure, accept key server's advice to determine whether to use a secure session or not, default);
+// 1 (Must secure, require the key server to select a cipher suite).
+// Requires the macsec_linux driver.
+func (b *NetworkBuilder) WithMacsecPolicy(policy MacsecPolicy) networkBuilder {
+	b.macsec.policy = policy
+	return b
+}
+
+// WithMacsecIntegOnly IEEE 802.1X/MACsec transmit mode;
+// 0 (Encrypt traffic, default); 1 (Integrity only).
+func (b *NetworkBuilder) WithMacsecIntegOnly(integOnly MacsecIntegOnly) networkBuilder {
+	b.macsec.integOnly = integOnly
+	return b
+}
+
+// WithMacsecReplayProtect IEEE 802.1X/MACsec replay protection;
+// 0 (Replay protection disabled, default); 1 (Replay protection enabled).
+func (b *NetworkBuilder) WithMacsecReplayProtect(replayProtect MacsecReplayProtect) networkBuilder {
+	b.macsec.replayProtect = replayProtect
+	return b
+}
+
+// WithMacsecReplayWindow IEEE 802.1X/MACsec replay protection window, the number of packets that
+// may be received out of order. 0 (default) means no out of order packets are accepted.
+// Only used when replay protection is enabled.
+func (b *NetworkBuilder) WithMacsecReplayWindow(window uint32) networkBuilder {
+	b.macsec.replayWindow = int64(window)
+	return b
+}
+
+// WithMacsecPort IEEE 802.1X/MACsec port; port component of the SCI, range 1-65534 (default 1).
+func (b *NetworkBuilder) WithMacsecPort(port uint16) networkBuilder {
+	b.macsec.port = int32(port)
+	return b
+}
+
+// WithMacsecOffload IEEE 802.1X/MACsec hardware offload;
+// 0 (No offload, default); 1 (Offload to the PHY); 2 (Offload to the MAC).
+func (b *NetworkBuilder) WithMacsecOffload(offload MacsecOffload) networkBuilder {
+	b.macsec.offload = offload
+	return b
+}
+
+// WithMKAPSK sets a pre-shared MKA connectivity association; cak (CAK) is a 16 or 32 byte key and
+// ckn (CKN) a 1 to 32 byte key name, both given as hex strings.
+// MKA in PSK mode requires key_mgmt set to NONE, without it MKA is keyed through IEEE8021X.
+func (b *NetworkBuilder) WithMKAPSK(cak, ckn string) networkBuilder {
+	b.macsec.mkaCak = cak
+	b.macsec.mkaCkn = ckn
+	return b
+}
+
+// WithMKAPriority sets the key server priority of the MKA actor, range 0-255 (default 255, lower is preferred).
+func (b *NetworkBuilder) WithMKAPriority(prio uint8) networkBuilder {
+	b.macsec.mkaPriority = int16(prio)
+	return b
+}
+
+// WithFrequency channel frequency in MHz (e.g. 2412 for channel 1) used when this device starts
+// the network itself, as in IBSS, AP and mesh mode. Ignored in infrastructure mode.
+func (b *NetworkBuilder) WithFrequency(freq uint32) networkBuilder {
+	b.frequency = freq
+	return b
+}
+
+// WithMeshFwding enables (1, default) or disables (0) forwarding of frames for other mesh points.
+// Only valid in mesh mode.
+func (b *NetworkBuilder) WithMeshFwding(fwding MeshFwding) networkBuilder {
+	b.mesh.fwding = fwding
+	return b
+}
+
+// WithMeshRSSIThreshold sets the minimum RSSI in dBm a mesh peer needs to be accepted, range -255 to 1.
+// 1 (default) disables the threshold. Only valid in mesh mode.
+func (b *NetworkBuilder) WithMeshRSSIThreshold(threshold int16) networkBuilder {
+	b.mesh.rssiThreshold = threshold
+	return b
+}
+
+// WithDot11MeshMaxRetries sets the number of mesh peering open frames sent before the peering
+// attempt is given up (default 2). Only valid in mesh mode.
+func (b *NetworkBuilder) WithDot11MeshMaxRetries(retries uint8) networkBuilder {
+	b.mesh.maxRetries = int16(retries)
+	return b
+}
+
+// WithHT40 enables (1) or disables (0) 40 MHz channels (HT40) in AP and mesh mode.
+func (b *NetworkBuilder) WithHT40(ht40 HT40) networkBuilder {
+	b.ap.ht40 = ht40
+	return b
+}
+
+// WithVHT enables (1) or disables (0) IEEE 802.11ac (VHT) in AP and mesh mode.
+func (b *NetworkBuilder) WithVHT(vht VHT) networkBuilder {
+	b.ap.vht = vht
+	return b
+}
+
+// WithHE enables (1) or disables (0) IEEE 802.11ax (HE) in AP and mesh mode.
+func (b *NetworkBuilder) WithHE(he HE) networkBuilder {
+	b.ap.he = he
+	return b
+}
+
+// WithBeaconInt beacon interval in TUs (1.024 ms), range 15-65535 (default 100). AP and mesh mode.
+func (b *NetworkBuilder) WithBeaconInt(interval uint16) networkBuilder {
+	b.ap.beaconInt = int32(interval)
+	return b
+}
+
+// WithDTIMPeriod DTIM period in beacon intervals, range 1-255 (default 2). AP and mesh mode.
+func (b *NetworkBuilder) WithDTIMPeriod(period uint8) networkBuilder {
+	b.ap.dtimPeriod = int16(period)
+	return b
+}
+
+// WithAPMaxInactivity time in seconds after which an inactive station is disconnected (default 300).
+// Only valid in AP mode.
+func (b *NetworkBuilder) WithAPMaxInactivity(seconds uint32) networkBuilder {
+	b.ap.maxInactivity = int64(seconds)
+	return b
+}
+
+// WithWPSDisabled disables (1) or enables (0, default) WPS for the AP. Only valid in AP mode.
+func (b *NetworkBuilder) WithWPSDisabled(disabled WPSDisabled) networkBuilder {
+	b.ap.wpsDisabled = disabled
+	return b
+}
+
+// WithBgscanSimple enables background scanning for a better access point while connected. The
+// supplicant scans every shortInterval seconds when the signal is below signalThreshold (dBm)
+// and every longInterval seconds otherwise.
+func (b *NetworkBuilder) WithBgscanSimple(shortInterval uint32, signalThreshold int16, longInterval uint32) networkBuilder {
+	b.bgscan = bgscanConfig{module: "simple", shortInterval: shortInterval, signalThreshold: signalThreshold, longInterval: longInterval}
+	return b
+}
+
+// WithBgscanLearn is like WithBgscanSimple but learns the channels of the ESS and keeps them in
+// dbFile, so background scans can be limited to the channels in use.
+func (b *NetworkBuilder) WithBgscanLearn(shortInterval uint32, signalThreshold int16, longInterval uint32, dbFile string) networkBuilder {
+	b.bgscan = bgscanConfig{module: "learn", shortInterval: shortInterval, signalThreshold: signalThreshold, longInterval: longInterval, dbFile: dbFile}
+	return b
+}
+
+// WithProactiveKeyCaching enables (1) or disables (0) opportunistic key caching (OKC) for this
+// network. If not set the global okc option of the interface is used.
+func (b *NetworkBuilder) WithProactiveKeyCaching(pkc ProactiveKeyCaching) networkBuilder {
+	b.pkc = pkc
+	return b
+}
+
 func (b *NetworkBuilder) Build() (*Network, error) {
 	err := b.validate()
 	if err != nil {
 		return nil, err
 	}
+	psk := b.psk
+	if b.pskStorage == PSKStoragePMK && isPassphrase(psk) {
+		psk, err = DerivePSK(b.ssid, psk)
+		if err != nil {
+			return nil, err
+		}
+	}
 	netConfig := Network{
-		ssid:     b.ssid,
-		scanSsid: b.scanSsid,
-		bssid:    b.bssid,
-		priority: b.priority,
-		mode:     b.mode,
-		proto:    b.proto,
-		keyMngnt: b.keyMngnt,
-		authAlg:  b.authAlg,
-		pairWise: b.pairWise,
-		group:    b.group,
-		psk:      b.psk,
-		eaPol:    b.eaPol,
-		eap:      b.eapMethods,
+		ssid:          b.ssid,
+		scanSsid:      b.scanSsid,
+		bssid:         b.bssid,
+		priority:      b.priority,
+		mode:          b.mode,
+		proto:         b.proto,
+		keyMngnt:      b.keyMngnt,
+		authAlg:       b.authAlg,
+		pairWise:      b.pairWise,
+		group:         b.group,
+		psk:           psk,
+		pskExternal:   b.pskExternal,
+		saePassword:   b.saePassword,
+		saePasswordID: b.saePasswordID,
+		eaPol:         b.eaPol,
+		eap:           b.eapMethods,
+		macsec:        b.macsec,
+		frequency:     b.frequency,
+		mesh:          b.mesh,
+		ap:            b.ap,
+		bgscan:        b.bgscan,
+		pkc:           b.pkc,
 	}
 	return &netConfig, nil
 }
 
 func (b *NetworkBuilder) validate() error {
-	if b.ssid == "" {
+	if b.ssid == "" && b.macsec.mkaCak == "" {
 		if !contains(b.keyMngnt, IEEE8021X) {
 			return errors.New("no ssid specified and no IEEE8021X key mngt. specify at least one")
 		}
 	}
+	if len(b.ssid) > maxSSIDLength {
+		return errors.New("invalid value for ssid. must be at most 32 bytes")
+	}
 	if b.scanSsid != -1 && !contains(scanSlice, b.scanSsid) {
 		return errors.New("invalid value for scanSSID")
 	}
@@ -283,84 +697,407 @@ func (b *NetworkBuilder) validate() error {
 	if !contains(groupSlice, b.group) {
 		return errors.New("invalid value for group")
 	}
+	if b.psk != "" && !isHexPSK(b.psk) && !isPassphrase(b.psk) {
+		return errors.New("invalid value for psk. must be 8 to 63 ascii characters or 64 hex digits")
+	}
 	if b.eaPol != -1 && !contains(eapFlagSlice, b.eaPol) {
 		return errors.New("invalid value for eapol flag")
 	}
-	if len(b.eapMethods) == 0 {
+	if len(b.eapMethods) == 0 && b.requiresEAP() {
 		return errors.New("at least one eap method must be specifed")
 	}
+	err := b.validatePSK()
+	if err != nil {
+		return err
+	}
+	err = b.validateMesh()
+	if err != nil {
+		return err
+	}
+	err = b.validateAP()
+	if err != nil {
+		return err
+	}
+	err = b.validateRoaming()
+	if err != nil {
+		return err
+	}
+	return b.validateMacsec()
+}
+
+func (b *NetworkBuilder) validatePSK() error {
+	if !contains(pskStorageSlice, b.pskStorage) {
+		return errors.New("invalid value for psk storage")
+	}
+	if b.pskExternal != "" && !isExternalName(b.pskExternal) {
+		return errors.New("invalid value for external psk name")
+	}
+	if b.pskExternal != "" && b.psk != "" {
+		return errors.New("psk and external psk can not be combined")
+	}
+	if b.pskStorage == PSKStoragePMK && isPassphrase(b.psk) && b.ssid == "" {
+		return errors.New("psk storage pmk requires an ssid")
+	}
+	sae := contains(b.keyMngnt, SAE)
+	if !sae && (b.saePassword != "" || b.saePasswordID != "") {
+		return errors.New("sae password and sae password id require key management SAE")
+	}
+	if b.saePasswordID != "" && (strings.ContainsAny(b.saePasswordID, "\n\r") || len(b.saePasswordID) > 255) {
+		return errors.New("invalid value for sae password id")
+	}
+	if !sae || b.saePassword != "" || b.pskExternal != "" {
+		return nil
+	}
+	if !isPassphrase(b.psk) {
+		return errors.New("sae requires an 8 to 63 character psk passphrase or a sae password")
+	}
+	if b.pskStorage == PSKStoragePMK {
+		return errors.New("sae can not use a pmk, set a sae password")
+	}
+	return nil
+}
+
+func (b *NetworkBuilder) validateRoaming() error {
+	if b.pkc != -1 && !contains(proactiveKeyCachingSlice, b.pkc) {
+		return errors.New("invalid value for proactive key caching")
+	}
+	if b.bgscan.module == "" {
+		return nil
+	}
+	if b.bgscan.shortInterval == 0 || b.bgscan.shortInterval > b.bgscan.longInterval {
+		return errors.New("invalid value for bgscan. short interval must be between 1 and the long interval")
+	}
+	if b.bgscan.signalThreshold >= 0 {
+		return errors.New("invalid value for bgscan. signal threshold must be negative dBm")
+	}
+	return nil
+}
+
+func (b *NetworkBuilder) validateAP() error {
+	if b.mode != ModeAP && b.ap.isAPOnlySet() {
+		return errors.New("ap max inactivity and wps disabled require mode ap")
+	}
+	if b.mode != ModeAP && b.mode != ModeMesh && b.ap.isSet() {
+		return errors.New("ht40, vht, he, beacon int and dtim period require mode ap or mesh")
+	}
+	if b.ap.ht40 != -1 && !contains(ht40Slice, b.ap.ht40) {
+		return errors.New("invalid value for ht40")
+	}
+	if b.ap.vht != -1 && !contains(vhtSlice, b.ap.vht) {
+		return errors.New("invalid value for vht")
+	}
+	if b.ap.he != -1 && !contains(heSlice, b.ap.he) {
+		return errors.New("invalid value for he")
+	}
+	if b.ap.beaconInt != -1 && b.ap.beaconInt < 15 {
+		return errors.New("invalid value for beacon int. must be between 15 and 65535")
+	}
+	if b.ap.dtimPeriod == 0 {
+		return errors.New("invalid value for dtim period. must be between 1 and 255")
+	}
+	if b.ap.wpsDisabled != -1 && !contains(wpsDisabledSlice, b.ap.wpsDisabled) {
+		return errors.New("invalid value for wps disabled")
+	}
+	if b.mode != ModeAP {
+		return nil
+	}
+	if b.ssid == "" {
+		return errors.New("ap requires an ssid")
+	}
+	if b.frequency == 0 {
+		return errors.New("ap requires a frequency")
+	}
+	if len(b.keyMngnt) == 0 || !contains(apKeyMngtSlice, b.keyMngnt) {
+		return errors.New("ap requires key management NONE, WPA-PSK or SAE")
+	}
+	if contains(b.keyMngnt, WpaPSK) && b.psk == "" && b.pskExternal == "" {
+		return errors.New("ap with WPA-PSK requires a psk")
+	}
+	if len(b.eapMethods) > 0 || b.macsec.isSet() {
+		return errors.New("ap does not support eap or macsec")
+	}
 	return nil
 }
 
+func (b *NetworkBuilder) validateMesh() error {
+	if b.mode != ModeMesh {
+		if b.mesh.isSet() {
+			return errors.New("mesh options require mode mesh")
+		}
+		return nil
+	}
+	if b.ssid == "" {
+		return errors.New("mesh requires an ssid")
+	}
+	if b.frequency == 0 {
+		return errors.New("mesh requires a frequency")
+	}
+	if len(b.keyMngnt) != 1 || !contains(meshKeyMngtSlice, b.keyMngnt[0]) {
+		return errors.New("mesh requires key management NONE or SAE")
+	}
+	if len(b.eapMethods) > 0 || b.macsec.isSet() {
+		return errors.New("mesh does not support eap or macsec")
+	}
+	if b.mesh.fwding != -1 && !contains(meshFwdingSlice, b.mesh.fwding) {
+		return errors.New("invalid value for mesh fwding")
+	}
+	if b.mesh.rssiThreshold < -255 || b.mesh.rssiThreshold > 1 {
+		return errors.New("invalid value for mesh rssi threshold. must be between -255 and 1")
+	}
+	return nil
+}
+
+func (b *NetworkBuilder) requiresEAP() bool {
+	if len(b.keyMngnt) == 0 {
+		return true
+	}
+	for i := 0; i < len(b.keyMngnt); i++ {
+		if contains(eapKeyMngtSlice, b.keyMngnt[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *NetworkBuilder) validateMacsec() error {
+	if !b.macsec.isSet() {
+		return nil
+	}
+	if b.macsec.policy != -1 && !contains(macsecPolicySlice, b.macsec.policy) {
+		return errors.New("invalid value for macsec policy")
+	}
+	if b.macsec.integOnly != -1 && !contains(macsecIntegOnlySlice, b.macsec.integOnly) {
+		return errors.New("invalid value for macsec integ only")
+	}
+	if b.macsec.replayProtect != -1 && !contains(macsecReplayProtectSlice, b.macsec.replayProtect) {
+		return errors.New("invalid value for macsec replay protect")
+	}
+	if b.macsec.replayWindow != -1 && b.macsec.replayProtect != MacsecReplayProtectOn {
+		return errors.New("macsec replay window requires macsec replay protect")
+	}
+	if b.macsec.offload != -1 && !contains(macsecOffloadSlice, b.macsec.offload) {
+		return errors.New("invalid value for macsec offload")
+	}
+	if b.macsec.port != -1 && (b.macsec.port < 1 || b.macsec.port > 65534) {
+		return errors.New("invalid value for macsec port. must be between 1 and 65534")
+	}
+	if b.macsec.mkaCak == "" && b.macsec.mkaCkn == "" {
+		if len(b.keyMngnt) != 1 || b.keyMngnt[0] != IEEE8021X {
+			return errors.New("macsec without mka psk requires key management IEEE8021X")
+		}
+		return nil
+	}
+	if !isHexString(b.macsec.mkaCak) || (len(b.macsec.mkaCak) != 32 && len(b.macsec.mkaCak) != 64) {
+		return errors.New("invalid value for mka cak. must be 16 or 32 bytes in hex")
+	}
+	if !isHexString(b.macsec.mkaCkn) || len(b.macsec.mkaCkn) < 2 || len(b.macsec.mkaCkn) > 64 {
+		return errors.New("invalid value for mka ckn. must be 1 to 32 bytes in hex")
+	}
+	if len(b.keyMngnt) != 1 || b.keyMngnt[0] != NONE {
+		return errors.New("mka psk requires key management NONE")
+	}
+	return nil
+}
+
+// maxSSIDLength is the longest SSID IEEE 802.11 allows, in bytes
+const maxSSIDLength = 32
+
+// isNtPasswordHash reports whether hash is a NtPasswordHash as used by hash: passwords
+func isNtPasswordHash(hash string) bool {
+	return len(hash) == 32 && isHexString(hash)
+}
+
+func isHexPSK(psk string) bool {
+	return len(psk) == 64 && isHexString(psk)
+}
+
+func isPassphrase(passphrase string) bool {
+	if len(passphrase) < 8 || len(passphrase) > 63 {
+		return false
+	}
+	for i := 0; i < len(passphrase); i++ {
+		if passphrase[i] < 32 || passphrase[i] > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexString(value string) bool {
+	if len(value)%2 != 0 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
 func (net *Network) ToConfigString() string {
 	builder := strings.Builder{}
 	builder.WriteString("network={\n")
+	builder.WriteString(renderConfigFields("  ", net.configFields()))
+	for i := 0; i < len(net.eap); i++ {
+		builder.WriteString(renderConfigFields("  ", net.eap[i].configFields()))
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// toDBusArgs converts the network into the dictionary expected by AddNetwork and MeshGroupAdd.
+// The EAP method parameters are included, when several methods set the same key the last one wins.
+func (net *Network) toDBusArgs() map[string]interface{} {
+	return configFieldsToDBusArgs(net.allConfigFields())
+}
+
+// allConfigFields returns the network fields followed by the fields of its EAP methods
+func (net *Network) allConfigFields() []configField {
+	fields := net.configFields()
+	for i := 0; i < len(net.eap); i++ {
+		fields = append(fields, net.eap[i].configFields()...)
+	}
+	return fields
+}
+
+func (net *Network) configFields() []configField {
+	fields := make([]configField, 0)
 	if net.ssid != "" {
-		builder.WriteString(fmt.Sprintf("  ssid=\"%s\"\n", net.ssid))
+		fields = append(fields, quotedField("ssid", net.ssid))
 	}
 	if net.scanSsid != -1 {
-		builder.WriteString(fmt.Sprintf("  scan_ssid=%d\n", net.scanSsid))
+		fields = append(fields, rawField("scan_ssid", net.scanSsid))
 	}
 	if net.bssid != "" {
-		builder.WriteString(fmt.Sprintf("  bssid=\"%s\"\n", net.bssid))
+		fields = append(fields, rawField("bssid", net.bssid))
 	}
 	if net.priority != 0 {
-		builder.WriteString(fmt.Sprintf("  priority=%d\n", net.priority))
+		fields = append(fields, intField("priority", net.priority))
 	}
 	if net.mode != -1 {
-		builder.WriteString(fmt.Sprintf("  mode=%d\n", net.mode))
+		fields = append(fields, intField("mode", net.mode))
 	}
-	if net.proto != nil && len(net.proto) > 0 {
-		builder.WriteString(fmt.Sprintf("  proto=%s", net.proto[0]))
-		for i := 1; i < len(net.proto); i++ {
-			builder.WriteString(fmt.Sprintf(" %s", net.proto[i]))
-		}
-		builder.WriteString("\n")
+	if net.frequency != 0 {
+		fields = append(fields, intField("frequency", net.frequency))
 	}
-	if net.keyMngnt != nil && len(net.keyMngnt) > 0 {
-		builder.WriteString(fmt.Sprintf("  key_mgmt=%s", net.keyMngnt[0]))
-		for i := 1; i < len(net.keyMngnt); i++ {
-			builder.WriteString(fmt.Sprintf(" %s", net.keyMngnt[i]))
-		}
-		builder.WriteString("\n")
+	if len(net.proto) > 0 {
+		fields = append(fields, listField("proto", net.proto))
 	}
-	if net.authAlg != nil && len(net.authAlg) > 0 {
-		builder.WriteString(fmt.Sprintf("  auth_alg=%s", net.authAlg[0]))
-		for i := 1; i < len(net.authAlg); i++ {
-			builder.WriteString(fmt.Sprintf(" %s", net.authAlg[i]))
-		}
-		builder.WriteString("\n")
+	if len(net.keyMngnt) > 0 {
+		fields = append(fields, listField("key_mgmt", net.keyMngnt))
 	}
-	if net.pairWise != nil && len(net.pairWise) > 0 {
-		builder.WriteString(fmt.Sprintf("  pairwise=%s", net.pairWise[0]))
-		for i := 1; i < len(net.pairWise); i++ {
-			builder.WriteString(fmt.Sprintf(" %s", net.pairWise[i]))
-		}
-		builder.WriteString("\n")
+	if len(net.authAlg) > 0 {
+		fields = append(fields, listField("auth_alg", net.authAlg))
 	}
-	if net.group != nil && len(net.group) > 0 {
-		builder.WriteString(fmt.Sprintf("  group=%s", net.group[0]))
-		for i := 1; i < len(net.group); i++ {
-			builder.WriteString(fmt.Sprintf(" %s", net.group[i]))
-		}
-		builder.WriteString("\n")
+	if len(net.pairWise) > 0 {
+		fields = append(fields, listField("pairwise", net.pairWise))
+	}
+	if len(net.group) > 0 {
+		fields = append(fields, listField("group", net.group))
 	}
 	if net.psk != "" {
-		builder.WriteString(fmt.Sprintf("  ssid=%s\n", net.psk))
+		if isHexPSK(net.psk) {
+			fields = append(fields, hexField("psk", net.psk))
+		} else {
+			fields = append(fields, passphraseField("psk", net.psk))
+		}
+	}
+	if net.pskExternal != "" {
+		fields = append(fields, rawField("psk", "ext:"+net.pskExternal))
+	}
+	if net.saePassword != "" {
+		fields = append(fields, quotedField("sae_password", net.saePassword))
+	}
+	if net.saePasswordID != "" {
+		fields = append(fields, quotedField("sae_password_id", net.saePasswordID))
 	}
 	if net.eaPol != -1 {
-		builder.WriteString(fmt.Sprintf("  eapol_flags=%d\n", net.eaPol))
+		fields = append(fields, intField("eapol_flags", net.eaPol))
 	}
-	if net.eap != nil && len(net.eap) > 0 {
-		builder.WriteString(fmt.Sprintf("  eap=%s", net.eap[0].GetEAPName()))
-		for i := 1; i < len(net.eap); i++ {
-			builder.WriteString(fmt.Sprintf(" %s", net.eap[i].GetEAPName()))
-		}
-		builder.WriteString("\n")
+	fields = append(fields, net.macsec.configFields()...)
+	fields = append(fields, net.mesh.configFields()...)
+	fields = append(fields, net.ap.configFields()...)
+	if net.bgscan.module != "" {
+		fields = append(fields, quotedField("bgscan", net.bgscan.toConfigValue()))
+	}
+	if net.pkc != -1 {
+		fields = append(fields, intField("proactive_key_caching", net.pkc))
+	}
+	if len(net.eap) > 0 {
+		names := make([]string, 0, len(net.eap))
 		for i := 0; i < len(net.eap); i++ {
-			builder.WriteString(net.eap[i].ToConfigString())
+			names = append(names, net.eap[i].GetEAPName())
 		}
+		fields = append(fields, listField("eap", names))
 	}
-	builder.WriteString("}\n")
-	return builder.String()
+	return fields
+}
+
+func (m *meshConfig) configFields() []configField {
+	fields := make([]configField, 0)
+	if m.fwding != -1 {
+		fields = append(fields, intField("mesh_fwding", m.fwding))
+	}
+	if m.rssiThreshold != defaultMeshRSSIThreshold {
+		fields = append(fields, intField("mesh_rssi_threshold", m.rssiThreshold))
+	}
+	if m.maxRetries != -1 {
+		fields = append(fields, intField("dot11MeshMaxRetries", m.maxRetries))
+	}
+	return fields
+}
+
+func (a *apConfig) configFields() []configField {
+	fields := make([]configField, 0)
+	if a.ht40 != -1 {
+		fields = append(fields, intField("ht40", a.ht40))
+	}
+	if a.vht != -1 {
+		fields = append(fields, intField("vht", a.vht))
+	}
+	if a.he != -1 {
+		fields = append(fields, intField("he", a.he))
+	}
+	if a.beaconInt != -1 {
+		fields = append(fields, intField("beacon_int", a.beaconInt))
+	}
+	if a.dtimPeriod != -1 {
+		fields = append(fields, intField("dtim_period", a.dtimPeriod))
+	}
+	if a.maxInactivity != -1 {
+		fields = append(fields, intField("ap_max_inactivity", a.maxInactivity))
+	}
+	if a.wpsDisabled != -1 {
+		fields = append(fields, intField("wps_disabled", a.wpsDisabled))
+	}
+	return fields
+}
+
+func (m *macsecConfig) configFields() []configField {
+	fields := make([]configField, 0)
+	if m.policy != -1 {
+		fields = append(fields, intField("macsec_policy", m.policy))
+	}
+	if m.integOnly != -1 {
+		fields = append(fields, intField("macsec_integ_only", m.integOnly))
+	}
+	if m.replayProtect != -1 {
+		fields = append(fields, intField("macsec_replay_protect", m.replayProtect))
+	}
+	if m.replayWindow != -1 {
+		fields = append(fields, intField("macsec_replay_window", m.replayWindow))
+	}
+	if m.offload != -1 {
+		fields = append(fields, intField("macsec_offload", m.offload))
+	}
+	if m.port != -1 {
+		fields = append(fields, intField("macsec_port", m.port))
+	}
+	if m.mkaCak != "" {
+		fields = append(fields, hexField("mka_cak", m.mkaCak))
+	}
+	if m.mkaCkn != "" {
+		fields = append(fields, hexField("mka_ckn", m.mkaCkn))
+	}
+	if m.mkaPriority != -1 {
+		fields = append(fields, intField("mka_priority", m.mkaPriority))
+	}
+	return fields
 }
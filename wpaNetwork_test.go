@@ -0,0 +1,212 @@
+package wpaSuppDBusLib
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNetworkMacsecPSKConfText(t *testing.T) {
+	//expected text is modeled after wpa_supplicant/examples/macsec_psk.conf
+	expectedConfText := "network={\n  key_mgmt=NONE\n  eapol_flags=0\n  macsec_policy=1\n  macsec_integ_only=0\n  macsec_replay_protect=1\n  macsec_replay_window=4\n  macsec_port=1\n  mka_cak=0123456789ABCDEF0123456789ABCDEF\n  mka_ckn=6162636465666768696A6B6C6D6E6F707172737475767778797A303132333435\n  mka_priority=128\n}\n"
+
+	netBuilder := NewNetworkBuilder()
+	network, err := netBuilder.
+		WithKeyManagement(NONE).
+		WithEapolFlag(EapolOff).
+		WithMacsecPolicy(MacsecPolicyMustSecure).
+		WithMacsecIntegOnly(MacsecIntegOnlyOff).
+		WithMacsecReplayProtect(MacsecReplayProtectOn).
+		WithMacsecReplayWindow(4).
+		WithMacsecPort(1).
+		WithMKAPSK("0123456789ABCDEF0123456789ABCDEF", "6162636465666768696A6B6C6D6E6F707172737475767778797A303132333435").
+		WithMKAPriority(128).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	confStr := network.ToConfigString()
+
+	if !strings.EqualFold(expectedConfText, confStr) {
+		t.Errorf("config strings don't match")
+	}
+}
+
+func TestNetworkMacsecDBusArgs(t *testing.T) {
+	network, err := NewNetworkBuilder().WithKeyManagement(NONE).WithMacsecPort(1).
+		WithMKAPSK("00112233445566778899001122334455", "0012").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := network.toDBusArgs()
+	if ckn, ok := args["mka_ckn"].([]byte); !ok || !bytes.Equal(ckn, []byte{0x00, 0x12}) {
+		t.Errorf("hex key name must keep its leading zeros, got %#v", args["mka_ckn"])
+	}
+	if cak, ok := args["mka_cak"].([]byte); !ok || len(cak) != 16 || cak[0] != 0x00 {
+		t.Errorf("unexpected cak %#v", args["mka_cak"])
+	}
+	if args["macsec_port"] != int32(1) || args["key_mgmt"] != "NONE" {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestNetworkMacsecValidation(t *testing.T) {
+	tlsBuilder := NewTLSBuilder()
+	eapTLS, _ := tlsBuilder.WithIdentity("user").WithClientCertPath("client.pem").WithPrivateKeyPath("client.key").Build()
+	cak := "0123456789ABCDEF0123456789ABCDEF"
+
+	invalid := map[string]networkBuilder{
+		"psk with eap key mngt":      NewNetworkBuilder().WithKeyManagement(IEEE8021X).WithEAPMethods(eapTLS).WithMKAPSK(cak, "01"),
+		"eap mka without 8021x":      NewNetworkBuilder().WithKeyManagement(WpaEAP).WithSSID("net").WithEAPMethods(eapTLS).WithMacsecPolicy(MacsecPolicyMustSecure),
+		"short cak":                  NewNetworkBuilder().WithKeyManagement(NONE).WithMKAPSK("0123", "01"),
+		"missing ckn":                NewNetworkBuilder().WithKeyManagement(NONE).WithMKAPSK(cak, ""),
+		"port out of range":          NewNetworkBuilder().WithKeyManagement(NONE).WithMKAPSK(cak, "01").WithMacsecPort(65535),
+		"window without protection":  NewNetworkBuilder().WithKeyManagement(NONE).WithMKAPSK(cak, "01").WithMacsecReplayWindow(4),
+		"invalid offload":            NewNetworkBuilder().WithKeyManagement(NONE).WithMKAPSK(cak, "01").WithMacsecOffload(3),
+		"eap without any eap method": NewNetworkBuilder().WithKeyManagement(IEEE8021X).WithMacsecPolicy(MacsecPolicyMustSecure),
+	}
+	for name, builder := range invalid {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	_, err := NewNetworkBuilder().WithKeyManagement(IEEE8021X).WithEAPMethods(eapTLS).WithMacsecPolicy(MacsecPolicyMustSecure).WithMacsecOffload(MacsecOffloadPHY).Build()
+	if err != nil {
+		t.Errorf("eap based mka rejected: %v", err)
+	}
+}
+
+func TestNetworkMeshSAEConfText(t *testing.T) {
+	//expected text is modeled after wpa_supplicant/examples/mesh-sae.conf
+	expectedConfText := "max_peer_links=16\nnetwork={\n  ssid=\"lighting\"\n  mode=5\n  frequency=2437\n  key_mgmt=SAE\n  psk=\"very secret passphrase\"\n  mesh_fwding=1\n  mesh_rssi_threshold=-80\n  dot11MeshMaxRetries=4\n}\n"
+
+	network, err := NewNetworkBuilder().WithSSID("lighting").WithMode(ModeMesh).WithFrequency(2437).
+		WithKeyManagement(SAE).WithPSK("very secret passphrase").WithMeshFwding(MeshFwdingOn).
+		WithMeshRSSIThreshold(-80).WithDot11MeshMaxRetries(4).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ifBuilder := NewWpaInterfaceBuilder()
+	x, err := ifBuilder.WithCtrlInterface("/run/wpa_supplicant").WithMaxPeerLinks(16).WithNetwork(*network).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	confStr := x.ToConfigString()
+
+	if !strings.EqualFold("ctrl_interface=/run/wpa_supplicant\n"+expectedConfText, confStr) {
+		t.Errorf("config strings don't match")
+	}
+
+	args := network.toDBusArgs()
+	if args["mode"] != int32(5) || args["frequency"] != int32(2437) || args["key_mgmt"] != "SAE" || args["psk"] != "very secret passphrase" {
+		t.Errorf("unexpected MeshGroupAdd arguments %v", args)
+	}
+}
+
+func TestNetworkMeshValidation(t *testing.T) {
+	invalid := map[string]networkBuilder{
+		"mesh without frequency":    NewNetworkBuilder().WithSSID("mesh").WithMode(ModeMesh).WithKeyManagement(NONE),
+		"mesh with psk key mngt":    NewNetworkBuilder().WithSSID("mesh").WithMode(ModeMesh).WithFrequency(2412).WithKeyManagement(WpaPSK).WithPSK("passphrase"),
+		"sae without passphrase":    NewNetworkBuilder().WithSSID("mesh").WithMode(ModeMesh).WithFrequency(2412).WithKeyManagement(SAE),
+		"mesh options without mesh": NewNetworkBuilder().WithSSID("net").WithKeyManagement(NONE).WithMeshFwding(MeshFwdingOff),
+		"rssi threshold too high":   NewNetworkBuilder().WithSSID("mesh").WithMode(ModeMesh).WithFrequency(2412).WithKeyManagement(NONE).WithMeshRSSIThreshold(2),
+	}
+	for name, builder := range invalid {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+	if _, err := NewNetworkBuilder().WithSSID("mesh").WithMode(ModeMesh).WithFrequency(2412).WithKeyManagement(NONE).Build(); err != nil {
+		t.Errorf("open mesh should be valid: %v", err)
+	}
+}
+
+func TestNetworkAPConfText(t *testing.T) {
+	expectedConfText := "network={\n  ssid=\"service\"\n  mode=2\n  frequency=5180\n  key_mgmt=WPA-PSK\n  psk=\"field service key\"\n  ht40=1\n  vht=1\n  beacon_int=200\n  dtim_period=1\n  ap_max_inactivity=600\n  wps_disabled=1\n}\n"
+
+	network, err := NewNetworkBuilder().WithSSID("service").WithMode(ModeAP).WithFrequency(5180).
+		WithKeyManagement(WpaPSK).WithPSK("field service key").WithHT40(HT40On).WithVHT(VHTOn).
+		WithBeaconInt(200).WithDTIMPeriod(1).WithAPMaxInactivity(600).WithWPSDisabled(WPSDisabledOn).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.EqualFold(expectedConfText, network.ToConfigString()) {
+		t.Errorf("config strings don't match")
+	}
+
+	invalid := map[string]networkBuilder{
+		"ap without frequency":     NewNetworkBuilder().WithSSID("service").WithMode(ModeAP).WithKeyManagement(NONE),
+		"ap with eap key mngt":     NewNetworkBuilder().WithSSID("service").WithMode(ModeAP).WithFrequency(2412).WithKeyManagement(WpaEAP),
+		"ap options without ap":    NewNetworkBuilder().WithSSID("net").WithKeyManagement(NONE).WithWPSDisabled(WPSDisabledOn),
+		"radio options in station": NewNetworkBuilder().WithSSID("net").WithKeyManagement(NONE).WithHT40(HT40On),
+		"beacon int too low":       NewNetworkBuilder().WithSSID("service").WithMode(ModeAP).WithFrequency(2412).WithKeyManagement(NONE).WithBeaconInt(10),
+	}
+	for name, builder := range invalid {
+		if _, err = builder.Build(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestNetworkRoamingConfText(t *testing.T) {
+	expectedConfText := "ctrl_interface=/run/wpa_supplicant\nokc=1\nnetwork={\n  ssid=\"warehouse\"\n  key_mgmt=FT-PSK WPA-PSK\n  psk=\"robot passphrase\"\n  bgscan=\"learn:10:-65:300:/var/lib/wpa/warehouse.bgscan\"\n  proactive_key_caching=0\n}\n"
+
+	network, err := NewNetworkBuilder().WithSSID("warehouse").WithKeyManagement(FtPSK, WpaPSK).WithPSK("robot passphrase").
+		WithBgscanLearn(10, -65, 300, "/var/lib/wpa/warehouse.bgscan").WithProactiveKeyCaching(ProactiveKeyCachingOff).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := NewWpaInterfaceBuilder().WithCtrlInterface("/run/wpa_supplicant").WithOKC(OKCOn).WithNetwork(*network).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.EqualFold(expectedConfText, x.ToConfigString()) {
+		t.Errorf("config strings don't match")
+	}
+
+	invalid := map[string]networkBuilder{
+		"ft eap without eap method": NewNetworkBuilder().WithSSID("warehouse").WithKeyManagement(FtEAP),
+		"bgscan short > long":       NewNetworkBuilder().WithSSID("warehouse").WithKeyManagement(NONE).WithBgscanSimple(600, -65, 300),
+		"bgscan positive threshold": NewNetworkBuilder().WithSSID("warehouse").WithKeyManagement(NONE).WithBgscanSimple(30, 10, 300),
+	}
+	for name, builder := range invalid {
+		if _, err = builder.Build(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestNetworkDBusArgsIncludeEAP(t *testing.T) {
+	peapBuilder := NewPEAPBuilder()
+	eapPEAP, err := peapBuilder.WithIdentity("user_name").WithPassword("user_password").WithInnerAuthType(InnerAuthMsChapV2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := NewNetworkBuilder().WithSSID("corp").WithKeyManagement(WpaEAP).WithEAPMethods(eapPEAP).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := network.toDBusArgs()
+	if args["identity"] != "user_name" || args["password"] != "user_password" || args["phase2"] != "auth=MSCHAPV2" || args["eap"] != "PEAP" {
+		t.Errorf("unexpected AddNetwork arguments %v", args)
+	}
+}
+
+func TestNetworksMatchLiveProperties(t *testing.T) {
+	home, _ := NewNetworkBuilder().WithSSID("home").WithKeyManagement(WpaPSK).WithPSK("very secret passphrase").WithPriority(5).Build()
+	guest, _ := NewNetworkBuilder().WithSSID("guest").WithKeyManagement(NONE).Build()
+	live := []map[string]string{
+		{"ssid": "\"guest\"", "key_mgmt": "NONE", "priority": "0"},
+		{"ssid": "\"home\"", "key_mgmt": "WPA-PSK", "priority": "5", "scan_ssid": "0"},
+	}
+	if !networksMatch([]Network{*home, *guest}, live) {
+		t.Errorf("networks in a different order with unset defaults must match")
+	}
+	live[1]["priority"] = "1"
+	if networksMatch([]Network{*home, *guest}, live) {
+		t.Errorf("changed priority must not match")
+	}
+	if networksMatch([]Network{*home}, live) {
+		t.Errorf("additional live networks must not match")
+	}
+}